@@ -33,6 +33,10 @@ var DefaultCollapseSidebar = false // default value of collapse sidebar
 var SessionSecret = uuid.New().String()
 var CryptoSecret = uuid.New().String()
 
+// 登录签发的 JWT 访问令牌与刷新令牌有效期，单位：秒
+var AccessTokenExpireSeconds = 15 * 60
+var RefreshTokenExpireSeconds = 30 * 24 * 60 * 60
+
 var OptionMap map[string]string
 var OptionMapRWMutex sync.RWMutex
 
@@ -40,6 +44,9 @@ var ItemsPerPage = 10
 var MaxRecentItems = 100
 
 var PhoneVerificationEnabled = true
+
+// AdminTwoFAEnforcementEnabled 管理员与超级管理员必须启用两步验证，未启用前只能访问两步验证设置接口
+var AdminTwoFAEnforcementEnabled = true
 var EmailVerificationEnabled = false
 var GitHubOAuthEnabled = false
 var LinuxDOOAuthEnabled = false
@@ -194,7 +201,14 @@ const (
 )
 
 const (
-	TopUpStatusPending = "pending"
-	TopUpStatusSuccess = "success"
-	TopUpStatusExpired = "expired"
+	TopUpStatusPending  = "pending"
+	TopUpStatusSuccess  = "success"
+	TopUpStatusExpired  = "expired"
+	TopUpStatusRefunded = "refunded"
+)
+
+const (
+	StripeSubscriptionStatusPending  = "pending"
+	StripeSubscriptionStatusActive   = "active"
+	StripeSubscriptionStatusCanceled = "canceled"
 )
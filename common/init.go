@@ -16,13 +16,15 @@ var (
 	PrintVersion = flag.Bool("version", false, "print version and exit")
 	PrintHelp    = flag.Bool("help", false, "print help and exit")
 	LogDir       = flag.String("log-dir", "./logs", "specify the log directory")
+	// EncryptSecrets 使用当前主密钥加密（或重新加密）数据库中的渠道密钥与密钥配置后退出
+	EncryptSecrets = flag.Bool("encrypt-secrets", false, "encrypt channel keys and secret options with the current master key and exit")
 )
 
 func printHelp() {
 	fmt.Println("New API " + Version + " - All in one API service for OpenAI API.")
 	fmt.Println("Copyright (C) 2023 JustSong. All rights reserved.")
 	fmt.Println("GitHub: https://github.com/songquanpeng/one-api")
-	fmt.Println("Usage: one-api [--port <port>] [--log-dir <log directory>] [--version] [--help]")
+	fmt.Println("Usage: one-api [--port <port>] [--log-dir <log directory>] [--encrypt-secrets] [--version] [--help]")
 }
 
 func InitEnv() {
@@ -53,6 +55,7 @@ func InitEnv() {
 	} else {
 		CryptoSecret = SessionSecret
 	}
+	InitSecretEncryption()
 	if os.Getenv("SQLITE_PATH") != "" {
 		SQLitePath = os.Getenv("SQLITE_PATH")
 	}
@@ -83,10 +86,13 @@ func InitEnv() {
 	SyncFrequency = GetEnvOrDefault("SYNC_FREQUENCY", 60)
 	BatchUpdateInterval = GetEnvOrDefault("BATCH_UPDATE_INTERVAL", 5)
 	RelayTimeout = GetEnvOrDefault("RELAY_TIMEOUT", 0)
+	AccessTokenExpireSeconds = GetEnvOrDefault("ACCESS_TOKEN_EXPIRE_SECONDS", 15*60)
+	RefreshTokenExpireSeconds = GetEnvOrDefault("REFRESH_TOKEN_EXPIRE_SECONDS", 30*24*60*60)
 
 	// Initialize string variables with GetEnvOrDefaultString
 	GeminiSafetySetting = GetEnvOrDefaultString("GEMINI_SAFETY_SETTING", "BLOCK_NONE")
 	CohereSafetySetting = GetEnvOrDefaultString("COHERE_SAFETY_SETTING", "NONE")
+	DefaultPhoneCountryCode = GetEnvOrDefaultString("DEFAULT_PHONE_COUNTRY_CODE", "86")
 
 	// Initialize rate limit variables
 	GlobalApiRateLimitEnable = GetEnvOrDefaultBool("GLOBAL_API_RATE_LIMIT_ENABLE", true)
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "newapi"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	relayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_requests_total",
+		Help:      "Total number of relay requests by model, channel, group and result.",
+	}, []string{"model", "channel", "group", "result"})
+
+	relayErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_errors_total",
+		Help:      "Total number of failed relay requests by error code.",
+	}, []string{"model", "channel", "group", "error_code", "status_code"})
+
+	relayDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_request_duration_seconds",
+		Help:      "End-to-end latency of relay requests, including retries.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"model", "channel", "group"})
+
+	relayFirstToken = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_time_to_first_token_seconds",
+		Help:      "Time from request start to the first upstream response chunk.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60},
+	}, []string{"model", "channel", "group"})
+
+	relayRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_retries_total",
+		Help:      "Total number of retried upstream attempts (len(use_channel) - 1).",
+	}, []string{"model", "group"})
+
+	quotaConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_consumed_total",
+		Help:      "Total quota consumed by model, channel and group.",
+	}, []string{"model", "channel", "group"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		relayRequests,
+		relayErrors,
+		relayDuration,
+		relayFirstToken,
+		relayRetries,
+		quotaConsumed,
+	)
+}
+
+// Handler 返回 Prometheus 文本格式的指标输出
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RelayResult 描述一次中继请求的最终结果
+type RelayResult struct {
+	Model      string
+	ChannelId  int
+	Group      string
+	ErrorCode  string // 为空表示成功
+	StatusCode int
+	Seconds    float64
+	Attempts   int
+}
+
+func RecordRelay(result RelayResult) {
+	channel := strconv.Itoa(result.ChannelId)
+	if result.ErrorCode == "" {
+		relayRequests.WithLabelValues(result.Model, channel, result.Group, "success").Inc()
+	} else {
+		relayRequests.WithLabelValues(result.Model, channel, result.Group, "error").Inc()
+		relayErrors.WithLabelValues(result.Model, channel, result.Group, result.ErrorCode, strconv.Itoa(result.StatusCode)).Inc()
+	}
+	relayDuration.WithLabelValues(result.Model, channel, result.Group).Observe(result.Seconds)
+	if result.Attempts > 1 {
+		relayRetries.WithLabelValues(result.Model, result.Group).Add(float64(result.Attempts - 1))
+	}
+}
+
+func RecordFirstToken(modelName string, channelId int, group string, seconds float64) {
+	if seconds <= 0 {
+		return
+	}
+	relayFirstToken.WithLabelValues(modelName, strconv.Itoa(channelId), group).Observe(seconds)
+}
+
+func RecordQuota(modelName string, channelId int, group string, quota int) {
+	if quota <= 0 {
+		return
+	}
+	quotaConsumed.WithLabelValues(modelName, strconv.Itoa(channelId), group).Add(float64(quota))
+}
+
+// gaugeFunc 在采集时调用 fn 生成带单个标签的 gauge，用于渠道状态、队列长度等需要实时读取的指标
+type gaugeFunc struct {
+	desc *prometheus.Desc
+	fn   func() map[string]float64
+}
+
+func (g *gaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *gaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	for label, value := range g.fn() {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, value, label)
+	}
+}
+
+var registeredGauges sync.Map
+
+// RegisterGaugeFunc 注册一个采集时求值的 gauge，同名指标只注册一次
+func RegisterGaugeFunc(name string, help string, label string, fn func() map[string]float64) {
+	if _, loaded := registeredGauges.LoadOrStore(name, struct{}{}); loaded {
+		return
+	}
+	registry.MustRegister(&gaugeFunc{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, []string{label}, nil),
+		fn:   fn,
+	})
+}
@@ -0,0 +1,35 @@
+package common
+
+// 管理权限，由管理员角色授予，超级管理员拥有全部权限
+const (
+	PermissionChannelManage    = "channel.manage"    // 管理渠道、测试渠道
+	PermissionChannelKey       = "channel.key"       // 查看渠道密钥
+	PermissionUserRead         = "user.read"         // 查看用户与用户账单
+	PermissionUserManage       = "user.manage"       // 创建、编辑、封禁、删除用户
+	PermissionLogRead          = "log.read"          // 查看日志、数据看板与任务记录
+	PermissionLogManage        = "log.manage"        // 删除与归档日志
+	PermissionRedemptionManage = "redemption.manage" // 管理兑换码
+	PermissionTopUpManage      = "topup.manage"      // 查看充值订单、手动补单
+	PermissionContentManage    = "content.manage"    // 管理订阅文章与系统推荐
+)
+
+var AllPermissions = []string{
+	PermissionChannelManage,
+	PermissionChannelKey,
+	PermissionUserRead,
+	PermissionUserManage,
+	PermissionLogRead,
+	PermissionLogManage,
+	PermissionRedemptionManage,
+	PermissionTopUpManage,
+	PermissionContentManage,
+}
+
+func IsValidPermission(permission string) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// DefaultPhoneCountryCode 未携带国家码的号码按此国家码处理，兼容原有的 11 位大陆手机号
+var DefaultPhoneCountryCode = "86"
+
+// phoneCountryRule 国家或地区的手机号规则，National 匹配去掉国家码与长途前缀后的号码
+type phoneCountryRule struct {
+	Name        string
+	National    *regexp.Regexp
+	TrunkPrefix string // 国内拨号时的长途前缀，如英国号码 07xxx 中的 0
+}
+
+var phoneCountryRules = map[string]phoneCountryRule{
+	"86":  {Name: "中国大陆", National: regexp.MustCompile(`^1[3-9]\d{9}$`)},
+	"852": {Name: "中国香港", National: regexp.MustCompile(`^[4-9]\d{7}$`)},
+	"853": {Name: "中国澳门", National: regexp.MustCompile(`^6\d{7}$`)},
+	"886": {Name: "中国台湾", National: regexp.MustCompile(`^9\d{8}$`), TrunkPrefix: "0"},
+	"1":   {Name: "美国/加拿大", National: regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`)},
+	"44":  {Name: "英国", National: regexp.MustCompile(`^7\d{9}$`), TrunkPrefix: "0"},
+	"33":  {Name: "法国", National: regexp.MustCompile(`^[67]\d{8}$`), TrunkPrefix: "0"},
+	"49":  {Name: "德国", National: regexp.MustCompile(`^1[5-7]\d{8,9}$`), TrunkPrefix: "0"},
+	"61":  {Name: "澳大利亚", National: regexp.MustCompile(`^4\d{8}$`), TrunkPrefix: "0"},
+	"64":  {Name: "新西兰", National: regexp.MustCompile(`^2\d{7,9}$`), TrunkPrefix: "0"},
+	"81":  {Name: "日本", National: regexp.MustCompile(`^[789]0\d{8}$`), TrunkPrefix: "0"},
+	"82":  {Name: "韩国", National: regexp.MustCompile(`^1\d{8,9}$`), TrunkPrefix: "0"},
+	"65":  {Name: "新加坡", National: regexp.MustCompile(`^[89]\d{7}$`)},
+	"60":  {Name: "马来西亚", National: regexp.MustCompile(`^1\d{8,9}$`), TrunkPrefix: "0"},
+	"66":  {Name: "泰国", National: regexp.MustCompile(`^[689]\d{8}$`), TrunkPrefix: "0"},
+	"84":  {Name: "越南", National: regexp.MustCompile(`^[35789]\d{8}$`), TrunkPrefix: "0"},
+	"91":  {Name: "印度", National: regexp.MustCompile(`^[6-9]\d{9}$`), TrunkPrefix: "0"},
+	"7":   {Name: "俄罗斯", National: regexp.MustCompile(`^9\d{9}$`), TrunkPrefix: "8"},
+}
+
+var (
+	e164Regex        = regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+	phoneDigitsRegex = regexp.MustCompile(`^\d+$`)
+	// 书写号码时常见的分隔符
+	phoneSeparatorReplacer = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "", "\u00a0", "")
+)
+
+var ErrInvalidPhone = errors.New("无效的手机号格式")
+
+// splitCountryCode 按最长匹配拆分已知国家码，国家码为 1 到 3 位数字
+func splitCountryCode(digits string) (string, string) {
+	for length := 3; length >= 1; length-- {
+		if len(digits) <= length {
+			continue
+		}
+		if _, ok := phoneCountryRules[digits[:length]]; ok {
+			return digits[:length], digits[length:]
+		}
+	}
+	return "", digits
+}
+
+// NormalizePhone 将手机号规范化为 E.164 格式（如 +8613800138000）。
+// 支持 +、00 国际前缀及常见分隔符，未携带国家码的号码按 DefaultPhoneCountryCode 处理；
+// 已知国家码按该国规则校验，未知国家码仅校验 E.164 长度
+func NormalizePhone(phone string) (string, error) {
+	phone = phoneSeparatorReplacer.Replace(strings.TrimSpace(phone))
+	var digits string
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		digits = phone[1:]
+	case strings.HasPrefix(phone, "00"):
+		digits = phone[2:]
+	default:
+		digits = DefaultPhoneCountryCode + strings.TrimPrefix(phone, phoneCountryRules[DefaultPhoneCountryCode].TrunkPrefix)
+	}
+	if !phoneDigitsRegex.MatchString(digits) {
+		return "", ErrInvalidPhone
+	}
+	countryCode, national := splitCountryCode(digits)
+	if countryCode != "" {
+		rule := phoneCountryRules[countryCode]
+		if rule.TrunkPrefix != "" && !rule.National.MatchString(national) {
+			national = strings.TrimPrefix(national, rule.TrunkPrefix)
+		}
+		if !rule.National.MatchString(national) {
+			return "", errors.New("无效的" + rule.Name + "手机号")
+		}
+		digits = countryCode + national
+	}
+	normalized := "+" + digits
+	if !e164Regex.MatchString(normalized) {
+		return "", ErrInvalidPhone
+	}
+	return normalized, nil
+}
+
+// IsValidPhone 判断手机号能否规范化为合法的 E.164 号码
+func IsValidPhone(phone string) bool {
+	_, err := NormalizePhone(phone)
+	return err == nil
+}
+
+// PhoneSuffix 返回号码最后 n 位数字，用于生成用户名等场景
+func PhoneSuffix(phone string, n int) string {
+	if len(phone) <= n {
+		return strings.TrimPrefix(phone, "+")
+	}
+	return phone[len(phone)-n:]
+}
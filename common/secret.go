@@ -0,0 +1,202 @@
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 渠道密钥、OAuth 密钥等敏感数据使用信封加密保存：每个值使用随机数据密钥（DEK）加密，
+// DEK 再由环境变量中的主密钥加密后与密文一同保存，格式为
+//
+//	enc:v1:<主密钥标识>:<base64(加密后的 DEK)>:<base64(nonce+密文)>
+//
+// 轮换主密钥时只需用新主密钥重新加密 DEK，无需重新加密数据。无 enc: 前缀的值视为尚未迁移的明文
+const secretPrefix = "enc:v1:"
+
+type secretMasterKey struct {
+	id  string
+	key []byte
+}
+
+var (
+	// 当前用于加密的主密钥，未配置时不加密
+	secretCurrentKey *secretMasterKey
+	// 可用于解密的全部主密钥，按标识索引
+	secretDecryptKeys = map[string]*secretMasterKey{}
+)
+
+func newSecretMasterKey(raw string) *secretMasterKey {
+	key := sha256.Sum256([]byte(raw))
+	fingerprint := sha256.Sum256(key[:])
+	return &secretMasterKey{
+		id:  hex.EncodeToString(fingerprint[:4]),
+		key: key[:],
+	}
+}
+
+// InitSecretEncryption 从环境变量加载主密钥。ENCRYPTION_MASTER_KEY 用于加密新数据，
+// ENCRYPTION_OLD_MASTER_KEYS（逗号分隔）仅用于解密，轮换时先在所有节点加入新密钥再切换
+func InitSecretEncryption() {
+	secretCurrentKey = nil
+	secretDecryptKeys = map[string]*secretMasterKey{}
+	for _, raw := range strings.Split(os.Getenv("ENCRYPTION_OLD_MASTER_KEYS"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		key := newSecretMasterKey(raw)
+		secretDecryptKeys[key.id] = key
+	}
+	if raw := strings.TrimSpace(os.Getenv("ENCRYPTION_MASTER_KEY")); raw != "" {
+		secretCurrentKey = newSecretMasterKey(raw)
+		secretDecryptKeys[secretCurrentKey.id] = secretCurrentKey
+		SysLog("secret encryption enabled, master key id: " + secretCurrentKey.id)
+	} else if len(secretDecryptKeys) > 0 {
+		SysError("ENCRYPTION_OLD_MASTER_KEYS is set without ENCRYPTION_MASTER_KEY, new secrets will be stored in plaintext")
+	}
+}
+
+// IsSecretEncryptionEnabled 是否配置了用于加密的主密钥
+func IsSecretEncryptionEnabled() bool {
+	return secretCurrentKey != nil
+}
+
+// IsEncryptedSecret 值是否为 EncryptSecret 的结果
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+func aesGCMSeal(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func aesGCMOpen(key []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// parseSecret 拆分加密值，返回主密钥标识、加密后的 DEK 与数据密文
+func parseSecret(value string) (string, []byte, string, error) {
+	parts := strings.Split(strings.TrimPrefix(value, secretPrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, "", errors.New("malformed encrypted secret")
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, "", err
+	}
+	return parts[0], wrappedKey, parts[2], nil
+}
+
+func unwrapSecretKey(keyId string, wrappedKey []byte) ([]byte, error) {
+	masterKey, ok := secretDecryptKeys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("master key %s is not configured", keyId)
+	}
+	return aesGCMOpen(masterKey.key, wrappedKey)
+}
+
+func formatSecret(keyId string, wrappedKey []byte, data string) string {
+	return secretPrefix + keyId + ":" + base64.StdEncoding.EncodeToString(wrappedKey) + ":" + data
+}
+
+// EncryptSecret 使用当前主密钥加密敏感数据，未配置主密钥时原样返回
+func EncryptSecret(plaintext string) (string, error) {
+	if secretCurrentKey == nil || plaintext == "" || IsEncryptedSecret(plaintext) {
+		return plaintext, nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := crand.Read(dataKey); err != nil {
+		return "", err
+	}
+	data, err := aesGCMSeal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := aesGCMSeal(secretCurrentKey.key, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return formatSecret(secretCurrentKey.id, wrappedKey, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 的结果，明文值原样返回
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	keyId, wrappedKey, encoded, err := parseSecret(value)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := unwrapSecretKey(keyId, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := aesGCMOpen(dataKey, data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// ReencryptSecret 将明文或由旧主密钥加密的值转换为当前主密钥加密的值。
+// 已加密的值只重新加密 DEK；返回值与原值相同表示无需更新
+func ReencryptSecret(value string) (string, error) {
+	if secretCurrentKey == nil || value == "" {
+		return value, nil
+	}
+	if !IsEncryptedSecret(value) {
+		return EncryptSecret(value)
+	}
+	keyId, wrappedKey, data, err := parseSecret(value)
+	if err != nil {
+		return "", err
+	}
+	if keyId == secretCurrentKey.id {
+		return value, nil
+	}
+	dataKey, err := unwrapSecretKey(keyId, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err = aesGCMSeal(secretCurrentKey.key, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return formatSecret(secretCurrentKey.id, wrappedKey, data), nil
+}
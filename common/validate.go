@@ -6,4 +6,8 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New()
+	// phone 校验手机号能否规范化为 E.164 号码，入库前需调用 NormalizePhone
+	_ = Validate.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return IsValidPhone(fl.Field().String())
+	})
 }
@@ -15,6 +15,7 @@ const (
 	ContextKeyTokenSpecificChannelId ContextKey = "specific_channel_id"
 	ContextKeyTokenModelLimitEnabled ContextKey = "token_model_limit_enabled"
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
+	ContextKeyTokenOrganizationId    ContextKey = "token_organization_id"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
@@ -41,4 +42,10 @@ const (
 	ContextKeyUserGroup   ContextKey = "user_group"
 	ContextKeyUsingGroup  ContextKey = "group"
 	ContextKeyUserName    ContextKey = "username"
+
+	/* relay related keys */
+	ContextKeyGeneratedImageCount ContextKey = "generated_image_count"
+	// realtime 会话内部发起的子请求，配额由会话统一结算
+	ContextKeyRealtimePipeline      ContextKey = "realtime_pipeline"
+	ContextKeyRealtimePipelineUsage ContextKey = "realtime_pipeline_usage"
 )
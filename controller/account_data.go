@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSelfDeletion 查询当前用户待执行的注销申请，没有申请时 data 为 null
+func GetSelfDeletion(c *gin.Context) {
+	deletion, err := model.GetPendingAccountDeletion(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, deletion)
+}
+
+// CancelSelfDeletion 在冷静期内撤销注销申请
+func CancelSelfDeletion(c *gin.Context) {
+	if err := model.CancelAccountDeletion(c.GetInt("id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// ExportSelfData 以 zip 格式导出当前用户的全部数据
+func ExportSelfData(c *gin.Context) {
+	userId := c.GetInt("id")
+	filename := fmt.Sprintf("user-data-%d-%s.zip", userId, time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+	if err := service.WriteUserDataExport(c.Request.Context(), userId, c.Writer); err != nil {
+		// 响应头已发送，只能记录错误并中断输出
+		common.LogError(c, "failed to export user data: "+err.Error())
+	}
+}
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type SetUserAdminRoleRequest struct {
+	UserId    int    `json:"user_id"`
+	AdminRole string `json:"admin_role"`
+}
+
+// GetAdminRoles 获取全部管理员角色与可授予的权限
+func GetAdminRoles(c *gin.Context) {
+	roles, err := model.GetAllAdminRoles()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"roles":       roles,
+		"permissions": common.AllPermissions,
+	})
+}
+
+func CreateAdminRole(c *gin.Context) {
+	var req AdminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 32 {
+		common.ApiErrorMsg(c, "角色名称长度须为 1-32")
+		return
+	}
+	role := &model.AdminRole{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := role.SetPermissions(req.Permissions); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	exist, err := model.GetAdminRoleByName(req.Name)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if exist != nil {
+		common.ApiErrorMsg(c, "角色名称已存在")
+		return
+	}
+	if err := role.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordAuditLog(c, "admin_role.create", model.AuditTargetAdminRole, role.Id, nil, role)
+	common.ApiSuccess(c, role)
+}
+
+// UpdateAdminRole 更新自定义角色的描述与权限，内置角色不可修改
+func UpdateAdminRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req AdminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	role, err := model.GetAdminRoleById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	originRole := *role
+	role.Description = req.Description
+	if err := role.SetPermissions(req.Permissions); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := role.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordAuditLog(c, "admin_role.update", model.AuditTargetAdminRole, role.Id, &originRole, role)
+	common.ApiSuccess(c, role)
+}
+
+func DeleteAdminRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	role, err := model.GetAdminRoleById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := role.Delete(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordAuditLog(c, "admin_role.delete", model.AuditTargetAdminRole, role.Id, role, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// SetUserAdminRole 为用户分配管理员角色，普通用户分配角色后提升为管理员；
+// 角色为空时恢复为拥有全部管理权限的管理员
+func SetUserAdminRole(c *gin.Context) {
+	var req SetUserAdminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.UserId == 0 {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	user, err := model.GetUserById(req.UserId, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if user.Role >= common.RoleRootUser {
+		common.ApiErrorMsg(c, "无法为超级管理员设置角色")
+		return
+	}
+	req.AdminRole = strings.TrimSpace(req.AdminRole)
+	if req.AdminRole == model.AdminRoleAdmin {
+		req.AdminRole = ""
+	}
+	if req.AdminRole != "" {
+		role, err := model.GetAdminRoleByName(req.AdminRole)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if role == nil {
+			common.ApiErrorMsg(c, "角色不存在")
+			return
+		}
+	} else if user.Role < common.RoleAdminUser {
+		common.ApiErrorMsg(c, "请选择角色，或使用提升操作将用户设为管理员")
+		return
+	}
+	if err := model.SetUserAdminRole(user, req.AdminRole); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordAuditLog(c, "user.set_admin_role", model.AuditTargetUser, user.Id,
+		map[string]interface{}{"role": user.Role, "admin_role": user.AdminRole},
+		map[string]interface{}{"role": max(user.Role, common.RoleAdminUser), "admin_role": req.AdminRole})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// GetSelfPermissions 当前用户拥有的管理权限，供前端控制菜单显示
+func GetSelfPermissions(c *gin.Context) {
+	userCache, err := model.GetUserCache(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, model.GetUserPermissions(c.GetInt("role"), userCache.AdminRole))
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAuditLog 以当前管理员为操作者创建审计日志，before 为 nil 表示新建，after 为 nil 表示删除
+func newAuditLog(c *gin.Context, action string, targetType string, targetId interface{}, before interface{}, after interface{}) *model.AuditLog {
+	return model.NewAuditLog(c.GetInt("id"), c.GetString("username"), c.ClientIP(), action, targetType, fmt.Sprint(targetId), before, after)
+}
+
+// recordAuditLog 修改无法与审计日志共用事务时，在修改成功后单独写入
+func recordAuditLog(c *gin.Context, action string, targetType string, targetId interface{}, before interface{}, after interface{}) {
+	model.RecordAuditLog(newAuditLog(c, action, targetType, targetId, before, after))
+}
+
+func getAuditLogFilter(c *gin.Context) model.AuditLogFilter {
+	actorId, _ := strconv.Atoi(c.Query("actor_id"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	return model.AuditLogFilter{
+		ActorId:        actorId,
+		Action:         c.Query("action"),
+		TargetType:     c.Query("target_type"),
+		TargetId:       c.Query("target_id"),
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}
+}
+
+func GetAuditLogs(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	logs, total, err := model.SearchAuditLogs(getAuditLogFilter(c), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetAuditLogActions(c *gin.Context) {
+	actions, err := model.GetAuditLogActions()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, actions)
+}
+
+var auditLogExportCSVHeader = []string{"id", "created_at", "actor_id", "actor_name", "action", "target_type", "target_id", "changes", "ip"}
+
+// ExportAuditLogs 以 CSV 或 JSONL 格式流式导出审计日志
+func ExportAuditLogs(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "jsonl" {
+		common.ApiErrorMsg(c, "不支持的导出格式")
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.%s", time.Now().Format("20060102150405"), format)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+
+	csvWriter := csv.NewWriter(c.Writer)
+	if format == "csv" {
+		// BOM，保证 Excel 打开时中文不乱码
+		_, _ = c.Writer.Write([]byte("\xEF\xBB\xBF"))
+		_ = csvWriter.Write(auditLogExportCSVHeader)
+	}
+
+	err := model.IterateAuditLogs(c.Request.Context(), getAuditLogFilter(c), 1000, func(logs []*model.AuditLog) error {
+		for _, log := range logs {
+			if format == "jsonl" {
+				data, err := common.Marshal(log)
+				if err != nil {
+					return err
+				}
+				if _, err = c.Writer.Write(append(data, '\n')); err != nil {
+					return err
+				}
+				continue
+			}
+			record := []string{
+				strconv.Itoa(log.Id),
+				time.Unix(log.CreatedAt, 0).Format("2006-01-02 15:04:05"),
+				strconv.Itoa(log.ActorId),
+				log.ActorName,
+				log.Action,
+				log.TargetType,
+				log.TargetId,
+				log.Changes,
+				log.Ip,
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		// 响应头已发送，只能记录错误并中断输出
+		common.LogError(c, "failed to export audit logs: "+err.Error())
+	}
+}
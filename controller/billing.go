@@ -3,6 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
 	"one-api/model"
 )
@@ -19,6 +20,9 @@ func GetSubscription(c *gin.Context) {
 		expiredTime = token.ExpiredTime
 		remainQuota = token.RemainQuota
 		usedQuota = token.UsedQuota
+	} else if orgId := common.GetContextKeyInt(c, constant.ContextKeyTokenOrganizationId); orgId != 0 {
+		// 组织令牌由组织额度付费，返回成员可用与已用的组织额度
+		remainQuota, usedQuota, err = model.GetOrganizationMemberBalance(orgId, c.GetInt("id"))
 	} else {
 		userId := c.GetInt("id")
 		remainQuota, err = model.GetUserQuota(userId, false)
@@ -65,6 +69,8 @@ func GetUsage(c *gin.Context) {
 		tokenId := c.GetInt("token_id")
 		token, err = model.GetTokenById(tokenId)
 		quota = token.UsedQuota
+	} else if orgId := common.GetContextKeyInt(c, constant.ContextKeyTokenOrganizationId); orgId != 0 {
+		_, quota, err = model.GetOrganizationMemberBalance(orgId, c.GetInt("id"))
 	} else {
 		userId := c.GetInt("id")
 		quota, err = model.GetUserUsedQuota(userId)
@@ -1,15 +1,18 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"one-api/common"
 	"one-api/constant"
 	"one-api/model"
+	"one-api/relay/channel/ollama"
 	"strconv"
 	"strings"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 )
 
@@ -168,6 +171,15 @@ func FetchUpstreamModels(c *gin.Context) {
 	if channel.GetBaseURL() != "" {
 		baseURL = channel.GetBaseURL()
 	}
+	if channel.Type == constant.ChannelTypeOllama {
+		ids, err := ollama.FetchModels(c.Request.Context(), baseURL, strings.Split(strings.TrimSpace(channel.Key), "\n")[0])
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		common.ApiSuccess(c, ids)
+		return
+	}
 	url := fmt.Sprintf("%s/v1/models", baseURL)
 	switch channel.Type {
 	case constant.ChannelTypeGemini:
@@ -206,6 +218,46 @@ func FetchUpstreamModels(c *gin.Context) {
 	})
 }
 
+// PullOllamaModel 在 Ollama 渠道上后台拉取指定模型
+func PullOllamaModel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err = c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Model) == "" {
+		common.ApiErrorMsg(c, "模型名称不能为空")
+		return
+	}
+	channel, err := model.GetChannelById(id, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if channel.Type != constant.ChannelTypeOllama {
+		common.ApiErrorMsg(c, "仅支持 Ollama 渠道")
+		return
+	}
+	baseURL := channel.GetBaseURL()
+	if baseURL == "" {
+		baseURL = constant.ChannelBaseURLs[channel.Type]
+	}
+	modelName := strings.TrimSpace(req.Model)
+	key := strings.Split(strings.TrimSpace(channel.Key), "\n")[0]
+	gopool.Go(func() {
+		common.SysLog(fmt.Sprintf("channel #%d start pulling ollama model %s", channel.Id, modelName))
+		if err := ollama.PullModel(context.Background(), baseURL, key, modelName); err != nil {
+			common.SysError(fmt.Sprintf("channel #%d failed to pull ollama model %s: %s", channel.Id, modelName, err.Error()))
+			return
+		}
+		common.SysLog(fmt.Sprintf("channel #%d pulled ollama model %s", channel.Id, modelName))
+	})
+	common.ApiSuccess(c, nil)
+}
+
 func FixChannelsAbilities(c *gin.Context) {
 	success, fails, err := model.FixAbility()
 	if err != nil {
@@ -350,6 +402,23 @@ func GetChannel(c *gin.Context) {
 	return
 }
 
+// GetChannelKey 获取渠道密钥，需先通过二次验证
+func GetChannelKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	channel, err := model.GetChannelById(id, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"key": channel.Key,
+	})
+}
+
 // validateChannel 通用的渠道校验函数
 func validateChannel(channel *model.Channel, isAdd bool) error {
 	// 校验 channel settings
@@ -508,7 +577,9 @@ func AddChannel(c *gin.Context) {
 		localChannel.Key = key
 		channels = append(channels, *localChannel)
 	}
-	err = model.BatchInsertChannels(channels)
+	err = model.BatchInsertChannelsWithAudit(channels, func(channel *model.Channel) *model.AuditLog {
+		return newAuditLog(c, "channel.create", model.AuditTargetChannel, channel.Id, nil, channel)
+	})
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -522,8 +593,9 @@ func AddChannel(c *gin.Context) {
 
 func DeleteChannel(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	originChannel, _ := model.GetChannelById(id, true)
 	channel := model.Channel{Id: id}
-	err := channel.Delete()
+	err := channel.DeleteWithAudit(newAuditLog(c, "channel.delete", model.AuditTargetChannel, id, originChannel, nil))
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -542,6 +614,7 @@ func DeleteDisabledChannel(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	recordAuditLog(c, "channel.delete_disabled", model.AuditTargetChannel, "", nil, map[string]interface{}{"deleted": rows})
 	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -652,7 +725,12 @@ func DeleteChannelBatch(c *gin.Context) {
 		})
 		return
 	}
-	err = model.BatchDeleteChannels(channelBatch.Ids)
+	originChannels, _ := model.GetChannelsByIds(channelBatch.Ids)
+	audits := make([]*model.AuditLog, 0, len(originChannels))
+	for _, originChannel := range originChannels {
+		audits = append(audits, newAuditLog(c, "channel.delete", model.AuditTargetChannel, originChannel.Id, originChannel, nil))
+	}
+	err = model.BatchDeleteChannelsWithAudit(channelBatch.Ids, audits)
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -688,7 +766,7 @@ func UpdateChannel(c *gin.Context) {
 		return
 	}
 	// Preserve existing ChannelInfo to ensure multi-key channels keep correct state even if the client does not send ChannelInfo in the request.
-	originChannel, err := model.GetChannelById(channel.Id, false)
+	originChannel, err := model.GetChannelById(channel.Id, true)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -704,7 +782,10 @@ func UpdateChannel(c *gin.Context) {
 	if channel.MultiKeyMode != nil && *channel.MultiKeyMode != "" {
 		channel.ChannelInfo.MultiKeyMode = constant.MultiKeyMode(*channel.MultiKeyMode)
 	}
-	err = channel.Update()
+	// 审计日志记录更新后重新读取的渠道，与更新在同一事务中写入
+	err = channel.UpdateWithAudit(func(updated *model.Channel) *model.AuditLog {
+		return newAuditLog(c, "channel.update", model.AuditTargetChannel, updated.Id, originChannel, updated)
+	})
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -739,6 +820,22 @@ func FetchModels(c *gin.Context) {
 		baseURL = constant.ChannelBaseURLs[req.Type]
 	}
 
+	if req.Type == constant.ChannelTypeOllama {
+		models, err := ollama.FetchModels(c.Request.Context(), baseURL, strings.TrimSpace(req.Key))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    models,
+		})
+		return
+	}
+
 	client := &http.Client{}
 	url := fmt.Sprintf("%s/v1/models", baseURL)
 
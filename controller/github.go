@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"net/http"
 	"one-api/common"
-	"one-api/model"
-	"strconv"
 	"time"
 
 	"github.com/gin-contrib/sessions"
@@ -107,69 +105,16 @@ func GitHubOAuth(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
-	user := model.User{
-		GitHubId: githubUser.Login,
-	}
-	// IsGitHubIdAlreadyTaken is unscoped
-	if model.IsGitHubIdAlreadyTaken(user.GitHubId) {
-		// FillUserByGitHubId is scoped
-		err := user.FillUserByGitHubId()
-		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
-			return
-		}
-		// if user.Id == 0 , user has been deleted
-		if user.Id == 0 {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": "用户已注销",
-			})
-			return
-		}
-	} else {
-		if common.RegisterEnabled {
-			user.Username = "github_" + strconv.Itoa(model.GetMaxUserId()+1)
-			if githubUser.Name != "" {
-				user.DisplayName = githubUser.Name
-			} else {
-				user.DisplayName = "GitHub User"
-			}
-			user.Email = githubUser.Email
-			user.Role = common.RoleCommonUser
-			user.Status = common.UserStatusEnabled
-			affCode := session.Get("aff")
-			inviterId := 0
-			if affCode != nil {
-				inviterId, _ = model.GetUserIdByAffCode(affCode.(string))
-			}
-
-			if err := user.Insert(inviterId); err != nil {
-				c.JSON(http.StatusOK, gin.H{
-					"success": false,
-					"message": err.Error(),
-				})
-				return
-			}
-		} else {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": "管理员关闭了新用户注册",
-			})
-			return
-		}
-	}
-
-	if user.Status != common.UserStatusEnabled {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "用户已被封禁",
-			"success": false,
-		})
-		return
+	displayName := githubUser.Name
+	if displayName == "" {
+		displayName = "GitHub User"
 	}
-	setupLogin(&user, c)
+	oauthLogin(c, &oauthIdentity{
+		Provider:    "github",
+		Id:          githubUser.Login,
+		DisplayName: displayName,
+		Email:       githubUser.Email,
+	})
 }
 
 func GitHubBind(c *gin.Context) {
@@ -186,27 +131,10 @@ func GitHubBind(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
-	user := model.User{
-		GitHubId: githubUser.Login,
-	}
-	if model.IsGitHubIdAlreadyTaken(user.GitHubId) {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": "该 GitHub 账户已被绑定",
-		})
-		return
-	}
-	session := sessions.Default(c)
-	id := session.Get("id")
-	// id := c.GetInt("id")  // critical bug!
-	user.Id = id.(int)
-	err = user.FillUserById()
-	if err != nil {
-		common.ApiError(c, err)
-		return
-	}
-	user.GitHubId = githubUser.Login
-	err = user.Update(false)
+	err = oauthBind(c, &oauthIdentity{
+		Provider: "github",
+		Id:       githubUser.Login,
+	})
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getInviteSource 注册请求的 IP 与客户端设备标识，用于检测刷邀请
+func getInviteSource(c *gin.Context) model.InviteSource {
+	return model.InviteSource{
+		Ip:       c.ClientIP(),
+		DeviceId: truncateRunes(c.GetHeader("X-Device-Id"), 128),
+	}
+}
+
+// GetSelfInviteRewards 当前用户作为邀请人的奖励台账
+func GetSelfInviteRewards(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	status, _ := strconv.Atoi(c.Query("status"))
+	rewards, total, err := model.SearchInviteRewards(c.GetInt("id"), status, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	// 风控信息仅对管理员可见
+	for _, reward := range rewards {
+		reward.Ip = ""
+		reward.DeviceId = ""
+		reward.RiskFlags = ""
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(rewards)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetInviteRewards(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	inviterId, _ := strconv.Atoi(c.Query("inviter_id"))
+	status, _ := strconv.Atoi(c.Query("status"))
+	rewards, total, err := model.SearchInviteRewards(inviterId, status, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(rewards)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func reviewInviteReward(c *gin.Context, approve bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	reward, err := model.GetInviteRewardById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.ReviewInviteReward(id, approve, c.GetInt("id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	action := "invite_reward.reject"
+	status := model.InviteRewardStatusRejected
+	if approve {
+		action = "invite_reward.approve"
+		status = model.InviteRewardStatusPending
+	}
+	recordAuditLog(c, action, model.AuditTargetInviteReward, id,
+		map[string]interface{}{"status": reward.Status}, map[string]interface{}{"status": status})
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// ApproveInviteReward 审核通过，奖励恢复为待发放
+func ApproveInviteReward(c *gin.Context) {
+	reviewInviteReward(c, true)
+}
+
+func RejectInviteReward(c *gin.Context) {
+	reviewInviteReward(c, false)
+}
@@ -1,12 +1,17 @@
 package controller
 
 import (
+	"encoding/csv"
+	"fmt"
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/service"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 func GetAllLogs(c *gin.Context) {
@@ -166,3 +171,197 @@ func DeleteHistoryLogs(c *gin.Context) {
 	})
 	return
 }
+
+func ExportAllLogs(c *gin.Context) {
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	channel, _ := strconv.Atoi(c.Query("channel"))
+	filter := model.LogExportFilter{
+		Type:           logType,
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+		ModelName:      c.Query("model_name"),
+		Username:       c.Query("username"),
+		TokenName:      c.Query("token_name"),
+		Channel:        channel,
+		Group:          c.Query("group"),
+	}
+	exportLogs(c, filter, true)
+}
+
+func ExportUserLogs(c *gin.Context) {
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	filter := model.LogExportFilter{
+		UserId:         c.GetInt("id"),
+		Type:           logType,
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+		ModelName:      c.Query("model_name"),
+		TokenName:      c.Query("token_name"),
+		Group:          c.Query("group"),
+	}
+	exportLogs(c, filter, false)
+}
+
+var logExportCSVHeader = []string{"id", "created_at", "type", "username", "token_name", "model_name", "quota",
+	"prompt_tokens", "completion_tokens", "use_time", "is_stream", "channel", "channel_name", "group", "ip", "content", "other"}
+
+// exportLogs 以 CSV 或 JSONL 格式流式输出日志，数据按批写出并及时 flush
+func exportLogs(c *gin.Context, filter model.LogExportFilter, isAdmin bool) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "jsonl" {
+		common.ApiErrorMsg(c, "不支持的导出格式")
+		return
+	}
+
+	filename := fmt.Sprintf("logs-%s.%s", time.Now().Format("20060102150405"), format)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+
+	csvWriter := csv.NewWriter(c.Writer)
+	if format == "csv" {
+		// BOM，保证 Excel 打开时中文不乱码
+		_, _ = c.Writer.Write([]byte("\xEF\xBB\xBF"))
+		header := logExportCSVHeader
+		if !isAdmin {
+			header = lo.Without(header, "channel", "channel_name")
+		}
+		_ = csvWriter.Write(header)
+	}
+
+	err := model.IterateLogs(c.Request.Context(), filter, 1000, func(logs []*model.Log) error {
+		for _, log := range logs {
+			if format == "jsonl" {
+				data, err := common.Marshal(log)
+				if err != nil {
+					return err
+				}
+				if _, err = c.Writer.Write(append(data, '\n')); err != nil {
+					return err
+				}
+				continue
+			}
+			record := []string{
+				strconv.Itoa(log.Id),
+				time.Unix(log.CreatedAt, 0).Format("2006-01-02 15:04:05"),
+				strconv.Itoa(log.Type),
+				log.Username,
+				log.TokenName,
+				log.ModelName,
+				strconv.Itoa(log.Quota),
+				strconv.Itoa(log.PromptTokens),
+				strconv.Itoa(log.CompletionTokens),
+				strconv.Itoa(log.UseTime),
+				strconv.FormatBool(log.IsStream),
+			}
+			if isAdmin {
+				record = append(record, strconv.Itoa(log.ChannelId), log.ChannelName)
+			}
+			record = append(record, log.Group, log.Ip, log.Content, log.Other)
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		// 响应头已发送，只能记录错误并中断输出
+		common.LogError(c, "failed to export logs: "+err.Error())
+	}
+}
+
+func ArchiveHistoryLogs(c *gin.Context) {
+	targetTimestamp, _ := strconv.ParseInt(c.Query("target_timestamp"), 10, 64)
+	if targetTimestamp == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "target timestamp is required",
+		})
+		return
+	}
+	count, err := service.ArchiveOldLogs(c.Request.Context(), targetTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+func GetLogArchives(c *gin.Context) {
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	archives, err := model.GetLogArchives(startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, archives)
+}
+
+// QueryArchivedLogs 从归档文件中按条件筛选日志，以 JSONL 格式流式返回
+func QueryArchivedLogs(c *gin.Context) {
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if startTimestamp == 0 || endTimestamp == 0 {
+		common.ApiErrorMsg(c, "start timestamp and end timestamp are required")
+		return
+	}
+	logType, _ := strconv.Atoi(c.Query("type"))
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	username := c.Query("username")
+	modelName := c.Query("model_name")
+	tokenName := c.Query("token_name")
+	channel, _ := strconv.Atoi(c.Query("channel"))
+
+	archives, err := model.GetLogArchives(startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=archived-logs-%d-%d.jsonl", startTimestamp, endTimestamp))
+	c.Status(http.StatusOK)
+	for _, archive := range archives {
+		err = service.ReadLogArchive(c.Request.Context(), archive, func(log *model.Log) error {
+			if log.CreatedAt < startTimestamp || log.CreatedAt > endTimestamp ||
+				(logType != model.LogTypeUnknown && log.Type != logType) ||
+				(userId != 0 && log.UserId != userId) ||
+				(username != "" && log.Username != username) ||
+				(modelName != "" && log.ModelName != modelName) ||
+				(tokenName != "" && log.TokenName != tokenName) ||
+				(channel != 0 && log.ChannelId != channel) {
+				return nil
+			}
+			data, err := common.Marshal(log)
+			if err != nil {
+				return err
+			}
+			_, err = c.Writer.Write(append(data, '\n'))
+			return err
+		})
+		if err != nil {
+			// 响应头已发送，只能记录错误并中断输出
+			common.LogError(c, fmt.Sprintf("failed to read log archive %d: %s", archive.Id, err.Error()))
+			return
+		}
+		c.Writer.Flush()
+	}
+}
@@ -82,6 +82,10 @@ func GetStatus(c *gin.Context) {
 		"pay_methods":              setting.PayMethods,
 		"usd_exchange_rate":        setting.USDExchangeRate,
 
+		// Stripe 订阅
+		"enable_stripe_subscription": setting.StripeApiSecret != "" && setting.StripeWebhookSecret != "" && setting.StripeSubscriptionPriceId != "" && setting.StripeSubscriptionQuota > 0,
+		"stripe_subscription_quota":  setting.StripeSubscriptionQuota,
+
 		// 面板启用开关
 		"api_info_enabled":      cs.ApiInfoEnabled,
 		"uptime_kuma_enabled":   cs.UptimeKumaEnabled,
@@ -229,11 +233,11 @@ func SendEmailVerification(c *gin.Context) {
 }
 
 func SendPasswordResetPhone(c *gin.Context) {
-	phone := c.Query("phone")
-	if err := common.Validate.Var(phone, "required,len=11"); err != nil {
+	phone, err := common.NormalizePhone(c.Query("phone"))
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
-			"message": "无效的手机号格式",
+			"message": err.Error(),
 		})
 		return
 	}
@@ -272,6 +276,11 @@ func ResetPassword(c *gin.Context) {
 		})
 		return
 	}
+	req.Phone, err = common.NormalizePhone(req.Phone)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	if !common.VerifyCodeWithKey(req.Phone, req.Token, common.PasswordResetPurpose) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -295,13 +304,14 @@ func ResetPassword(c *gin.Context) {
 }
 
 func SendPhoneVerification(c *gin.Context) {
-	phone := c.Query("phone")
-	purpose := c.Query("purpose") // 添加用途参数：register 或 login
+	purpose := c.Query("purpose") // 添加用途参数：register、login 或 bind
 	
-	if err := common.Validate.Var(phone, "required,len=11"); err != nil {
+	// 验证码按规范化后的 E.164 号码保存，登录与注册时同样先规范化
+	phone, err := common.NormalizePhone(c.Query("phone"))
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
-			"message": "无效的手机号格式",
+			"message": err.Error(),
 		})
 		return
 	}
@@ -309,7 +319,10 @@ func SendPhoneVerification(c *gin.Context) {
 	isPhoneTaken := model.IsPhoneAlreadyTaken(phone)
 	
 	// 根据用途检查手机号状态
-	if purpose == "login" {
+	switch purpose {
+	case "bind":
+		// 第三方登录绑定手机号时，已注册的手机号关联到原账户，未注册的手机号创建新账户
+	case "login":
 		// 登录时，手机号必须已存在
 		if !isPhoneTaken {
 			c.JSON(http.StatusOK, gin.H{
@@ -318,7 +331,7 @@ func SendPhoneVerification(c *gin.Context) {
 			})
 			return
 		}
-	} else {
+	default:
 		// 注册时（默认情况），手机号不能已存在
 		if isPhoneTaken {
 			c.JSON(http.StatusOK, gin.H{
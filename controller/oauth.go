@@ -0,0 +1,324 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/system_setting"
+	"strconv"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthProvider 第三方登录方式，统一登录、绑定手机号、绑定与解绑流程
+type oauthProvider struct {
+	Name    string // 提示信息中的名称
+	Column  string // users 表中的绑定字段
+	Enabled func() bool
+	IsTaken func(id string) bool
+	GetId   func(user *model.User) string
+	SetId   func(user *model.User, id string)
+	Fill    func(user *model.User) error
+}
+
+var oauthProviders = map[string]*oauthProvider{
+	"github": {
+		Name:    "GitHub",
+		Column:  "github_id",
+		Enabled: func() bool { return common.GitHubOAuthEnabled },
+		IsTaken: model.IsGitHubIdAlreadyTaken,
+		GetId:   func(user *model.User) string { return user.GitHubId },
+		SetId:   func(user *model.User, id string) { user.GitHubId = id },
+		Fill:    (*model.User).FillUserByGitHubId,
+	},
+	"oidc": {
+		Name:    "OIDC",
+		Column:  "oidc_id",
+		Enabled: func() bool { return system_setting.GetOIDCSettings().Enabled },
+		IsTaken: model.IsOidcIdAlreadyTaken,
+		GetId:   func(user *model.User) string { return user.OidcId },
+		SetId:   func(user *model.User, id string) { user.OidcId = id },
+		Fill:    (*model.User).FillUserByOidcId,
+	},
+	"wechat": {
+		Name:    "微信",
+		Column:  "wechat_id",
+		Enabled: func() bool { return common.WeChatAuthEnabled },
+		IsTaken: model.IsWeChatIdAlreadyTaken,
+		GetId:   func(user *model.User) string { return user.WeChatId },
+		SetId:   func(user *model.User, id string) { user.WeChatId = id },
+		Fill:    (*model.User).FillUserByWeChatId,
+	},
+	"telegram": {
+		Name:    "Telegram",
+		Column:  "telegram_id",
+		Enabled: func() bool { return common.TelegramOAuthEnabled },
+		IsTaken: model.IsTelegramIdAlreadyTaken,
+		GetId:   func(user *model.User) string { return user.TelegramId },
+		SetId:   func(user *model.User, id string) { user.TelegramId = id },
+		Fill:    (*model.User).FillUserByTelegramId,
+	},
+	"linuxdo": {
+		Name:    "Linux DO",
+		Column:  "linux_do_id",
+		Enabled: func() bool { return common.LinuxDOOAuthEnabled },
+		IsTaken: model.IsLinuxDOIdAlreadyTaken,
+		GetId:   func(user *model.User) string { return user.LinuxDOId },
+		SetId:   func(user *model.User, id string) { user.LinuxDOId = id },
+		Fill:    (*model.User).FillUserByLinuxDOId,
+	},
+}
+
+// oauthIdentity 第三方平台返回的用户身份
+type oauthIdentity struct {
+	Provider    string
+	Id          string
+	DisplayName string
+	Email       string
+}
+
+// 待绑定手机号的第三方身份保存在会话中的键
+const (
+	oauthPendingProviderKey = "oauth_pending_provider"
+	oauthPendingIdKey       = "oauth_pending_id"
+	oauthPendingNameKey     = "oauth_pending_name"
+	oauthPendingEmailKey    = "oauth_pending_email"
+)
+
+type OAuthBindPhoneRequest struct {
+	Phone                 string `json:"phone"`
+	PhoneVerificationCode string `json:"phone_verification_code"`
+}
+
+// oauthLogin 第三方身份登录。已绑定手机号的用户直接登录；
+// 新用户或尚未绑定手机号的旧用户需先调用 OAuthBindPhone 绑定已验证的手机号
+func oauthLogin(c *gin.Context, identity *oauthIdentity) {
+	provider := oauthProviders[identity.Provider]
+	if provider.IsTaken(identity.Id) {
+		user := model.User{}
+		provider.SetId(&user, identity.Id)
+		if err := provider.Fill(&user); err != nil {
+			common.ApiErrorMsg(c, err.Error())
+			return
+		}
+		// 绑定字段查询不含已注销用户
+		if user.Id == 0 {
+			common.ApiErrorMsg(c, "用户已注销")
+			return
+		}
+		if user.Status != common.UserStatusEnabled {
+			common.ApiErrorMsg(c, "用户已被封禁")
+			return
+		}
+		if user.Phone != "" {
+			setupLogin(&user, c)
+			return
+		}
+	} else if !common.RegisterEnabled {
+		common.ApiErrorMsg(c, "管理员关闭了新用户注册")
+		return
+	}
+
+	session := sessions.Default(c)
+	session.Set(oauthPendingProviderKey, identity.Provider)
+	session.Set(oauthPendingIdKey, identity.Id)
+	session.Set(oauthPendingNameKey, identity.DisplayName)
+	session.Set(oauthPendingEmailKey, identity.Email)
+	if err := session.Save(); err != nil {
+		common.ApiErrorMsg(c, "无法保存会话信息，请重试")
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"need_bind_phone": true,
+		"provider":        identity.Provider,
+		"display_name":    identity.DisplayName,
+	})
+}
+
+// OAuthBindPhone 为待登录的第三方身份绑定已验证的手机号并完成登录：
+// 手机号已注册时将第三方账户关联到该用户，否则以该手机号创建新用户
+func OAuthBindPhone(c *gin.Context) {
+	var req OAuthBindPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Phone == "" || req.PhoneVerificationCode == "" {
+		common.ApiErrorMsg(c, "请输入手机号和验证码")
+		return
+	}
+	session := sessions.Default(c)
+	providerName, _ := session.Get(oauthPendingProviderKey).(string)
+	providerId, _ := session.Get(oauthPendingIdKey).(string)
+	provider, ok := oauthProviders[providerName]
+	if !ok || providerId == "" {
+		common.ApiErrorMsg(c, "第三方登录已过期，请重新登录")
+		return
+	}
+	if !provider.Enabled() {
+		common.ApiErrorMsg(c, "管理员未开启通过 "+provider.Name+" 登录以及注册")
+		return
+	}
+	phone, err := common.NormalizePhone(req.Phone)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	req.Phone = phone
+	if !common.VerifyCodeWithKey(req.Phone, req.PhoneVerificationCode, common.PhoneVerificationPurpose) {
+		common.ApiErrorMsg(c, "手机验证码错误或已过期")
+		return
+	}
+
+	phoneUser, err := model.GetUserByPhone(req.Phone)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var user *model.User
+	if provider.IsTaken(providerId) {
+		// 未绑定手机号的旧第三方用户补绑手机号
+		user = &model.User{}
+		provider.SetId(user, providerId)
+		if err := provider.Fill(user); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if user.Id == 0 {
+			common.ApiErrorMsg(c, "用户已注销")
+			return
+		}
+		if phoneUser != nil && phoneUser.Id != user.Id {
+			common.ApiErrorMsg(c, "该手机号已被其他账户使用")
+			return
+		}
+		if user.Phone == "" {
+			user.Phone = req.Phone
+			if err := user.Update(false); err != nil {
+				common.ApiError(c, err)
+				return
+			}
+		}
+	} else if phoneUser != nil {
+		// 手机号已注册，关联第三方账户
+		if provider.GetId(phoneUser) != "" {
+			common.ApiErrorMsg(c, "该手机号已绑定其他 "+provider.Name+" 账户")
+			return
+		}
+		provider.SetId(phoneUser, providerId)
+		if err := phoneUser.Update(false); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		user = phoneUser
+	} else {
+		exist, err := model.CheckUserExistOrDeleted(req.Phone)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if exist {
+			common.ApiErrorMsg(c, "手机号已存在，或已注销")
+			return
+		}
+		if !common.RegisterEnabled {
+			common.ApiErrorMsg(c, "管理员关闭了新用户注册")
+			return
+		}
+		displayName, _ := session.Get(oauthPendingNameKey).(string)
+		email, _ := session.Get(oauthPendingEmailKey).(string)
+		user = &model.User{
+			Username:    providerName + "_" + strconv.Itoa(model.GetMaxUserId()+1),
+			DisplayName: common.GetStringIfEmpty(displayName, provider.Name+" User"),
+			Email:       email,
+			Role:        common.RoleCommonUser,
+			Status:      common.UserStatusEnabled,
+			Phone:       req.Phone,
+		}
+		provider.SetId(user, providerId)
+		inviterId := 0
+		if affCode, ok := session.Get("aff").(string); ok && affCode != "" {
+			inviterId, _ = model.GetUserIdByAffCode(affCode)
+		}
+		user.InviterId = inviterId
+		if err := user.InsertWithInvite(inviterId, getInviteSource(c)); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	common.DeleteKey(req.Phone, common.PhoneVerificationPurpose)
+
+	session.Delete(oauthPendingProviderKey)
+	session.Delete(oauthPendingIdKey)
+	session.Delete(oauthPendingNameKey)
+	session.Delete(oauthPendingEmailKey)
+	if user.Status != common.UserStatusEnabled {
+		_ = session.Save()
+		common.ApiErrorMsg(c, "用户已被封禁")
+		return
+	}
+	setupLogin(user, c)
+}
+
+// getOAuthBindUserId 绑定第三方账户的当前用户，优先使用令牌认证的用户，其次为网页会话中的用户
+func getOAuthBindUserId(c *gin.Context) int {
+	if id := c.GetInt("id"); id != 0 {
+		return id
+	}
+	if id, ok := sessions.Default(c).Get("id").(int); ok {
+		return id
+	}
+	return 0
+}
+
+// oauthBind 将第三方身份绑定到当前登录用户
+func oauthBind(c *gin.Context, identity *oauthIdentity) error {
+	provider := oauthProviders[identity.Provider]
+	if provider.IsTaken(identity.Id) {
+		return errors.New("该 " + provider.Name + " 账户已被绑定")
+	}
+	userId := getOAuthBindUserId(c)
+	if userId == 0 {
+		return errors.New("请先登录")
+	}
+	user := model.User{Id: userId}
+	if err := user.FillUserById(); err != nil {
+		return err
+	}
+	if user.Id == 0 {
+		return errors.New("用户已注销")
+	}
+	if provider.GetId(&user) != "" {
+		return errors.New("请先解绑当前的 " + provider.Name + " 账户")
+	}
+	provider.SetId(&user, identity.Id)
+	return user.Update(false)
+}
+
+// UnbindOAuth 解绑当前用户的第三方账户，仅在已绑定手机号、解绑后仍可登录时允许
+func UnbindOAuth(c *gin.Context) {
+	provider, ok := oauthProviders[c.Param("provider")]
+	if !ok {
+		common.ApiErrorMsg(c, "不支持的第三方登录方式")
+		return
+	}
+	user, err := model.GetUserById(c.GetInt("id"), false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if provider.GetId(user) == "" {
+		common.ApiErrorMsg(c, "未绑定 "+provider.Name+" 账户")
+		return
+	}
+	if user.Phone == "" {
+		common.ApiErrorMsg(c, "请先绑定手机号再解绑第三方账户")
+		return
+	}
+	if err := model.UnbindUserOAuth(user.Id, provider.Column); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -7,10 +7,8 @@ import (
 	"net/http"
 	"net/url"
 	"one-api/common"
-	"one-api/model"
 	"one-api/setting"
 	"one-api/setting/system_setting"
-	"strconv"
 	"strings"
 	"time"
 
@@ -129,56 +127,16 @@ func OidcAuth(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
-	user := model.User{
-		OidcId: oidcUser.OpenID,
-	}
-	if model.IsOidcIdAlreadyTaken(user.OidcId) {
-		err := user.FillUserByOidcId()
-		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
-			return
-		}
-	} else {
-		if common.RegisterEnabled {
-			user.Email = oidcUser.Email
-			if oidcUser.PreferredUsername != "" {
-				user.Username = oidcUser.PreferredUsername
-			} else {
-				user.Username = "oidc_" + strconv.Itoa(model.GetMaxUserId()+1)
-			}
-			if oidcUser.Name != "" {
-				user.DisplayName = oidcUser.Name
-			} else {
-				user.DisplayName = "OIDC User"
-			}
-			err := user.Insert(0)
-			if err != nil {
-				c.JSON(http.StatusOK, gin.H{
-					"success": false,
-					"message": err.Error(),
-				})
-				return
-			}
-		} else {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": "管理员关闭了新用户注册",
-			})
-			return
-		}
+	displayName := oidcUser.Name
+	if displayName == "" {
+		displayName = "OIDC User"
 	}
-
-	if user.Status != common.UserStatusEnabled {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "用户已被封禁",
-			"success": false,
-		})
-		return
-	}
-	setupLogin(&user, c)
+	oauthLogin(c, &oauthIdentity{
+		Provider:    "oidc",
+		Id:          oidcUser.OpenID,
+		DisplayName: displayName,
+		Email:       oidcUser.Email,
+	})
 }
 
 func OidcBind(c *gin.Context) {
@@ -195,27 +153,10 @@ func OidcBind(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
-	user := model.User{
-		OidcId: oidcUser.OpenID,
-	}
-	if model.IsOidcIdAlreadyTaken(user.OidcId) {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": "该 OIDC 账户已被绑定",
-		})
-		return
-	}
-	session := sessions.Default(c)
-	id := session.Get("id")
-	// id := c.GetInt("id")  // critical bug!
-	user.Id = id.(int)
-	err = user.FillUserById()
-	if err != nil {
-		common.ApiError(c, err)
-		return
-	}
-	user.OidcId = oidcUser.OpenID
-	err = user.Update(false)
+	err = oauthBind(c, &oauthIdentity{
+		Provider: "oidc",
+		Id:       oidcUser.OpenID,
+	})
 	if err != nil {
 		common.ApiError(c, err)
 		return
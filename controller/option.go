@@ -9,7 +9,6 @@ import (
 	"one-api/setting/console_setting"
 	"one-api/setting/ratio_setting"
 	"one-api/setting/system_setting"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,7 +17,7 @@ func GetOptions(c *gin.Context) {
 	var options []*model.Option
 	common.OptionMapRWMutex.Lock()
 	for k, v := range common.OptionMap {
-		if strings.HasSuffix(k, "Token") || strings.HasSuffix(k, "Secret") || strings.HasSuffix(k, "Key") {
+		if model.IsSecretOption(k) {
 			continue
 		}
 		options = append(options, &model.Option{
@@ -158,7 +157,12 @@ func UpdateOption(c *gin.Context) {
 			return
 		}
 	}
-	err = model.UpdateOption(option.Key, option.Value)
+	common.OptionMapRWMutex.RLock()
+	originValue := common.OptionMap[option.Key]
+	common.OptionMapRWMutex.RUnlock()
+	audit := newAuditLog(c, "option.update", model.AuditTargetOption, option.Key,
+		map[string]interface{}{option.Key: originValue}, map[string]interface{}{option.Key: option.Value})
+	err = model.UpdateOptionWithAudit(option.Key, option.Value, audit)
 	if err != nil {
 		common.ApiError(c, err)
 		return
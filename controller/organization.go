@@ -0,0 +1,362 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+type OrganizationMemberRequest struct {
+	Phone      string `json:"phone"`
+	Role       string `json:"role"`
+	QuotaLimit int    `json:"quota_limit"`
+}
+
+type OrganizationDepositRequest struct {
+	Quota int `json:"quota"`
+}
+
+// getSelfOrganizationMember 获取当前用户已生效的组织成员记录，manage 为 true 时要求所有者或管理员
+func getSelfOrganizationMember(c *gin.Context, manage bool) (*model.OrganizationMember, bool) {
+	member, err := model.GetUserOrganizationMember(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, false
+	}
+	if member == nil || !member.IsActive() {
+		common.ApiErrorMsg(c, "尚未加入组织")
+		return nil, false
+	}
+	if manage && !member.CanManage() {
+		common.ApiErrorMsg(c, "仅组织所有者或管理员可执行该操作")
+		return nil, false
+	}
+	return member, true
+}
+
+// GetSelfOrganization 当前用户所在的组织，包括待接受的邀请；未加入组织时 data 为 null
+func GetSelfOrganization(c *gin.Context) {
+	member, err := model.GetUserOrganizationMember(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if member == nil {
+		common.ApiSuccess(c, nil)
+		return
+	}
+	org, err := model.GetOrganizationById(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"organization": org,
+		"member":       member,
+	})
+}
+
+func CreateOrganization(c *gin.Context) {
+	var req CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 64 {
+		common.ApiErrorMsg(c, "组织名称长度须为 1-64")
+		return
+	}
+	userId := c.GetInt("id")
+	exist, err := model.GetUserOrganizationMember(userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if exist != nil {
+		common.ApiErrorMsg(c, "已加入或已被邀请加入其他组织")
+		return
+	}
+	org, err := model.CreateOrganization(req.Name, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, org)
+}
+
+// DeleteOrganization 所有者解散组织，剩余额度退回所有者
+func DeleteOrganization(c *gin.Context) {
+	member, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	if member.Role != model.OrganizationRoleOwner {
+		common.ApiErrorMsg(c, "仅组织所有者可解散组织")
+		return
+	}
+	org, err := model.GetOrganizationById(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteOrganization(org); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// DepositOrganizationQuota 成员将个人额度转入组织
+func DepositOrganizationQuota(c *gin.Context) {
+	member, ok := getSelfOrganizationMember(c, false)
+	if !ok {
+		return
+	}
+	var req OrganizationDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if err := model.DepositOrganizationQuota(member.OrganizationId, member.UserId, req.Quota); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(member.UserId, model.LogTypeManage, "转入组织额度 "+common.LogQuota(req.Quota))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetOrganizationMembers(c *gin.Context) {
+	member, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	members, err := model.GetOrganizationMembers(member.OrganizationId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, members)
+}
+
+// InviteOrganizationMember 按手机号邀请用户加入组织
+func InviteOrganizationMember(c *gin.Context) {
+	member, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	var req OrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if !model.IsValidOrganizationRole(req.Role) || req.QuotaLimit < 0 {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	// 只有所有者可以任命管理员
+	if req.Role == model.OrganizationRoleAdmin && member.Role != model.OrganizationRoleOwner {
+		common.ApiErrorMsg(c, "仅组织所有者可设置管理员")
+		return
+	}
+	phone, err := common.NormalizePhone(req.Phone)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	user, err := model.GetUserByPhone(phone)
+	if err != nil {
+		common.ApiErrorMsg(c, "该手机号未注册")
+		return
+	}
+	invited, err := model.InviteOrganizationMember(member.OrganizationId, user.Id, req.Role, req.QuotaLimit, member.UserId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, invited)
+}
+
+func UpdateOrganizationMember(c *gin.Context) {
+	member, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req OrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if !model.IsValidOrganizationRole(req.Role) || req.QuotaLimit < 0 {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if member.Role != model.OrganizationRoleOwner {
+		target, err := model.GetUserOrganizationMember(userId)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		// 管理员只能修改普通成员，且不能任命管理员
+		if target == nil || target.Role != model.OrganizationRoleMember || req.Role != model.OrganizationRoleMember {
+			common.ApiErrorMsg(c, "仅组织所有者可修改管理员")
+			return
+		}
+	}
+	if err := model.UpdateOrganizationMember(member.OrganizationId, userId, req.Role, req.QuotaLimit); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RemoveOrganizationMember 移除成员或撤回邀请
+func RemoveOrganizationMember(c *gin.Context) {
+	member, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if member.Role != model.OrganizationRoleOwner {
+		target, err := model.GetUserOrganizationMember(userId)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if target == nil || target.Role != model.OrganizationRoleMember {
+			common.ApiErrorMsg(c, "仅组织所有者可移除管理员")
+			return
+		}
+	}
+	if err := model.RemoveOrganizationMember(member.OrganizationId, userId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func LeaveOrganization(c *gin.Context) {
+	member, ok := getSelfOrganizationMember(c, false)
+	if !ok {
+		return
+	}
+	if member.Role == model.OrganizationRoleOwner {
+		common.ApiErrorMsg(c, "组织所有者无法退出，请解散组织")
+		return
+	}
+	if err := model.RemoveOrganizationMember(member.OrganizationId, member.UserId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func AcceptOrganizationInvitation(c *gin.Context) {
+	if err := model.AcceptOrganizationInvitation(c.GetInt("id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func DeclineOrganizationInvitation(c *gin.Context) {
+	if err := model.DeclineOrganizationInvitation(c.GetInt("id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// GetOrganizationUsage 组织用量报表，按成员与模型汇总组织令牌的消费
+func GetOrganizationUsage(c *gin.Context) {
+	member, ok := getSelfOrganizationMember(c, true)
+	if !ok {
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	usages, err := model.GetOrganizationUsage(member.OrganizationId, startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, usages)
+}
+
+func GetAllOrganizations(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	orgs, total, err := model.GetAllOrganizations(pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(orgs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// UpdateOrganization 管理员修改组织名称、额度与状态
+func UpdateOrganization(c *gin.Context) {
+	var req model.Organization
+	if err := c.ShouldBindJSON(&req); err != nil || req.Id == 0 {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if req.Status != model.OrganizationStatusEnabled && req.Status != model.OrganizationStatusDisabled {
+		common.ApiErrorMsg(c, "无效的组织状态")
+		return
+	}
+	org, err := model.GetOrganizationById(req.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	originOrg := *org
+	org.Name = strings.TrimSpace(req.Name)
+	org.Quota = req.Quota
+	org.Status = req.Status
+	if err := model.UpdateOrganizationByAdmin(org); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordAuditLog(c, "organization.update", model.AuditTargetOrganization, org.Id, &originOrg, org)
+	common.ApiSuccess(c, org)
+}
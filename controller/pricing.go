@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"one-api/common"
 	"one-api/model"
 	"one-api/setting"
 	"one-api/setting/ratio_setting"
@@ -48,7 +49,12 @@ func GetPricing(c *gin.Context) {
 
 func ResetModelRatio(c *gin.Context) {
 	defaultStr := ratio_setting.DefaultModelRatio2JSONString()
-	err := model.UpdateOption("ModelRatio", defaultStr)
+	common.OptionMapRWMutex.RLock()
+	originStr := common.OptionMap["ModelRatio"]
+	common.OptionMapRWMutex.RUnlock()
+	audit := newAuditLog(c, "ratio.reset", model.AuditTargetRatio, "ModelRatio",
+		map[string]interface{}{"ModelRatio": originStr}, map[string]interface{}{"ModelRatio": defaultStr})
+	err := model.UpdateOptionWithAudit("ModelRatio", defaultStr, audit)
 	if err != nil {
 		c.JSON(200, gin.H{
 			"success": false,
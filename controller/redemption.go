@@ -99,7 +99,7 @@ func AddRedemption(c *gin.Context) {
 			Quota:       redemption.Quota,
 			ExpiredTime: redemption.ExpiredTime,
 		}
-		err = cleanRedemption.Insert()
+		err = cleanRedemption.InsertWithAudit(newAuditLog(c, "redemption.create", model.AuditTargetRedemption, "", nil, &cleanRedemption))
 		if err != nil {
 			c.JSON(http.StatusOK, gin.H{
 				"success": false,
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"one-api/common"
+	"one-api/common/metrics"
 	"one-api/constant"
 	constant2 "one-api/constant"
 	"one-api/dto"
@@ -19,6 +20,7 @@ import (
 	"one-api/service"
 	"one-api/types"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -75,6 +77,10 @@ func Relay(c *gin.Context) {
 	group := c.GetString("group")
 	originalModel := c.GetString("original_model")
 	var newAPIError *types.NewAPIError
+	startTime := time.Now()
+	defer func() {
+		recordRelayMetrics(c, startTime, originalModel, group, newAPIError)
+	}()
 
 	for i := 0; i <= common.RetryTimes; i++ {
 		channel, err := getChannel(c, group, originalModel, i)
@@ -138,6 +144,10 @@ func WssRelay(c *gin.Context) {
 	//wss://api.openai.com/v1/realtime?model=gpt-4o-realtime-preview-2024-10-01
 	originalModel := c.GetString("original_model")
 	var newAPIError *types.NewAPIError
+	startTime := time.Now()
+	defer func() {
+		recordRelayMetrics(c, startTime, originalModel, group, newAPIError)
+	}()
 
 	for i := 0; i <= common.RetryTimes; i++ {
 		channel, err := getChannel(c, group, originalModel, i)
@@ -180,6 +190,10 @@ func RelayClaude(c *gin.Context) {
 	group := c.GetString("group")
 	originalModel := c.GetString("original_model")
 	var newAPIError *types.NewAPIError
+	startTime := time.Now()
+	defer func() {
+		recordRelayMetrics(c, startTime, originalModel, group, newAPIError)
+	}()
 
 	for i := 0; i <= common.RetryTimes; i++ {
 		channel, err := getChannel(c, group, originalModel, i)
@@ -237,6 +251,21 @@ func claudeRequest(c *gin.Context, channel *model.Channel) *types.NewAPIError {
 	return relay.ClaudeHelper(c)
 }
 
+func recordRelayMetrics(c *gin.Context, startTime time.Time, originalModel string, group string, newAPIError *types.NewAPIError) {
+	result := metrics.RelayResult{
+		Model:     originalModel,
+		ChannelId: c.GetInt("channel_id"),
+		Group:     group,
+		Seconds:   time.Since(startTime).Seconds(),
+		Attempts:  len(c.GetStringSlice("use_channel")),
+	}
+	if newAPIError != nil {
+		result.ErrorCode = string(newAPIError.GetErrorCode())
+		result.StatusCode = newAPIError.StatusCode
+	}
+	metrics.RecordRelay(result)
+}
+
 func addUsedChannel(c *gin.Context, channelId int) {
 	useChannel := c.GetStringSlice("use_channel")
 	useChannel = append(useChannel, fmt.Sprintf("%d", channelId))
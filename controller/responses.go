@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func responseNotFound(c *gin.Context, responseId string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": dto.OpenAIError{
+			Message: fmt.Sprintf("Response with id '%s' not found.", responseId),
+			Type:    "invalid_request_error",
+			Param:   "response_id",
+			Code:    "response_not_found",
+		},
+	})
+}
+
+func responseStoreError(c *gin.Context, err error) {
+	common.LogError(c, "stored response error: "+err.Error())
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": dto.OpenAIError{
+			Message: "failed to access stored response",
+			Type:    "new_api_error",
+			Code:    "stored_response_error",
+		},
+	})
+}
+
+// GetStoredResponse 返回本地保存的 Responses API 响应对象
+func GetStoredResponse(c *gin.Context) {
+	responseId := c.Param("id")
+	stored, err := model.GetStoredResponse(c.GetInt("id"), responseId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			responseNotFound(c, responseId)
+			return
+		}
+		responseStoreError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", []byte(stored.Response))
+}
+
+// DeleteStoredResponse 删除本地保存的 Responses API 响应，删除后无法再通过 previous_response_id 续接
+func DeleteStoredResponse(c *gin.Context) {
+	responseId := c.Param("id")
+	deleted, err := model.DeleteStoredResponse(c.GetInt("id"), responseId)
+	if err != nil {
+		responseStoreError(c, err)
+		return
+	}
+	if !deleted {
+		responseNotFound(c, responseId)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      responseId,
+		"object":  "response",
+		"deleted": true,
+	})
+}
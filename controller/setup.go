@@ -93,6 +93,14 @@ func PostSetup(c *gin.Context) {
 			})
 			return
 		}
+		req.Phone, err = common.NormalizePhone(req.Phone)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 
 		// Validate phone verification code
 		if req.PhoneVerificationCode == "" {
@@ -143,6 +151,7 @@ func PostSetup(c *gin.Context) {
 			})
 			return
 		}
+		model.RecordQuotaLedger(rootUser.Id, model.QuotaLedgerTypeRegister, rootUser.Quota)
 	}
 
 	// Set operation modes
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetSelfStatement(c *gin.Context) {
+	renderStatement(c, c.GetInt("id"))
+}
+
+func GetUserStatement(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	renderStatement(c, userId)
+}
+
+func renderStatement(c *gin.Context, userId int) {
+	monthStart, err := model.ParseStatementMonth(c.Query("month"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	statement, err := model.GetUserStatement(userId, monthStart)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if c.Query("format") == "csv" {
+		writeStatementCSV(c, statement)
+		return
+	}
+	common.ApiSuccess(c, statement)
+}
+
+func writeStatementCSV(c *gin.Context, statement *model.Statement) {
+	filename := fmt.Sprintf("statement-%d-%s.csv", statement.UserId, statement.Month)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+	// BOM，保证 Excel 打开时中文不乱码
+	_, _ = c.Writer.Write([]byte("\xEF\xBB\xBF"))
+
+	w := csv.NewWriter(c.Writer)
+	formatMoney := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 6, 64)
+	}
+	formatTime := func(ts int64) string {
+		return time.Unix(ts, 0).Format("2006-01-02 15:04:05")
+	}
+
+	_ = w.Write([]string{"user_id", strconv.Itoa(statement.UserId)})
+	_ = w.Write([]string{"username", statement.Username})
+	_ = w.Write([]string{"month", statement.Month})
+	_ = w.Write([]string{})
+	_ = w.Write([]string{"item", "quota", "usd", "rmb"})
+	// 早于额度流水启用的月份没有可信的余额，留空而不是输出 0
+	formatBalance := func(quota int, usd float64, rmb float64) []string {
+		if !statement.BalanceAvailable {
+			return []string{"", "", ""}
+		}
+		return []string{strconv.Itoa(quota), formatMoney(usd), formatMoney(rmb)}
+	}
+	_ = w.Write(append([]string{"opening_balance"}, formatBalance(statement.OpeningBalance, statement.USD.OpeningBalance, statement.RMB.OpeningBalance)...))
+	_ = w.Write([]string{"top_up", strconv.Itoa(statement.TopUpQuota), formatMoney(statement.USD.TopUp), formatMoney(statement.RMB.TopUp)})
+	_ = w.Write([]string{"adjustment", strconv.Itoa(statement.AdjustmentQuota), formatMoney(statement.USD.Adjustment), formatMoney(statement.RMB.Adjustment)})
+	_ = w.Write([]string{"consumed", strconv.Itoa(statement.ConsumedQuota), formatMoney(statement.USD.Consumed), formatMoney(statement.RMB.Consumed)})
+	_ = w.Write(append([]string{"closing_balance"}, formatBalance(statement.ClosingBalance, statement.USD.ClosingBalance, statement.RMB.ClosingBalance)...))
+	_ = w.Write([]string{})
+	_ = w.Write([]string{"trade_no", "complete_time", "money", "quota", "status"})
+	for _, topUp := range statement.TopUps {
+		_ = w.Write([]string{topUp.TradeNo, formatTime(topUp.CompleteTime), strconv.FormatFloat(topUp.Money, 'f', 2, 64), strconv.Itoa(topUp.Quota), topUp.Status})
+	}
+	_ = w.Write([]string{})
+	_ = w.Write([]string{"adjustment_type", "count", "quota", "usd"})
+	for _, adjustment := range statement.Adjustments {
+		_ = w.Write([]string{adjustment.Type, strconv.Itoa(adjustment.Count), strconv.Itoa(adjustment.Quota), formatMoney(float64(adjustment.Quota) / common.QuotaPerUnit)})
+	}
+	_ = w.Write([]string{})
+	_ = w.Write([]string{"model_name", "requests", "prompt_tokens", "completion_tokens", "quota", "usd"})
+	for _, spend := range statement.ModelSpends {
+		_ = w.Write([]string{spend.ModelName, strconv.Itoa(spend.Requests), strconv.Itoa(spend.PromptTokens), strconv.Itoa(spend.CompletionTokens),
+			strconv.Itoa(spend.Quota), formatMoney(float64(spend.Quota) / common.QuotaPerUnit)})
+	}
+	w.Flush()
+}
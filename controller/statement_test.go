@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"one-api/common"
+	"one-api/model"
+)
+
+func TestStatementBeforeQuotaLedger(t *testing.T) {
+	setupStripeTest(t)
+	if err := model.DB.AutoMigrate(&model.Token{}); err != nil {
+		t.Fatal(err)
+	}
+	user := createStripeTestUser(t, 2000)
+
+	now := time.Now()
+	lastMonth := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local)
+	twoMonthsAgo := lastMonth.AddDate(0, -1, 0)
+	// 升级前的充值只有订单，没有额度流水；额度流水从上个月初开始记录
+	for _, topUp := range []*model.TopUp{
+		{UserId: user.Id, TradeNo: "old", Money: 3, Quota: 300, CompleteTime: twoMonthsAgo.Unix() + 10, Status: common.TopUpStatusSuccess},
+		{UserId: user.Id, TradeNo: "new", Money: 5, Quota: 500, CompleteTime: lastMonth.Unix() + 10, Status: common.TopUpStatusSuccess},
+	} {
+		if err := model.DB.Create(topUp).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ledger := range []*model.QuotaLedger{
+		{UserId: user.Id, CreatedAt: lastMonth.Unix(), Type: model.QuotaLedgerTypeAdminAdjust, Quota: 200},
+		{UserId: user.Id, CreatedAt: lastMonth.Unix() + 10, Type: model.QuotaLedgerTypeTopUp, Quota: 500},
+	} {
+		if err := model.DB.Create(ledger).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	statement, err := model.GetUserStatement(user.Id, twoMonthsAgo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.TopUpQuota != 300 || len(statement.TopUps) != 1 {
+		t.Fatalf("expected top-up total to come from orders, got %d with %d rows", statement.TopUpQuota, len(statement.TopUps))
+	}
+	if statement.BalanceAvailable || statement.OpeningBalance != 0 || statement.ClosingBalance != 0 {
+		t.Fatalf("expected balances unavailable before the ledger, got %+v", statement)
+	}
+
+	statement, err = model.GetUserStatement(user.Id, lastMonth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statement.BalanceAvailable {
+		t.Fatal("expected balances available once the ledger exists")
+	}
+	if statement.TopUpQuota != 500 || statement.AdjustmentQuota != 200 {
+		t.Fatalf("unexpected top-up %d / adjustment %d", statement.TopUpQuota, statement.AdjustmentQuota)
+	}
+	if statement.ClosingBalance != 2000 || statement.OpeningBalance != 1300 {
+		t.Fatalf("unexpected balances %d -> %d", statement.OpeningBalance, statement.ClosingBalance)
+	}
+}
@@ -5,12 +5,10 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"io"
-	"net/http"
 	"one-api/common"
-	"one-api/model"
 	"sort"
+	"strings"
 
-	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
@@ -30,34 +28,11 @@ func TelegramBind(c *gin.Context) {
 		})
 		return
 	}
-	telegramId := params["id"][0]
-	if model.IsTelegramIdAlreadyTaken(telegramId) {
-		c.JSON(200, gin.H{
-			"message": "该 Telegram 账户已被绑定",
-			"success": false,
-		})
-		return
-	}
-
-	session := sessions.Default(c)
-	id := session.Get("id")
-	user := model.User{Id: id.(int)}
-	if err := user.FillUserById(); err != nil {
-		c.JSON(200, gin.H{
-			"message": err.Error(),
-			"success": false,
-		})
-		return
-	}
-	if user.Id == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": "用户已注销",
-		})
-		return
-	}
-	user.TelegramId = telegramId
-	if err := user.Update(false); err != nil {
+	err := oauthBind(c, &oauthIdentity{
+		Provider: "telegram",
+		Id:       params["id"][0],
+	})
+	if err != nil {
 		c.JSON(200, gin.H{
 			"message": err.Error(),
 			"success": false,
@@ -85,16 +60,15 @@ func TelegramLogin(c *gin.Context) {
 		return
 	}
 
-	telegramId := params["id"][0]
-	user := model.User{TelegramId: telegramId}
-	if err := user.FillUserByTelegramId(); err != nil {
-		c.JSON(200, gin.H{
-			"message": err.Error(),
-			"success": false,
-		})
-		return
+	displayName := strings.TrimSpace(params.Get("first_name") + " " + params.Get("last_name"))
+	if displayName == "" {
+		displayName = params.Get("username")
 	}
-	setupLogin(&user, c)
+	oauthLogin(c, &oauthIdentity{
+		Provider:    "telegram",
+		Id:          params.Get("id"),
+		DisplayName: displayName,
+	})
 }
 
 func checkTelegramAuthorization(params map[string][]string, token string) bool {
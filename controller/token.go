@@ -96,6 +96,18 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	// 组织令牌只能由该组织的成员创建
+	if token.OrganizationId != 0 {
+		member, err := model.GetUserOrganizationMember(c.GetInt("id"))
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if member == nil || !member.IsActive() || member.OrganizationId != token.OrganizationId {
+			common.ApiErrorMsg(c, "只能为所在的组织创建令牌")
+			return
+		}
+	}
 	key, err := common.GenerateKey()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -118,6 +130,7 @@ func AddToken(c *gin.Context) {
 		ModelLimits:        token.ModelLimits,
 		AllowIps:           token.AllowIps,
 		Group:              token.Group,
+		OrganizationId:     token.OrganizationId,
 	}
 	err = cleanToken.Insert()
 	if err != nil {
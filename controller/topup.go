@@ -214,23 +214,26 @@ func EpayNotify(c *gin.Context) {
 			return
 		}
 		if topUp.Status == "pending" {
+			//user, _ := model.GetUserById(topUp.UserId, false)
+			//user.Quota += topUp.Amount * 500000
+			dAmount := decimal.NewFromInt(int64(topUp.Amount))
+			dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
+			quotaToAdd := int(dAmount.Mul(dQuotaPerUnit).IntPart())
 			topUp.Status = "success"
+			topUp.CompleteTime = common.GetTimestamp()
+			topUp.Quota = quotaToAdd
 			err := topUp.Update()
 			if err != nil {
 				log.Printf("易支付回调更新订单失败: %v", topUp)
 				return
 			}
-			//user, _ := model.GetUserById(topUp.UserId, false)
-			//user.Quota += topUp.Amount * 500000
-			dAmount := decimal.NewFromInt(int64(topUp.Amount))
-			dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
-			quotaToAdd := int(dAmount.Mul(dQuotaPerUnit).IntPart())
 			err = model.IncreaseUserQuota(topUp.UserId, quotaToAdd, true)
 			if err != nil {
 				log.Printf("易支付回调更新用户失败: %v", topUp)
 				return
 			}
 			log.Printf("易支付回调更新用户成功 %v", topUp)
+			model.RecordQuotaLedger(topUp.UserId, model.QuotaLedgerTypeTopUp, quotaToAdd)
 			model.RecordLog(topUp.UserId, model.LogTypeTopup, fmt.Sprintf("使用在线充值成功，充值金额: %v，支付金额：%f", common.LogQuota(quotaToAdd), topUp.Money))
 		}
 	} else {
@@ -263,3 +266,48 @@ func RequestAmount(c *gin.Context) {
 	}
 	c.JSON(200, gin.H{"message": "success", "data": strconv.FormatFloat(payMoney, 'f', 2, 64)})
 }
+
+// GetAllTopUps 管理端查询充值订单，支持按用户与状态筛选
+func GetAllTopUps(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	topUps, total, err := model.GetAllTopUps(userId, c.Query("status"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(topUps)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// CompleteTopUp 管理员手动补单，用于已到账但未收到支付回调的订单
+func CompleteTopUp(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	topUp := model.GetTopUpById(id)
+	if topUp == nil {
+		common.ApiError(c, model.ErrTopUpNotFound)
+		return
+	}
+	// 与易支付回调共用订单锁，避免回调与补单同时处理同一订单
+	LockOrder(topUp.TradeNo)
+	defer UnlockOrder(topUp.TradeNo)
+	if topUp.Status != common.TopUpStatusPending {
+		common.ApiError(c, model.ErrTopUpStatusInvalid)
+		return
+	}
+	completed := *topUp
+	completed.Status = common.TopUpStatusSuccess
+	completed.Quota = topUp.GetPendingQuota()
+	audit := newAuditLog(c, "topup.complete", model.AuditTargetTopUp, topUp.Id, topUp, &completed)
+	topUp, err = model.CompleteTopUpWithAudit(id, audit)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, topUp)
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stripe/stripe-go/v81"
+	portalsession "github.com/stripe/stripe-go/v81/billingportal/session"
 	"github.com/stripe/stripe-go/v81/checkout/session"
 	"github.com/stripe/stripe-go/v81/webhook"
 	"github.com/thanhpk/randstr"
@@ -141,55 +143,109 @@ func StripeWebhook(c *gin.Context) {
 		return
 	}
 
+	// 以事件ID去重，Stripe 重试同一事件时直接确认
+	claimed, err := model.ClaimStripeEvent(event.ID, string(event.Type))
+	if err != nil {
+		log.Printf("记录Stripe Webhook事件失败: %v\n", err)
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	}
+	if !claimed {
+		log.Printf("Stripe Webhook事件已处理: %s\n", event.ID)
+		c.Status(http.StatusOK)
+		return
+	}
+
 	switch event.Type {
 	case stripe.EventTypeCheckoutSessionCompleted:
-		sessionCompleted(event)
+		err = sessionCompleted(event)
 	case stripe.EventTypeCheckoutSessionExpired:
-		sessionExpired(event)
+		err = sessionExpired(event)
+	case stripe.EventTypeInvoicePaid:
+		err = invoicePaid(event)
+	case stripe.EventTypeCustomerSubscriptionDeleted:
+		err = subscriptionDeleted(event)
+	case stripe.EventTypeChargeRefunded:
+		err = chargeRefunded(event)
 	default:
 		log.Printf("不支持的Stripe Webhook事件类型: %s\n", event.Type)
 	}
 
+	if err != nil {
+		log.Printf("处理Stripe Webhook事件失败: %s, %v\n", event.ID, err)
+		// 释放事件记录，让 Stripe 重试
+		if err := model.ReleaseStripeEvent(event.ID); err != nil {
+			log.Printf("释放Stripe Webhook事件失败: %s, %v\n", event.ID, err)
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
 	c.Status(http.StatusOK)
 }
 
-func sessionCompleted(event stripe.Event) {
+func sessionCompleted(event stripe.Event) error {
 	customerId := event.GetObjectValue("customer")
 	referenceId := event.GetObjectValue("client_reference_id")
 	status := event.GetObjectValue("status")
 	if "complete" != status {
 		log.Println("错误的Stripe Checkout完成状态:", status, ",", referenceId)
-		return
+		return nil
 	}
 
-	err := model.Recharge(referenceId, customerId)
-	if err != nil {
+	if event.GetObjectValue("mode") == string(stripe.CheckoutSessionModeSubscription) {
+		subscriptionId := event.GetObjectValue("subscription")
+		err := model.ActivateStripeSubscription(referenceId, subscriptionId, customerId)
+		if err != nil {
+			return fmt.Errorf("%s, %s", err.Error(), referenceId)
+		}
+		log.Printf("订阅已生效：%s, %s", referenceId, subscriptionId)
+		return nil
+	}
+
+	err := model.Recharge(referenceId, customerId, event.GetObjectValue("payment_intent"))
+	if errors.Is(err, model.ErrTopUpStatusInvalid) {
+		// 订单已处理，Stripe 以新事件重复通知时无需重试
 		log.Println(err.Error(), referenceId)
-		return
+		return nil
+	}
+	if err != nil {
+		// 返回错误以释放事件记录，由 Stripe 重试
+		return fmt.Errorf("%w, %s", err, referenceId)
 	}
 
 	total, _ := strconv.ParseFloat(event.GetObjectValue("amount_total"), 64)
 	currency := strings.ToUpper(event.GetObjectValue("currency"))
 	log.Printf("收到款项：%s, %.2f(%s)", referenceId, total/100, currency)
+	return nil
 }
 
-func sessionExpired(event stripe.Event) {
+func sessionExpired(event stripe.Event) error {
 	referenceId := event.GetObjectValue("client_reference_id")
 	status := event.GetObjectValue("status")
 	if "expired" != status {
 		log.Println("错误的Stripe Checkout过期状态:", status, ",", referenceId)
-		return
+		return nil
 	}
 
 	if len(referenceId) == 0 {
 		log.Println("未提供支付单号")
-		return
+		return nil
+	}
+
+	if event.GetObjectValue("mode") == string(stripe.CheckoutSessionModeSubscription) {
+		sub := model.GetStripeSubscriptionByReferenceId(referenceId)
+		if sub == nil || sub.Status != common.StripeSubscriptionStatusPending {
+			return nil
+		}
+		sub.Status = common.StripeSubscriptionStatusCanceled
+		return sub.Update()
 	}
 
 	topUp := model.GetTopUpByTradeNo(referenceId)
 	if topUp == nil {
 		log.Println("充值订单不存在", referenceId)
-		return
+		return nil
 	}
 
 	if topUp.Status != common.TopUpStatusPending {
@@ -199,19 +255,183 @@ func sessionExpired(event stripe.Event) {
 	topUp.Status = common.TopUpStatusExpired
 	err := topUp.Update()
 	if err != nil {
-		log.Println("过期充值订单失败", referenceId, ", err:", err.Error())
-		return
+		return fmt.Errorf("过期充值订单失败 %s, err: %s", referenceId, err.Error())
 	}
 
 	log.Println("充值订单已过期", referenceId)
+	return nil
 }
 
-func genStripeLink(referenceId string, customerId string, email string, amount int64) (string, error) {
-	if !strings.HasPrefix(setting.StripeApiSecret, "sk_") && !strings.HasPrefix(setting.StripeApiSecret, "rk_") {
-		return "", fmt.Errorf("无效的Stripe API密钥")
+// stripeInvoice 只包含处理 invoice.paid 所需的字段
+type stripeInvoice struct {
+	Id                  string `json:"id"`
+	Subscription        string `json:"subscription"`
+	PaymentIntent       string `json:"payment_intent"`
+	AmountPaid          int64  `json:"amount_paid"`
+	Currency            string `json:"currency"`
+	SubscriptionDetails *struct {
+		Metadata map[string]string `json:"metadata"`
+	} `json:"subscription_details"`
+	Lines struct {
+		Data []struct {
+			Period struct {
+				End int64 `json:"end"`
+			} `json:"period"`
+		} `json:"data"`
+	} `json:"lines"`
+}
+
+func invoicePaid(event stripe.Event) error {
+	var invoice stripeInvoice
+	if err := common.Unmarshal(event.Data.Raw, &invoice); err != nil {
+		return err
+	}
+	if invoice.Subscription == "" {
+		// 非订阅账单（一次性 Checkout）由 checkout.session.completed 处理
+		return nil
 	}
 
-	stripe.Key = setting.StripeApiSecret
+	sub := model.GetStripeSubscriptionBySubscriptionId(invoice.Subscription)
+	if sub == nil && invoice.SubscriptionDetails != nil {
+		// invoice.paid 可能先于 checkout.session.completed 到达，通过订阅元数据关联
+		referenceId := invoice.SubscriptionDetails.Metadata["reference_id"]
+		if err := model.ActivateStripeSubscription(referenceId, invoice.Subscription, event.GetObjectValue("customer")); err == nil {
+			sub = model.GetStripeSubscriptionByReferenceId(referenceId)
+		}
+	}
+	if sub == nil {
+		return fmt.Errorf("订阅记录不存在: %s", invoice.Subscription)
+	}
+	if sub.Status == common.StripeSubscriptionStatusCanceled {
+		log.Println("订阅已取消，忽略账单", invoice.Id)
+		return nil
+	}
+
+	var periodEnd int64
+	if len(invoice.Lines.Data) > 0 {
+		periodEnd = invoice.Lines.Data[0].Period.End
+	}
+	err := model.RechargeStripeInvoice(sub, invoice.Id, invoice.PaymentIntent, float64(invoice.AmountPaid)/100, periodEnd)
+	if err != nil {
+		return err
+	}
+	log.Printf("收到订阅款项：%s, %.2f(%s)", invoice.Id, float64(invoice.AmountPaid)/100, strings.ToUpper(invoice.Currency))
+	return nil
+}
+
+func subscriptionDeleted(event stripe.Event) error {
+	subscriptionId := event.GetObjectValue("id")
+	sub := model.GetStripeSubscriptionBySubscriptionId(subscriptionId)
+	if sub == nil {
+		log.Println("订阅记录不存在", subscriptionId)
+		return nil
+	}
+	sub.Status = common.StripeSubscriptionStatusCanceled
+	if err := sub.Update(); err != nil {
+		return err
+	}
+	log.Println("订阅已取消", subscriptionId)
+	return nil
+}
+
+func chargeRefunded(event stripe.Event) error {
+	paymentIntent := event.GetObjectValue("payment_intent")
+	if model.GetTopUpByPaymentIntent(paymentIntent) == nil {
+		log.Println("退款未找到对应充值订单", paymentIntent)
+		return nil
+	}
+	refunded, _ := strconv.ParseInt(event.GetObjectValue("amount_refunded"), 10, 64)
+	total, _ := strconv.ParseInt(event.GetObjectValue("amount"), 10, 64)
+	err := model.RefundTopUp(paymentIntent, refunded, total)
+	if err != nil {
+		return err
+	}
+	log.Printf("充值订单退款：%s, %.2f/%.2f", paymentIntent, float64(refunded)/100, float64(total)/100)
+	return nil
+}
+
+func RequestStripeSubscribe(c *gin.Context) {
+	if setting.StripeSubscriptionPriceId == "" || setting.StripeSubscriptionQuota <= 0 {
+		c.JSON(200, gin.H{"message": "error", "data": "订阅未开启"})
+		return
+	}
+
+	id := c.GetInt("id")
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "获取用户失败"})
+		return
+	}
+
+	reference := fmt.Sprintf("new-api-sub-%d-%d-%s", user.Id, time.Now().UnixMilli(), randstr.String(4))
+	referenceId := "sub_" + common.Sha1([]byte(reference))
+
+	payLink, err := genStripeSubscriptionLink(referenceId, user.StripeCustomer, user.Email)
+	if err != nil {
+		log.Println("获取Stripe订阅支付链接失败", err)
+		c.JSON(200, gin.H{"message": "error", "data": "拉起支付失败"})
+		return
+	}
+
+	sub := &model.StripeSubscription{
+		UserId:      id,
+		ReferenceId: referenceId,
+		PriceId:     setting.StripeSubscriptionPriceId,
+		Quota:       setting.StripeSubscriptionQuota,
+		Status:      common.StripeSubscriptionStatusPending,
+	}
+	err = sub.Insert()
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "创建订阅失败"})
+		return
+	}
+	c.JSON(200, gin.H{
+		"message": "success",
+		"data": gin.H{
+			"pay_link": payLink,
+		},
+	})
+}
+
+func GetStripeSubscriptions(c *gin.Context) {
+	subs, err := model.GetUserStripeSubscriptions(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, subs)
+}
+
+func RequestStripePortal(c *gin.Context) {
+	id := c.GetInt("id")
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "获取用户失败"})
+		return
+	}
+	if user.StripeCustomer == "" {
+		c.JSON(200, gin.H{"message": "error", "data": "尚未通过Stripe支付过"})
+		return
+	}
+
+	portalLink, err := genStripePortalLink(user.StripeCustomer)
+	if err != nil {
+		log.Println("获取Stripe客户门户链接失败", err)
+		c.JSON(200, gin.H{"message": "error", "data": "获取客户门户失败"})
+		return
+	}
+	c.JSON(200, gin.H{
+		"message": "success",
+		"data": gin.H{
+			"portal_link": portalLink,
+		},
+	})
+}
+
+func genStripeLink(referenceId string, customerId string, email string, amount int64) (string, error) {
+	if err := initStripeKey(); err != nil {
+		return "", err
+	}
 
 	params := &stripe.CheckoutSessionParams{
 		ClientReferenceID: stripe.String(referenceId),
@@ -244,6 +464,69 @@ func genStripeLink(referenceId string, customerId string, email string, amount i
 	return result.URL, nil
 }
 
+func genStripeSubscriptionLink(referenceId string, customerId string, email string) (string, error) {
+	if err := initStripeKey(); err != nil {
+		return "", err
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(referenceId),
+		SuccessURL:        stripe.String(setting.ServerAddress + "/log"),
+		CancelURL:         stripe.String(setting.ServerAddress + "/topup"),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				Price:    stripe.String(setting.StripeSubscriptionPriceId),
+				Quantity: stripe.Int64(1),
+			},
+		},
+		Mode: stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		SubscriptionData: &stripe.CheckoutSessionSubscriptionDataParams{
+			Metadata: map[string]string{"reference_id": referenceId},
+		},
+	}
+
+	if "" == customerId {
+		if "" != email {
+			params.CustomerEmail = stripe.String(email)
+		}
+	} else {
+		params.Customer = stripe.String(customerId)
+	}
+
+	result, err := session.New(params)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+func genStripePortalLink(customerId string) (string, error) {
+	if err := initStripeKey(); err != nil {
+		return "", err
+	}
+
+	params := &stripe.BillingPortalSessionParams{
+		Customer:  stripe.String(customerId),
+		ReturnURL: stripe.String(setting.ServerAddress + "/topup"),
+	}
+	result, err := portalsession.New(params)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+func initStripeKey() error {
+	if !strings.HasPrefix(setting.StripeApiSecret, "sk_") && !strings.HasPrefix(setting.StripeApiSecret, "rk_") {
+		return fmt.Errorf("无效的Stripe API密钥")
+	}
+
+	stripe.Key = setting.StripeApiSecret
+	return nil
+}
+
 func GetChargedAmount(count float64, user model.User) float64 {
 	topUpGroupRatio := common.GetTopupGroupRatio(user.Group)
 	if topUpGroupRatio == 0 {
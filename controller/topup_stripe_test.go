@@ -0,0 +1,403 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/webhook"
+	"gorm.io/gorm"
+)
+
+const stripeTestWebhookSecret = "whsec_test"
+
+// stripeMock 本地 Stripe API 模拟服务，记录创建 Checkout 与客户门户会话时提交的参数
+type stripeMock struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	requests map[string]url.Values
+}
+
+func newStripeMock(t *testing.T) *stripeMock {
+	t.Helper()
+	mock := &stripeMock{requests: make(map[string]url.Values)}
+	mock.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mock.mu.Lock()
+		mock.requests[r.URL.Path] = r.PostForm
+		mock.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/checkout/sessions":
+			_, _ = fmt.Fprintf(w, `{"id":"cs_test_1","object":"checkout.session","mode":%q,"url":"https://checkout.stripe.test/cs_test_1"}`, r.PostForm.Get("mode"))
+		case "/v1/billing_portal/sessions":
+			_, _ = fmt.Fprintf(w, `{"id":"bps_test_1","object":"billing_portal.session","customer":%q,"url":"https://billing.stripe.test/bps_test_1"}`, r.PostForm.Get("customer"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"unknown path"}}`))
+		}
+	}))
+	stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		URL:               stripe.String(mock.server.URL),
+		MaxNetworkRetries: stripe.Int64(0),
+	}))
+	t.Cleanup(func() {
+		mock.server.Close()
+		stripe.SetBackend(stripe.APIBackend, nil)
+	})
+	return mock
+}
+
+func (m *stripeMock) request(path string) url.Values {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests[path]
+}
+
+func setupStripeTest(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "stripe.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&model.User{}, &model.TopUp{}, &model.StripeSubscription{}, &model.StripeEvent{}, &model.QuotaLedger{}, &model.Log{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDB, oldLogDB := model.DB, model.LOG_DB
+	model.DB, model.LOG_DB = db, db
+	common.RedisEnabled = false
+
+	oldSecret, oldWebhookSecret := setting.StripeApiSecret, setting.StripeWebhookSecret
+	oldPriceId, oldQuota := setting.StripeSubscriptionPriceId, setting.StripeSubscriptionQuota
+	setting.StripeApiSecret = "sk_test_123"
+	setting.StripeWebhookSecret = stripeTestWebhookSecret
+	setting.StripeSubscriptionPriceId = "price_monthly"
+	setting.StripeSubscriptionQuota = 500000
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB = oldDB, oldLogDB
+		setting.StripeApiSecret, setting.StripeWebhookSecret = oldSecret, oldWebhookSecret
+		setting.StripeSubscriptionPriceId, setting.StripeSubscriptionQuota = oldPriceId, oldQuota
+	})
+	gin.SetMode(gin.TestMode)
+}
+
+func createStripeTestUser(t *testing.T, quota int) *model.User {
+	t.Helper()
+	user := &model.User{
+		Username: "stripe_user",
+		Email:    "stripe@example.com",
+		Quota:    quota,
+		Status:   common.UserStatusEnabled,
+		AffCode:  "stripe",
+	}
+	if err := model.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func userQuota(t *testing.T, userId int) int {
+	t.Helper()
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.Quota
+}
+
+// sendStripeEvent 以 Stripe 的签名方式投递 Webhook 事件，返回响应状态码
+func sendStripeEvent(t *testing.T, eventId string, eventType stripe.EventType, object map[string]any, secret string) int {
+	t.Helper()
+	payload, err := json.Marshal(map[string]any{
+		"id":          eventId,
+		"object":      "event",
+		"type":        eventType,
+		"api_version": stripe.APIVersion,
+		"data":        map[string]any{"object": object},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: secret})
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/stripe/webhook", bytes.NewReader(payload))
+	c.Request.Header.Set("Stripe-Signature", signed.Header)
+	StripeWebhook(c)
+	return c.Writer.Status()
+}
+
+func callStripeHandler(t *testing.T, handler gin.HandlerFunc, userId int) map[string]any {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Set("id", userId)
+	handler(c)
+	var response map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response %q: %v", recorder.Body.String(), err)
+	}
+	return response
+}
+
+func TestStripeWebhookRejectsInvalidSignature(t *testing.T) {
+	setupStripeTest(t)
+	status := sendStripeEvent(t, "evt_bad", stripe.EventTypeInvoicePaid, map[string]any{"id": "in_1"}, "whsec_other")
+	if status != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
+	}
+	var count int64
+	model.DB.Model(&model.StripeEvent{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("stripe events recorded = %d, want 0", count)
+	}
+}
+
+func TestStripeSubscriptionLifecycle(t *testing.T) {
+	setupStripeTest(t)
+	mock := newStripeMock(t)
+	user := createStripeTestUser(t, 0)
+
+	response := callStripeHandler(t, RequestStripeSubscribe, user.Id)
+	if response["message"] != "success" {
+		t.Fatalf("subscribe response = %v", response)
+	}
+	form := mock.request("/v1/checkout/sessions")
+	if form.Get("mode") != "subscription" || form.Get("line_items[0][price]") != "price_monthly" {
+		t.Fatalf("checkout session params = %v", form)
+	}
+	referenceId := form.Get("client_reference_id")
+	if form.Get("subscription_data[metadata][reference_id]") != referenceId {
+		t.Fatalf("subscription metadata = %v, want reference %s", form, referenceId)
+	}
+
+	invoice := map[string]any{
+		"id":             "in_1",
+		"object":         "invoice",
+		"customer":       "cus_1",
+		"subscription":   "sub_1",
+		"payment_intent": "pi_1",
+		"amount_paid":    1000,
+		"currency":       "usd",
+		"subscription_details": map[string]any{
+			"metadata": map[string]any{"reference_id": referenceId},
+		},
+		"lines": map[string]any{"data": []any{map[string]any{"period": map[string]any{"end": 1767225600}}}},
+	}
+	// invoice.paid 先于 checkout.session.completed 到达时通过订阅元数据关联
+	if status := sendStripeEvent(t, "evt_invoice_1", stripe.EventTypeInvoicePaid, invoice, stripeTestWebhookSecret); status != http.StatusOK {
+		t.Fatalf("invoice.paid status = %d", status)
+	}
+	if quota := userQuota(t, user.Id); quota != 500000 {
+		t.Fatalf("quota after first invoice = %d, want 500000", quota)
+	}
+
+	// Stripe 重试同一事件，或以新事件再次通知同一账单，都不应重复充值
+	sendStripeEvent(t, "evt_invoice_1", stripe.EventTypeInvoicePaid, invoice, stripeTestWebhookSecret)
+	sendStripeEvent(t, "evt_invoice_1_dup", stripe.EventTypeInvoicePaid, invoice, stripeTestWebhookSecret)
+	if quota := userQuota(t, user.Id); quota != 500000 {
+		t.Fatalf("quota after retries = %d, want 500000", quota)
+	}
+
+	completed := map[string]any{
+		"id":                  "cs_test_1",
+		"object":              "checkout.session",
+		"mode":                "subscription",
+		"status":              "complete",
+		"customer":            "cus_1",
+		"subscription":        "sub_1",
+		"client_reference_id": referenceId,
+	}
+	if status := sendStripeEvent(t, "evt_completed", stripe.EventTypeCheckoutSessionCompleted, completed, stripeTestWebhookSecret); status != http.StatusOK {
+		t.Fatalf("checkout.session.completed status = %d", status)
+	}
+
+	invoice["id"] = "in_2"
+	invoice["payment_intent"] = "pi_2"
+	sendStripeEvent(t, "evt_invoice_2", stripe.EventTypeInvoicePaid, invoice, stripeTestWebhookSecret)
+	if quota := userQuota(t, user.Id); quota != 1000000 {
+		t.Fatalf("quota after renewal = %d, want 1000000", quota)
+	}
+
+	sub := model.GetStripeSubscriptionBySubscriptionId("sub_1")
+	if sub == nil || sub.Status != common.StripeSubscriptionStatusActive || sub.CurrentPeriodEnd != 1767225600 {
+		t.Fatalf("subscription = %+v", sub)
+	}
+	if customer := mustGetUser(t, user.Id).StripeCustomer; customer != "cus_1" {
+		t.Fatalf("stripe customer = %q, want cus_1", customer)
+	}
+
+	deleted := map[string]any{"id": "sub_1", "object": "subscription", "status": "canceled"}
+	sendStripeEvent(t, "evt_deleted", stripe.EventTypeCustomerSubscriptionDeleted, deleted, stripeTestWebhookSecret)
+	invoice["id"] = "in_3"
+	invoice["payment_intent"] = "pi_3"
+	sendStripeEvent(t, "evt_invoice_3", stripe.EventTypeInvoicePaid, invoice, stripeTestWebhookSecret)
+	if quota := userQuota(t, user.Id); quota != 1000000 {
+		t.Fatalf("quota after cancellation = %d, want 1000000", quota)
+	}
+
+	response = callStripeHandler(t, RequestStripePortal, user.Id)
+	if response["message"] != "success" {
+		t.Fatalf("portal response = %v", response)
+	}
+	if form := mock.request("/v1/billing_portal/sessions"); form.Get("customer") != "cus_1" {
+		t.Fatalf("portal session params = %v", form)
+	}
+}
+
+func TestStripeChargeRefundedClawsBackQuota(t *testing.T) {
+	setupStripeTest(t)
+	user := createStripeTestUser(t, 0)
+	topUp := &model.TopUp{
+		UserId:     user.Id,
+		Amount:     10,
+		Money:      10,
+		TradeNo:    "ref_refund",
+		CreateTime: common.GetTimestamp(),
+		Status:     common.TopUpStatusPending,
+	}
+	if err := topUp.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	completed := map[string]any{
+		"id":                  "cs_refund",
+		"object":              "checkout.session",
+		"mode":                "payment",
+		"status":              "complete",
+		"customer":            "cus_2",
+		"payment_intent":      "pi_refund",
+		"client_reference_id": "ref_refund",
+		"amount_total":        1000,
+		"currency":            "usd",
+	}
+	sendStripeEvent(t, "evt_paid", stripe.EventTypeCheckoutSessionCompleted, completed, stripeTestWebhookSecret)
+	credited := int(10 * common.QuotaPerUnit)
+	if quota := userQuota(t, user.Id); quota != credited {
+		t.Fatalf("quota after payment = %d, want %d", quota, credited)
+	}
+
+	charge := map[string]any{
+		"id":              "ch_1",
+		"object":          "charge",
+		"payment_intent":  "pi_refund",
+		"amount":          1000,
+		"amount_refunded": 250,
+	}
+	sendStripeEvent(t, "evt_refund_1", stripe.EventTypeChargeRefunded, charge, stripeTestWebhookSecret)
+	if quota := userQuota(t, user.Id); quota != credited*3/4 {
+		t.Fatalf("quota after partial refund = %d, want %d", quota, credited*3/4)
+	}
+
+	// amount_refunded 为累计值，再次退款只扣回差额
+	charge["amount_refunded"] = 1000
+	sendStripeEvent(t, "evt_refund_2", stripe.EventTypeChargeRefunded, charge, stripeTestWebhookSecret)
+	sendStripeEvent(t, "evt_refund_2", stripe.EventTypeChargeRefunded, charge, stripeTestWebhookSecret)
+	if quota := userQuota(t, user.Id); quota != 0 {
+		t.Fatalf("quota after full refund = %d, want 0", quota)
+	}
+	if topUp := model.GetTopUpByPaymentIntent("pi_refund"); topUp.Status != common.TopUpStatusRefunded {
+		t.Fatalf("top up status = %s, want %s", topUp.Status, common.TopUpStatusRefunded)
+	}
+
+	sums, err := model.SumUserQuotaLedger(user.Id, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger := make(map[string]int)
+	for _, sum := range sums {
+		ledger[sum.Type] = sum.Quota
+	}
+	if ledger[model.QuotaLedgerTypeTopUp] != credited || ledger[model.QuotaLedgerTypeTopUpRefund] != -credited {
+		t.Fatalf("quota ledger = %v", ledger)
+	}
+}
+
+// TestStripeCheckoutCompletedRetriesAfterFailure 充值事务失败时释放事件记录，Stripe 重试同一事件后正常到账；
+// 订单完成后以新事件重复通知不会重复充值
+func TestStripeCheckoutCompletedRetriesAfterFailure(t *testing.T) {
+	setupStripeTest(t)
+	user := createStripeTestUser(t, 0)
+	topUp := &model.TopUp{
+		UserId:     user.Id,
+		Amount:     5,
+		Money:      5,
+		TradeNo:    "ref_retry",
+		CreateTime: common.GetTimestamp(),
+		Status:     common.TopUpStatusPending,
+	}
+	if err := topUp.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	completed := map[string]any{
+		"id":                  "cs_retry",
+		"object":              "checkout.session",
+		"mode":                "payment",
+		"status":              "complete",
+		"customer":            "cus_3",
+		"payment_intent":      "pi_retry",
+		"client_reference_id": "ref_retry",
+		"amount_total":        500,
+		"currency":            "usd",
+	}
+
+	// 额度流水写入失败时整个充值事务回滚
+	if err := model.DB.Migrator().DropTable(&model.QuotaLedger{}); err != nil {
+		t.Fatal(err)
+	}
+	if status := sendStripeEvent(t, "evt_retry", stripe.EventTypeCheckoutSessionCompleted, completed, stripeTestWebhookSecret); status != http.StatusInternalServerError {
+		t.Fatalf("status with failing ledger = %d, want %d", status, http.StatusInternalServerError)
+	}
+	if quota := userQuota(t, user.Id); quota != 0 {
+		t.Fatalf("quota after failed recharge = %d, want 0", quota)
+	}
+	var count int64
+	model.DB.Model(&model.StripeEvent{}).Where("id = ?", "evt_retry").Count(&count)
+	if count != 0 {
+		t.Fatal("failed event is still claimed")
+	}
+
+	if err := model.DB.AutoMigrate(&model.QuotaLedger{}); err != nil {
+		t.Fatal(err)
+	}
+	if status := sendStripeEvent(t, "evt_retry", stripe.EventTypeCheckoutSessionCompleted, completed, stripeTestWebhookSecret); status != http.StatusOK {
+		t.Fatalf("retry status = %d, want %d", status, http.StatusOK)
+	}
+	credited := int(5 * common.QuotaPerUnit)
+	if quota := userQuota(t, user.Id); quota != credited {
+		t.Fatalf("quota after retry = %d, want %d", quota, credited)
+	}
+
+	if status := sendStripeEvent(t, "evt_retry_dup", stripe.EventTypeCheckoutSessionCompleted, completed, stripeTestWebhookSecret); status != http.StatusOK {
+		t.Fatalf("duplicate notification status = %d, want %d", status, http.StatusOK)
+	}
+	if quota := userQuota(t, user.Id); quota != credited {
+		t.Fatalf("quota after duplicate notification = %d, want %d", quota, credited)
+	}
+}
+
+func mustGetUser(t *testing.T, userId int) *model.User {
+	t.Helper()
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
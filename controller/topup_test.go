@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"one-api/common"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func completeTopUp(t *testing.T, topUpId int) map[string]any {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(topUpId)}}
+	c.Set("id", 1)
+	c.Set("username", "root")
+	CompleteTopUp(c)
+	var response map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response %q: %v", recorder.Body.String(), err)
+	}
+	return response
+}
+
+func TestCompleteTopUpManually(t *testing.T) {
+	setupStripeTest(t)
+	if err := model.DB.AutoMigrate(&model.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	user := createStripeTestUser(t, 0)
+	topUp := &model.TopUp{UserId: user.Id, Amount: 10, Money: 72, TradeNo: "USR1NOabc", Status: common.TopUpStatusPending}
+	if err := topUp.Insert(); err != nil {
+		t.Fatal(err)
+	}
+
+	if response := completeTopUp(t, topUp.Id); response["success"] != true {
+		t.Fatalf("expected manual completion to succeed, got %v", response)
+	}
+	expected := int(10 * common.QuotaPerUnit)
+	if got := userQuota(t, user.Id); got != expected {
+		t.Fatalf("expected quota %d, got %d", expected, got)
+	}
+	completed := model.GetTopUpById(topUp.Id)
+	if completed.Status != common.TopUpStatusSuccess || completed.Quota != expected {
+		t.Fatalf("unexpected order after completion: %+v", completed)
+	}
+	var audits []*model.AuditLog
+	model.DB.Where("action = ? AND target_id = ?", "topup.complete", strconv.Itoa(topUp.Id)).Find(&audits)
+	if len(audits) != 1 || audits[0].ActorId != 1 {
+		t.Fatalf("expected one audit entry, got %+v", audits)
+	}
+
+	// 已完成的订单不能重复补单
+	if response := completeTopUp(t, topUp.Id); response["success"] != false {
+		t.Fatalf("expected repeated completion to fail, got %v", response)
+	}
+	if got := userQuota(t, user.Id); got != expected {
+		t.Fatalf("expected quota unchanged at %d, got %d", expected, got)
+	}
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"strconv"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFALoginRequest struct {
+	TwoFAToken string `json:"two_fa_token"`
+	Code       string `json:"code"`
+}
+
+// isTwoFARequired 当前设置下该角色是否必须启用两步验证
+func isTwoFARequired(role int) bool {
+	return common.AdminTwoFAEnforcementEnabled && role >= common.RoleAdminUser
+}
+
+// LoginTwoFA 使用登录时签发的临时令牌与验证码（或恢复码）完成登录
+func LoginTwoFA(c *gin.Context) {
+	var req TwoFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.TwoFAToken == "" || req.Code == "" {
+		common.ApiErrorMsg(c, "请输入验证码")
+		return
+	}
+	userId, err := service.ParseTwoFALoginToken(req.TwoFAToken)
+	if err != nil {
+		common.ApiErrorMsg(c, "登录已过期，请重新登录")
+		return
+	}
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if user.Status != common.UserStatusEnabled {
+		common.ApiErrorMsg(c, "用户已被封禁")
+		return
+	}
+	if err := model.VerifyTwoFA(user.Id, req.Code); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	completeLogin(user, c)
+}
+
+// GetTwoFAStatus 当前用户的两步验证状态
+func GetTwoFAStatus(c *gin.Context) {
+	twoFA, err := model.GetTwoFAByUserId(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"enabled":  twoFA != nil && twoFA.Enabled,
+		"required": isTwoFARequired(c.GetInt("role")),
+	})
+}
+
+// SetupTwoFA 生成新的 TOTP 密钥，返回密钥与供验证器扫码的 otpauth URI
+func SetupTwoFA(c *gin.Context) {
+	userId := c.GetInt("id")
+	secret, err := model.SetupTwoFA(userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"secret": secret,
+		"uri":    common.TOTPProvisioningURI(secret, common.SystemName, c.GetString("username")),
+	})
+}
+
+// EnableTwoFA 使用验证器中的验证码确认并启用两步验证，返回一次性恢复码
+func EnableTwoFA(c *gin.Context) {
+	var req TwoFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		common.ApiErrorMsg(c, "请输入验证码")
+		return
+	}
+	codes, err := model.EnableTwoFA(c.GetInt("id"), req.Code)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFA 校验验证码后关闭两步验证，强制启用的管理员不允许关闭
+func DisableTwoFA(c *gin.Context) {
+	var req TwoFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		common.ApiErrorMsg(c, "请输入验证码")
+		return
+	}
+	if isTwoFARequired(c.GetInt("role")) {
+		common.ApiErrorMsg(c, "管理员账户必须启用两步验证")
+		return
+	}
+	userId := c.GetInt("id")
+	if err := model.VerifyTwoFA(userId, req.Code); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DisableTwoFA(userId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// RegenerateTwoFARecoveryCodes 校验验证码后重新生成恢复码
+func RegenerateTwoFARecoveryCodes(c *gin.Context) {
+	var req TwoFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		common.ApiErrorMsg(c, "请输入验证码")
+		return
+	}
+	userId := c.GetInt("id")
+	if err := model.VerifyTwoFA(userId, req.Code); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	codes, err := model.RegenerateTwoFARecoveryCodes(userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// SudoTwoFA 重新校验验证码，通过后当前会话在一段时间内可执行敏感操作
+func SudoTwoFA(c *gin.Context) {
+	var req TwoFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		common.ApiErrorMsg(c, "请输入验证码")
+		return
+	}
+	userId := c.GetInt("id")
+	if err := model.VerifyTwoFA(userId, req.Code); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var expiresAt int64
+	if sessionId := c.GetInt("user_session_id"); sessionId != 0 {
+		var err error
+		expiresAt, err = model.GrantUserSessionSudo(sessionId)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	} else {
+		expiresAt = common.GetTimestamp() + model.UserSudoDurationSeconds
+		session := sessions.Default(c)
+		session.Set(model.UserSudoSessionKey, expiresAt)
+		if err := session.Save(); err != nil {
+			common.ApiErrorMsg(c, "无法保存会话信息，请重试")
+			return
+		}
+	}
+	common.ApiSuccess(c, gin.H{
+		"sudo_expires_at": expiresAt,
+	})
+}
+
+// ResetUserTwoFA 管理员为丢失验证器与恢复码的用户重置两步验证
+func ResetUserTwoFA(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	myRole := c.GetInt("role")
+	if myRole <= user.Role && myRole != common.RoleRootUser {
+		common.ApiErrorMsg(c, "无权重置同权限等级或更高权限等级用户的两步验证")
+		return
+	}
+	if err := model.DisableTwoFA(user.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -8,7 +8,9 @@ import (
 	"one-api/common"
 	"one-api/dto"
 	"one-api/model"
+	"one-api/service"
 	"one-api/setting"
+	"one-api/setting/operation_setting"
 	"strconv"
 	"strings"
 	"sync"
@@ -59,8 +61,28 @@ func Login(c *gin.Context) {
 }
 
 // setup session & cookies and then return user info
+// setupLogin 密码等凭据校验通过后登录。已启用两步验证的用户先返回临时令牌，
+// 由 LoginTwoFA 校验验证码后再完成登录
 func setupLogin(user *model.User, c *gin.Context) {
+	if model.IsTwoFAEnabled(user.Id) {
+		token, expiresIn, err := service.GenerateTwoFALoginToken(user.Id)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		common.ApiSuccess(c, gin.H{
+			"require_2fa":  true,
+			"two_fa_token": token,
+			"expires_in":   expiresIn,
+		})
+		return
+	}
+	completeLogin(user, c)
+}
+
+func completeLogin(user *model.User, c *gin.Context) {
 	session := sessions.Default(c)
+	session.Delete(model.UserSudoSessionKey)
 	session.Set("id", user.Id)
 	session.Set("username", user.Username)
 	session.Set("phone", user.Phone)
@@ -76,35 +98,6 @@ func setupLogin(user *model.User, c *gin.Context) {
 		return
 	}
 
-	// 自动生成access_token
-	if user.AccessToken == nil || *user.AccessToken == "" {
-		randI := common.GetRandomInt(4)
-		key, err := common.GenerateRandomKey(29 + randI)
-		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"message": "生成访问令牌失败",
-				"success": false,
-			})
-			common.SysError("failed to generate key: " + err.Error())
-			return
-		}
-		user.SetAccessToken(key)
-
-		// 检查token是否重复
-		if model.DB.Where("access_token = ?", user.AccessToken).First(user).RowsAffected != 0 {
-			c.JSON(http.StatusOK, gin.H{
-				"message": "请重试，系统生成的 UUID 竟然重复了！",
-				"success": false,
-			})
-			return
-		}
-
-		// 更新用户access_token
-		if err := user.Update(false); err != nil {
-			common.ApiError(c, err)
-			return
-		}
-	}
 	cleanUser := model.User{
 		Id:          user.Id,
 		Username:    user.Username,
@@ -115,17 +108,35 @@ func setupLogin(user *model.User, c *gin.Context) {
 		School:      user.School,
 		College:     user.College,
 		Phone:       user.Phone,
-		AccessToken: user.AccessToken, // 包含access_token
-		IsFirstUse:  user.IsFirstUse,  // 包含首次使用标识
+		IsFirstUse:  user.IsFirstUse, // 包含首次使用标识
+	}
+	// 为本次登录的设备签发短期访问令牌与刷新令牌
+	data, err := issueUserSession(c, &cleanUser)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "生成访问令牌失败",
+			"success": false,
+		})
+		common.SysError("failed to issue user session: " + err.Error())
+		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"message": "",
 		"success": true,
-		"data":    cleanUser,
+		"data":    data,
 	})
 }
 
 func Logout(c *gin.Context) {
+	// 携带 JWT 访问令牌时同时吊销对应的设备会话
+	accessToken := strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+	if service.IsJWTAccessToken(accessToken) {
+		if claims, err := service.ParseAccessToken(accessToken); err == nil {
+			if err := model.RevokeUserSession(claims.UserId(), claims.SessionId); err != nil {
+				common.SysError("failed to revoke user session: " + err.Error())
+			}
+		}
+	}
 	session := sessions.Default(c)
 	session.Clear()
 	err := session.Save()
@@ -142,6 +153,15 @@ func Logout(c *gin.Context) {
 	})
 }
 
+// generatePhoneUsername 以手机号后 4 位生成用户名，重名时追加随机后缀
+func generatePhoneUsername(phone string) string {
+	username := "user_" + common.PhoneSuffix(phone, 4)
+	for i := 0; i < 5 && model.IsUsernameAlreadyTaken(username); i++ {
+		username = "user_" + common.PhoneSuffix(phone, 4) + strings.ToLower(common.GetRandomString(3))
+	}
+	return username
+}
+
 func Register(c *gin.Context) {
 	if !common.RegisterEnabled {
 		c.JSON(http.StatusOK, gin.H{
@@ -166,6 +186,17 @@ func Register(c *gin.Context) {
 		})
 		return
 	}
+	if user.Phone != "" {
+		phone, err := common.NormalizePhone(user.Phone)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		user.Phone = phone
+	}
 	if common.EmailVerificationEnabled {
 		if user.Email == "" || user.VerificationCode == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -218,7 +249,7 @@ func Register(c *gin.Context) {
 	inviterId, _ := model.GetUserIdByAffCode(affCode)
 
 	// 生成用户名（基于手机号）
-	username := "user_" + user.Phone[len(user.Phone)-4:] // 使用手机号后4位作为用户名后缀
+	username := generatePhoneUsername(user.Phone)
 
 	cleanUser := model.User{
 		Username:    username,
@@ -234,7 +265,7 @@ func Register(c *gin.Context) {
 	// 清理验证码字段，不保存到数据库
 	cleanUser.VerificationCode = ""
 	cleanUser.PhoneVerificationCode = ""
-	if err := cleanUser.Insert(inviterId); err != nil {
+	if err := cleanUser.InsertWithInvite(inviterId, getInviteSource(c)); err != nil {
 		common.ApiError(c, err)
 		return
 	}
@@ -348,43 +379,33 @@ func GetUser(c *gin.Context) {
 	return
 }
 
+// GenerateAccessToken 重新生成系统访问令牌。令牌绑定独立的设备会话，旧令牌随之吊销，
+// 可在设备列表中单独吊销，并在会话到期后失效
 func GenerateAccessToken(c *gin.Context) {
 	id := c.GetInt("id")
-	user, err := model.GetUserById(id, true)
-	if err != nil {
+	if err := model.RevokeUserSessionsByDeviceName(id, model.SystemAccessTokenDeviceName); err != nil {
 		common.ApiError(c, err)
 		return
 	}
-	// get rand int 28-32
-	randI := common.GetRandomInt(4)
-	key, err := common.GenerateRandomKey(29 + randI)
+	session, _, err := model.CreateUserSession(id, model.SystemAccessTokenDeviceName, truncateRunes(c.Request.UserAgent(), 512), c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": "生成失败",
-		})
-		common.SysError("failed to generate key: " + err.Error())
+		common.ApiError(c, err)
 		return
 	}
-	user.SetAccessToken(key)
-
-	if model.DB.Where("access_token = ?", user.AccessToken).First(user).RowsAffected != 0 {
+	token, _, err := service.GenerateSystemAccessToken(id, session.Id, session.ExpiresAt)
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
-			"message": "请重试，系统生成的 UUID 竟然重复了！",
+			"message": "生成失败",
 		})
-		return
-	}
-
-	if err := user.Update(false); err != nil {
-		common.ApiError(c, err)
+		common.SysError("failed to generate access token: " + err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
-		"data":    user.AccessToken,
+		"data":    token,
 	})
 	return
 }
@@ -506,6 +527,17 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	if updatedUser.Phone != "" {
+		phone, err := common.NormalizePhone(updatedUser.Phone)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		updatedUser.Phone = phone
+	}
 	originUser, err := model.GetUserById(updatedUser.Id, false)
 	if err != nil {
 		common.ApiError(c, err)
@@ -526,7 +558,8 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
-	if err := updatedUser.Edit(false); err != nil {
+	audit := newAuditLog(c, "user.update", model.AuditTargetUser, originUser.Id, originUser.EditUpdates(), updatedUser.EditUpdates())
+	if err := updatedUser.EditWithAudit(audit); err != nil {
 		common.ApiError(c, err)
 		return
 	}
@@ -575,14 +608,22 @@ func UpdateSelf(c *gin.Context) {
 	}
 	if user.Phone != "" {
 		// 如果提供了手机号，需要验证格式
-		if len(user.Phone) != 11 {
+		phone, err := common.NormalizePhone(user.Phone)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		if phone != currentUser.Phone && model.IsPhoneAlreadyTaken(phone) {
 			c.JSON(http.StatusOK, gin.H{
 				"success": false,
-				"message": "手机号必须是11位数字",
+				"message": "手机号已被占用",
 			})
 			return
 		}
-		updates["phone"] = user.Phone
+		updates["phone"] = phone
 	}
 
 	// 如果没有提供任何要更新的字段
@@ -653,7 +694,18 @@ func DeleteSelf(c *gin.Context) {
 		return
 	}
 
-	err := model.DeleteUserById(id)
+	member, err := model.GetUserOrganizationMember(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if member != nil && member.Role == model.OrganizationRoleOwner {
+		common.ApiErrorMsg(c, "请先解散所拥有的组织")
+		return
+	}
+
+	// 注销在冷静期结束后执行，期间可撤销
+	deletion, err := model.RequestAccountDeletion(id, operation_setting.GetAccountDeletionSetting().GracePeriodDays)
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -661,6 +713,7 @@ func DeleteSelf(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
+		"data":    deletion,
 	})
 	return
 }
@@ -695,9 +748,21 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 	// Even for admin users, we cannot fully trust them!
+	if user.Phone != "" {
+		phone, err := common.NormalizePhone(user.Phone)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		user.Phone = phone
+	}
 	cleanUser := model.User{
 		Username:    user.Username,
 		DisplayName: user.DisplayName,
+		Phone:       user.Phone,
 	}
 	if err := cleanUser.Insert(0); err != nil {
 		common.ApiError(c, err)
@@ -748,6 +813,7 @@ func ManageUser(c *gin.Context) {
 		})
 		return
 	}
+	originUser := user
 	switch req.Action {
 	case "disable":
 		user.Status = common.UserStatusDisabled
@@ -809,10 +875,27 @@ func ManageUser(c *gin.Context) {
 		user.Role = common.RoleCommonUser
 	}
 
-	if err := user.Update(false); err != nil {
+	var after interface{} = user
+	switch req.Action {
+	case "delete":
+		after = nil
+	case "demote":
+		demoted := user
+		demoted.AdminRole = ""
+		after = demoted
+	}
+	audit := newAuditLog(c, "user."+req.Action, model.AuditTargetUser, user.Id, originUser, after)
+	if err := user.UpdateWithAudit(audit); err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	// 降级为普通用户时清除管理员角色
+	if req.Action == "demote" && user.AdminRole != "" {
+		if err := model.SetUserAdminRole(&user, ""); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
 	clearUser := model.User{
 		Role:   user.Role,
 		Status: user.Status,
@@ -1014,6 +1097,15 @@ func VerifyResetCode(c *gin.Context) {
 		})
 		return
 	}
+	phone, err := common.NormalizePhone(req.Phone)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	req.Phone = phone
 	if !common.VerifyCodeWithKey(req.Phone, req.Token, common.PasswordResetPurpose) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -1056,6 +1148,15 @@ func ResetPasswordWithNewPassword(c *gin.Context) {
 		})
 		return
 	}
+	phone, err := common.NormalizePhone(req.Phone)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	req.Phone = phone
 	if !common.VerifyCodeWithKey(req.Phone, req.Token, common.PasswordResetPurpose) {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginUser 登录与刷新接口返回的用户信息，access_token 为短期 JWT，过期后使用 refresh_token 换取新令牌
+type loginUser struct {
+	model.User
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	SessionId    int    `json:"session_id"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type userSessionItem struct {
+	*model.UserSession
+	Current bool `json:"current"`
+}
+
+// getDeviceName 设备名称优先取客户端传入的 X-Device-Name，否则使用 User-Agent
+func getDeviceName(c *gin.Context) string {
+	deviceName := c.GetHeader("X-Device-Name")
+	if deviceName == "" {
+		deviceName = c.Request.UserAgent()
+	}
+	return truncateRunes(deviceName, 128)
+}
+
+func truncateRunes(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
+
+// issueUserSession 为登录用户创建设备会话并签发访问令牌与刷新令牌
+func issueUserSession(c *gin.Context, user *model.User) (*loginUser, error) {
+	session, refreshToken, err := model.CreateUserSession(user.Id, getDeviceName(c), truncateRunes(c.Request.UserAgent(), 512), c.ClientIP())
+	if err != nil {
+		return nil, err
+	}
+	accessToken, expiresIn, err := service.GenerateAccessToken(user.Id, session.Id)
+	if err != nil {
+		return nil, err
+	}
+	result := &loginUser{
+		User:         *user,
+		RefreshToken: refreshToken,
+		ExpiresIn:    expiresIn,
+		SessionId:    session.Id,
+	}
+	result.SetAccessToken(accessToken)
+	return result, nil
+}
+
+// RefreshAccessToken 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换
+func RefreshAccessToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	session, refreshToken, err := model.RotateUserSession(req.RefreshToken, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, model.ErrUserSessionInvalid) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	user, err := model.GetUserById(session.UserId, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if user.Status != common.UserStatusEnabled {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "用户已被封禁",
+		})
+		return
+	}
+	accessToken, expiresIn, err := service.GenerateAccessToken(user.Id, session.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    expiresIn,
+		"session_id":    session.Id,
+	})
+}
+
+// GetSelfSessions 列出当前用户已登录的设备
+func GetSelfSessions(c *gin.Context) {
+	sessions, err := model.GetActiveUserSessions(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	currentSessionId := c.GetInt("user_session_id")
+	items := make([]userSessionItem, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, userSessionItem{
+			UserSession: session,
+			Current:     session.Id == currentSessionId,
+		})
+	}
+	common.ApiSuccess(c, items)
+}
+
+// RevokeSelfSession 吊销指定设备，该设备的访问令牌与刷新令牌立即失效
+func RevokeSelfSession(c *gin.Context) {
+	sessionId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if err := model.RevokeUserSession(c.GetInt("id"), sessionId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// RevokeOtherSelfSessions 吊销除当前设备外的全部设备
+func RevokeOtherSelfSessions(c *gin.Context) {
+	if err := model.RevokeUserSessions(c.GetInt("id"), c.GetInt("user_session_id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
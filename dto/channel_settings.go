@@ -4,4 +4,22 @@ type ChannelSettings struct {
 	ForceFormat       bool   `json:"force_format,omitempty"`
 	ThinkingToContent bool   `json:"thinking_to_content,omitempty"`
 	Proxy             string `json:"proxy"`
+	// AzureDeployments Azure 渠道中模型到部署的映射，未配置的模型按模型名推导部署名
+	AzureDeployments map[string]AzureDeployment `json:"azure_deployments,omitempty"`
+	// RealtimePipeline 配置后 /v1/realtime 会话按 语音识别 → 对话 → 语音合成 处理，无原生 realtime 接口的渠道默认启用
+	RealtimePipeline *RealtimePipelineSetting `json:"realtime_pipeline,omitempty"`
+}
+
+type AzureDeployment struct {
+	Deployment string `json:"deployment"`
+	// ApiVersion 为 v1 或 preview 时使用 /openai/v1 统一路径
+	ApiVersion string `json:"api_version,omitempty"`
+}
+
+type RealtimePipelineSetting struct {
+	TranscriptionModel string `json:"transcription_model,omitempty"`
+	// ChatModel 为空时使用会话请求的模型
+	ChatModel   string `json:"chat_model,omitempty"`
+	SpeechModel string `json:"speech_model,omitempty"`
+	Voice       string `json:"voice,omitempty"`
 }
@@ -615,7 +615,7 @@ type OpenAIResponsesRequest struct {
 	PreviousResponseID string           `json:"previous_response_id,omitempty"`
 	Reasoning          *Reasoning       `json:"reasoning,omitempty"`
 	ServiceTier        string           `json:"service_tier,omitempty"`
-	Store              bool             `json:"store,omitempty"`
+	Store              *bool            `json:"store,omitempty"`
 	Stream             bool             `json:"stream,omitempty"`
 	Temperature        float64          `json:"temperature,omitempty"`
 	Text               json.RawMessage  `json:"text,omitempty"`
@@ -628,6 +628,11 @@ type OpenAIResponsesRequest struct {
 	Prompt             json.RawMessage  `json:"prompt,omitempty"`
 }
 
+// ShouldStore 未指定 store 时与 OpenAI 保持一致，默认保存
+func (r *OpenAIResponsesRequest) ShouldStore() bool {
+	return r.Store == nil || *r.Store
+}
+
 type Reasoning struct {
 	Effort  string `json:"effort,omitempty"`
 	Summary string `json:"summary,omitempty"`
@@ -7,9 +7,18 @@ type RerankRequest struct {
 	TopN            int    `json:"top_n,omitempty"`
 	ReturnDocuments *bool  `json:"return_documents,omitempty"`
 	MaxChunkPerDoc  int    `json:"max_chunk_per_doc,omitempty"`
+	MaxChunksPerDoc int    `json:"max_chunks_per_doc,omitempty"`
 	OverLapTokens   int    `json:"overlap_tokens,omitempty"`
 }
 
+// GetMaxChunksPerDoc 兼容 max_chunk_per_doc 与 max_chunks_per_doc 两种写法
+func (r *RerankRequest) GetMaxChunksPerDoc() int {
+	if r.MaxChunksPerDoc > 0 {
+		return r.MaxChunksPerDoc
+	}
+	return r.MaxChunkPerDoc
+}
+
 func (r *RerankRequest) GetReturnDocuments() bool {
 	if r.ReturnDocuments == nil {
 		return false
@@ -39,6 +39,13 @@ func main() {
 		return
 	}
 
+	if *common.EncryptSecrets {
+		if err := model.EncryptStoredSecrets(); err != nil {
+			common.FatalLog("failed to encrypt secrets: " + err.Error())
+		}
+		return
+	}
+
 	common.SysLog("New API " + common.Version + " started")
 	if os.Getenv("GIN_MODE") != "debug" {
 		gin.SetMode(gin.ReleaseMode)
@@ -83,9 +90,28 @@ func main() {
 	// 热更新配置
 	go model.SyncOptions(common.SyncFrequency)
 
+	// 管理员角色缓存
+	model.InitAdminRoleCache()
+	go model.SyncAdminRoleCache(common.SyncFrequency)
+
 	// 数据看板
 	go model.UpdateQuotaData()
 
+	// 日志归档
+	if common.IsMasterNode {
+		go service.StartLogArchiveTask()
+	}
+
+	// 执行到期的账户注销申请
+	if common.IsMasterNode {
+		go service.StartAccountDeletionTask()
+	}
+
+	// 发放满足归属条件的邀请奖励
+	if common.IsMasterNode {
+		go service.StartInviteRewardTask()
+	}
+
 	if os.Getenv("CHANNEL_UPDATE_FREQUENCY") != "" {
 		frequency, err := strconv.Atoi(os.Getenv("CHANNEL_UPDATE_FREQUENCY"))
 		if err != nil {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/service"
 	"strconv"
 	"strings"
 
@@ -23,6 +24,24 @@ func validUserInfo(username string, role int) bool {
 	return true
 }
 
+// authenticateAccessToken 校验登录或系统访问令牌签发的 JWT，返回用户及令牌所属的设备会话 ID。
+// 旧版永久 access token 无法吊销，不再接受
+func authenticateAccessToken(accessToken string) (*model.User, int) {
+	accessToken = strings.TrimPrefix(accessToken, "Bearer ")
+	if !service.IsJWTAccessToken(accessToken) {
+		return nil, 0
+	}
+	claims, err := service.ParseAccessToken(accessToken)
+	if err != nil || model.IsUserSessionRevoked(claims.SessionId) {
+		return nil, 0
+	}
+	user, err := model.GetUserById(claims.UserId(), false)
+	if err != nil {
+		return nil, 0
+	}
+	return user, claims.SessionId
+}
+
 // WebAuth 专门用于Web界面的session认证
 func WebAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
@@ -82,7 +101,7 @@ func APIAuth() func(c *gin.Context) {
 			return
 		}
 
-		user := model.ValidateAccessToken(accessToken)
+		user, sessionId := authenticateAccessToken(accessToken)
 		if user == nil || user.Username == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
@@ -140,17 +159,48 @@ func APIAuth() func(c *gin.Context) {
 			return
 		}
 
+		if !checkAdminTwoFA(c, user.Id, user.Role) {
+			return
+		}
+
 		c.Set("username", user.Username)
 		c.Set("role", user.Role)
 		c.Set("id", user.Id)
 		c.Set("status", user.Status)
 		c.Set("group", user.Group)
 		c.Set("use_access_token", true)
+		c.Set("user_session_id", sessionId)
 
 		c.Next()
 	}
 }
 
+// TwoFAEnrolment 标记启用两步验证所需的接口，须在用户认证之前使用。
+// 强制管理员启用两步验证时，尚未启用的管理员只能访问带此标记的接口
+func TwoFAEnrolment() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.Set("two_fa_enrolment", true)
+		c.Next()
+	}
+}
+
+// checkAdminTwoFA 强制启用两步验证时拒绝未启用的管理员访问两步验证设置以外的接口
+func checkAdminTwoFA(c *gin.Context, userId int, role int) bool {
+	if role < common.RoleAdminUser || !common.AdminTwoFAEnforcementEnabled || c.GetBool("two_fa_enrolment") {
+		return true
+	}
+	if model.IsTwoFAEnabled(userId) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"success":           false,
+		"message":           "请先启用两步验证",
+		"require_2fa_setup": true,
+	})
+	c.Abort()
+	return false
+}
+
 // 保留原有的authHelper用于向后兼容，但标记为deprecated
 // Deprecated: 使用 WebAuth() 或 APIAuth() 替代
 func authHelper(c *gin.Context, minRole int) {
@@ -160,6 +210,7 @@ func authHelper(c *gin.Context, minRole int) {
 	id := session.Get("id")
 	status := session.Get("status")
 	useAccessToken := false
+	sessionId := 0
 
 	fmt.Println(username, role, status)
 	if username == nil {
@@ -173,7 +224,8 @@ func authHelper(c *gin.Context, minRole int) {
 			c.Abort()
 			return
 		}
-		user := model.ValidateAccessToken(accessToken)
+		var user *model.User
+		user, sessionId = authenticateAccessToken(accessToken)
 		if user != nil && user.Username != "" {
 			if !validUserInfo(user.Username, user.Role) {
 				c.JSON(http.StatusUnauthorized, gin.H{
@@ -250,11 +302,28 @@ func authHelper(c *gin.Context, minRole int) {
 		c.Abort()
 		return
 	}
+	if !checkAdminTwoFA(c, id.(int), role.(int)) {
+		return
+	}
+	// 管理接口按缓存中的管理员角色校验权限
+	if minRole >= common.RoleAdminUser {
+		userCache, err := model.GetUserCache(id.(int))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		c.Set("admin_role", userCache.AdminRole)
+	}
 	c.Set("username", username)
 	c.Set("role", role)
 	c.Set("id", id)
 	c.Set("group", session.Get("group"))
 	c.Set("use_access_token", useAccessToken)
+	c.Set("user_session_id", sessionId)
 
 	//userCache, err := model.GetUserCache(id.(int))
 	//if err != nil {
@@ -270,6 +339,50 @@ func authHelper(c *gin.Context, minRole int) {
 	c.Next()
 }
 
+// SudoAuth 敏感操作需要近期通过二次验证，须在用户认证之后使用。
+// 可先调用 /api/user/2fa/sudo，或在请求头 X-2FA-Code 中直接携带验证码
+func SudoAuth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userId := c.GetInt("id")
+		// 未启用两步验证的用户（仅在未强制启用时可能出现）无需二次验证
+		if !model.IsTwoFAEnabled(userId) {
+			c.Next()
+			return
+		}
+		if code := c.GetHeader("X-2FA-Code"); code != "" {
+			if err := model.VerifyTwoFA(userId, code); err != nil {
+				c.JSON(http.StatusForbidden, gin.H{
+					"success":      false,
+					"message":      err.Error(),
+					"require_sudo": true,
+				})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if sessionId := c.GetInt("user_session_id"); sessionId != 0 {
+			if model.IsUserSessionSudo(sessionId) {
+				c.Next()
+				return
+			}
+		} else if !c.GetBool("use_access_token") {
+			expiresAt, _ := sessions.Default(c).Get(model.UserSudoSessionKey).(int64)
+			if expiresAt > common.GetTimestamp() {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"success":      false,
+			"message":      "该操作需要重新进行两步验证",
+			"require_sudo": true,
+		})
+		c.Abort()
+	}
+}
+
 func TryUserAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		session := sessions.Default(c)
@@ -299,6 +412,21 @@ func RootAuth() func(c *gin.Context) {
 	}
 }
 
+// RequirePermission 要求当前管理员拥有任一指定权限，须在 AdminAuth 之后使用
+func RequirePermission(permissions ...string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !model.UserHasAnyPermission(c.GetInt("role"), c.GetString("admin_role"), permissions...) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "无权进行此操作，权限不足",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 func WssAuth(c *gin.Context) {
 
 }
@@ -406,6 +534,7 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	c.Set("allow_ips", token.GetIpLimitsMap())
 	c.Set("token_group", token.Group)
+	c.Set("token_organization_id", token.OrganizationId)
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			c.Set("specific_channel_id", parts[1])
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"one-api/setting"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsAuth 校验 root 配置的 MetricsToken，未配置时指标接口不可用
+func MetricsAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := setting.MetricsToken
+		if token == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
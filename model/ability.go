@@ -143,6 +143,11 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 }
 
 func (channel *Channel) AddAbilities() error {
+	return channel.addAbilities(DB)
+}
+
+// addAbilities 在 tx 中为渠道创建能力记录，与渠道写入同一事务时使用
+func (channel *Channel) addAbilities(tx *gorm.DB) error {
 	models_ := strings.Split(channel.Models, ",")
 	groups_ := strings.Split(channel.Group, ",")
 	abilitySet := make(map[string]struct{})
@@ -170,7 +175,7 @@ func (channel *Channel) AddAbilities() error {
 		return nil
 	}
 	for _, chunk := range lo.Chunk(abilities, 50) {
-		err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&chunk).Error
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&chunk).Error
 		if err != nil {
 			return err
 		}
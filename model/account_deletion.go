@@ -0,0 +1,169 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AccountDeletionStatusPending   = 1
+	AccountDeletionStatusCancelled = 2
+	AccountDeletionStatusCompleted = 3
+)
+
+// AccountDeletion 用户自助注销申请，冷静期结束后由后台任务执行
+type AccountDeletion struct {
+	Id          int   `json:"id"`
+	UserId      int   `json:"user_id" gorm:"index"`
+	Status      int   `json:"status" gorm:"type:int;default:1;index"`
+	RequestedAt int64 `json:"requested_at" gorm:"bigint"`
+	ScheduledAt int64 `json:"scheduled_at" gorm:"bigint;index"`
+	CompletedAt int64 `json:"completed_at" gorm:"bigint"`
+}
+
+// GetPendingAccountDeletion 获取用户待执行的注销申请，不存在时返回 nil
+func GetPendingAccountDeletion(userId int) (*AccountDeletion, error) {
+	var deletion AccountDeletion
+	err := DB.Where("user_id = ? AND status = ?", userId, AccountDeletionStatusPending).First(&deletion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// RequestAccountDeletion 提交注销申请，已有待执行的申请时直接返回该申请
+func RequestAccountDeletion(userId int, gracePeriodDays int) (*AccountDeletion, error) {
+	deletion, err := GetPendingAccountDeletion(userId)
+	if err != nil || deletion != nil {
+		return deletion, err
+	}
+	now := common.GetTimestamp()
+	deletion = &AccountDeletion{
+		UserId:      userId,
+		Status:      AccountDeletionStatusPending,
+		RequestedAt: now,
+		ScheduledAt: now + int64(gracePeriodDays)*24*3600,
+	}
+	return deletion, DB.Create(deletion).Error
+}
+
+func CancelAccountDeletion(userId int) error {
+	result := DB.Model(&AccountDeletion{}).
+		Where("user_id = ? AND status = ?", userId, AccountDeletionStatusPending).
+		Update("status", AccountDeletionStatusCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("没有待执行的注销申请")
+	}
+	return nil
+}
+
+func GetDueAccountDeletions(now int64, limit int) (deletions []*AccountDeletion, err error) {
+	err = DB.Where("status = ? AND scheduled_at <= ?", AccountDeletionStatusPending, now).
+		Order("id").Limit(limit).Find(&deletions).Error
+	return deletions, err
+}
+
+// Execute 执行注销：彻底删除对话内容、令牌与登录凭据，匿名化使用日志，
+// 清除用户资料中的个人信息后软删除用户。已归档到外部存储的日志不在此处理
+func (deletion *AccountDeletion) Execute() error {
+	userId := deletion.UserId
+	if err := RevokeUserSessions(userId, 0); err != nil {
+		common.SysError("failed to revoke user sessions: " + err.Error())
+	}
+	// 日志可能位于独立的日志库，无法与下面的修改共用事务，先行匿名化，失败时整个注销下次重试
+	err := LOG_DB.Model(&Log{}).Where("user_id = ?", userId).Updates(map[string]interface{}{
+		"username":   "",
+		"token_name": "",
+		"ip":         "",
+	}).Error
+	if err != nil {
+		return err
+	}
+	var tokenKeys []string
+	if err = DB.Unscoped().Model(&Token{}).Where("user_id = ?", userId).Pluck("key", &tokenKeys).Error; err != nil {
+		return err
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		topicIds := tx.Model(&Topic{}).Select("id").Where("user_id = ?", userId)
+		if err := tx.Where("topic_id IN (?)", topicIds).Delete(&Message{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&Topic{}).Error; err != nil {
+			return err
+		}
+		sessionIds := tx.Model(&ChatSession{}).Select("session_id").Where("user_id = ?", userId)
+		if err := tx.Where("session_id IN (?)", sessionIds).Delete(&ChatMessage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&ChatSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&StoredResponse{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&UserSubscription{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userId).Delete(&Token{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&TwoFA{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userId).Delete(&UserSession{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND role <> ?", userId, OrganizationRoleOwner).Delete(&OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&QuotaData{}).Where("user_id = ?", userId).Update("username", "").Error; err != nil {
+			return err
+		}
+		err := tx.Unscoped().Model(&User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+			"username":        fmt.Sprintf("deleted_%d", userId),
+			"display_name":    "",
+			"email":           "",
+			"phone":           "",
+			"github_id":       "",
+			"oidc_id":         "",
+			"wechat_id":       "",
+			"telegram_id":     "",
+			"linux_do_id":     "",
+			"access_token":    nil,
+			"setting":         "",
+			"remark":          "",
+			"stripe_customer": "",
+			"school":          "",
+			"college":         "",
+			"status":          common.UserStatusDisabled,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&User{}, "id = ?", userId).Error; err != nil {
+			return err
+		}
+		return tx.Model(deletion).Updates(map[string]interface{}{
+			"status":       AccountDeletionStatusCompleted,
+			"completed_at": common.GetTimestamp(),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	if common.RedisEnabled {
+		for _, key := range tokenKeys {
+			_ = cacheDeleteToken(key)
+		}
+	}
+	return invalidateUserCache(userId)
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"time"
+)
+
+// UserDataExportTopic 导出的话题及其全部消息
+type UserDataExportTopic struct {
+	Id        int       `json:"id"`
+	TopicName string    `json:"topic_name"`
+	Model     string    `json:"model"`
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Messages  []Message `json:"messages"`
+}
+
+// UserDataExportChatSession 导出的会话及其全部消息
+type UserDataExportChatSession struct {
+	ChatSession
+	Messages []ChatMessage `json:"messages"`
+}
+
+type UserDataExportSubscription struct {
+	Id               int       `json:"id"`
+	SubscriptionId   int       `json:"subscription_id"`
+	TopicName        string    `json:"topic_name"`
+	TopicDescription string    `json:"topic_description"`
+	Status           int       `json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// maskTokenKey 仅保留令牌首尾各 4 位
+func maskTokenKey(key string) string {
+	if len(key) <= 8 {
+		return "********"
+	}
+	return key[:4] + "********" + key[len(key)-4:]
+}
+
+// GetUserTokensForExport 获取用户全部令牌，令牌密钥已脱敏
+func GetUserTokensForExport(userId int) ([]*Token, error) {
+	var tokens []*Token
+	if err := DB.Where("user_id = ?", userId).Order("id").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		token.Key = maskTokenKey(token.Key)
+	}
+	return tokens, nil
+}
+
+func GetUserTopicsForExport(userId int) ([]*UserDataExportTopic, error) {
+	var topics []Topic
+	if err := DB.Where("user_id = ?", userId).Order("id").Find(&topics).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*UserDataExportTopic, 0, len(topics))
+	for _, topic := range topics {
+		var messages []Message
+		if err := DB.Where("topic_id = ?", topic.ID).Order("id").Find(&messages).Error; err != nil {
+			return nil, err
+		}
+		result = append(result, &UserDataExportTopic{
+			Id:        topic.ID,
+			TopicName: topic.TopicName,
+			Model:     topic.Model,
+			Status:    topic.Status,
+			CreatedAt: topic.CreatedAt,
+			UpdatedAt: topic.UpdatedAt,
+			Messages:  messages,
+		})
+	}
+	return result, nil
+}
+
+func GetUserChatSessionsForExport(userId int) ([]*UserDataExportChatSession, error) {
+	var sessions []ChatSession
+	if err := DB.Where("user_id = ?", userId).Order("id").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	result := make([]*UserDataExportChatSession, 0, len(sessions))
+	for _, session := range sessions {
+		var messages []ChatMessage
+		if err := DB.Where("session_id = ?", session.SessionId).Order("id").Find(&messages).Error; err != nil {
+			return nil, err
+		}
+		result = append(result, &UserDataExportChatSession{
+			ChatSession: session,
+			Messages:    messages,
+		})
+	}
+	return result, nil
+}
+
+func GetUserSubscriptionsForExport(userId int) ([]*UserDataExportSubscription, error) {
+	var userSubscriptions []UserSubscription
+	err := DB.Preload("Subscription").Where("user_id = ?", userId).Order("id").Find(&userSubscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*UserDataExportSubscription, 0, len(userSubscriptions))
+	for _, us := range userSubscriptions {
+		result = append(result, &UserDataExportSubscription{
+			Id:               us.ID,
+			SubscriptionId:   us.SubscriptionID,
+			TopicName:        us.Subscription.TopicName,
+			TopicDescription: us.Subscription.TopicDescription,
+			Status:           us.Status,
+			CreatedAt:        us.CreatedAt,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,262 @@
+package model
+
+import (
+	"errors"
+	"one-api/common"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 内置管理员角色名称。未设置角色的管理员对应 admin，拥有全部管理权限
+const (
+	AdminRoleAdmin    = "admin"
+	AdminRoleOperator = "operator"
+	AdminRoleSupport  = "support"
+	AdminRoleFinance  = "finance"
+)
+
+// AdminRole 管理员角色，Permissions 以逗号分隔。内置角色定义在代码中，自定义角色保存在数据库
+type AdminRole struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(32);uniqueIndex"`
+	Description string `json:"description" gorm:"type:varchar(255)"`
+	Permissions string `json:"permissions" gorm:"type:text"`
+	BuiltIn     bool   `json:"built_in" gorm:"-"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt   int64  `json:"updated_at" gorm:"bigint"`
+}
+
+var builtinAdminRoles = map[string]*AdminRole{
+	AdminRoleAdmin: {
+		Name:        AdminRoleAdmin,
+		Description: "管理员，拥有全部管理权限",
+		Permissions: strings.Join(common.AllPermissions, ","),
+	},
+	AdminRoleOperator: {
+		Name:        AdminRoleOperator,
+		Description: "运营，仅可管理渠道",
+		Permissions: strings.Join([]string{common.PermissionChannelManage, common.PermissionChannelKey}, ","),
+	},
+	AdminRoleSupport: {
+		Name:        AdminRoleSupport,
+		Description: "客服，可查看用户与日志，不可查看渠道密钥",
+		Permissions: strings.Join([]string{common.PermissionUserRead, common.PermissionLogRead}, ","),
+	},
+	AdminRoleFinance: {
+		Name:        AdminRoleFinance,
+		Description: "财务，可管理兑换码与充值订单",
+		Permissions: strings.Join([]string{common.PermissionRedemptionManage, common.PermissionTopUpManage}, ","),
+	},
+}
+
+// 自定义角色的内存缓存，权限校验时不再查询数据库。本节点修改角色后立即刷新，其他节点定时同步
+var (
+	adminRoleCache     = map[string]*AdminRole{}
+	adminRoleCacheLock sync.RWMutex
+)
+
+func InitAdminRoleCache() {
+	var roles []*AdminRole
+	if err := DB.Find(&roles).Error; err != nil {
+		common.SysError("failed to load admin roles: " + err.Error())
+		return
+	}
+	cache := make(map[string]*AdminRole, len(roles))
+	for _, role := range roles {
+		cache[role.Name] = role
+	}
+	adminRoleCacheLock.Lock()
+	adminRoleCache = cache
+	adminRoleCacheLock.Unlock()
+}
+
+func SyncAdminRoleCache(frequency int) {
+	for {
+		time.Sleep(time.Duration(frequency) * time.Second)
+		InitAdminRoleCache()
+	}
+}
+
+// getCachedAdminRole 从内置角色与缓存中获取角色，不存在时返回 nil
+func getCachedAdminRole(name string) *AdminRole {
+	if role, ok := builtinAdminRoles[name]; ok {
+		return role
+	}
+	adminRoleCacheLock.RLock()
+	defer adminRoleCacheLock.RUnlock()
+	return adminRoleCache[name]
+}
+
+func (role *AdminRole) GetPermissions() []string {
+	permissions := make([]string, 0)
+	for _, p := range strings.Split(role.Permissions, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+// SetPermissions 校验并设置权限列表，去重后按固定顺序保存
+func (role *AdminRole) SetPermissions(permissions []string) error {
+	set := make(map[string]bool)
+	for _, p := range permissions {
+		if !common.IsValidPermission(p) {
+			return errors.New("无效的权限：" + p)
+		}
+		set[p] = true
+	}
+	result := make([]string, 0, len(set))
+	for _, p := range common.AllPermissions {
+		if set[p] {
+			result = append(result, p)
+		}
+	}
+	role.Permissions = strings.Join(result, ",")
+	return nil
+}
+
+func (role *AdminRole) HasPermission(permission string) bool {
+	for _, p := range role.GetPermissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func IsBuiltinAdminRole(name string) bool {
+	_, ok := builtinAdminRoles[name]
+	return ok
+}
+
+// GetAllAdminRoles 返回内置角色与自定义角色
+func GetAllAdminRoles() ([]*AdminRole, error) {
+	var roles []*AdminRole
+	if err := DB.Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	builtins := make([]*AdminRole, 0, len(builtinAdminRoles))
+	for _, role := range builtinAdminRoles {
+		r := *role
+		r.BuiltIn = true
+		builtins = append(builtins, &r)
+	}
+	sort.Slice(builtins, func(i, j int) bool {
+		return builtins[i].Name < builtins[j].Name
+	})
+	return append(builtins, roles...), nil
+}
+
+// GetAdminRoleByName 获取角色，不存在时返回 nil
+func GetAdminRoleByName(name string) (*AdminRole, error) {
+	if role, ok := builtinAdminRoles[name]; ok {
+		r := *role
+		r.BuiltIn = true
+		return &r, nil
+	}
+	var role AdminRole
+	err := DB.Where("name = ?", name).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func GetAdminRoleById(id int) (*AdminRole, error) {
+	var role AdminRole
+	err := DB.First(&role, "id = ?", id).Error
+	return &role, err
+}
+
+func (role *AdminRole) Insert() error {
+	if IsBuiltinAdminRole(role.Name) {
+		return errors.New("角色名称与内置角色重复")
+	}
+	now := common.GetTimestamp()
+	role.CreatedAt = now
+	role.UpdatedAt = now
+	if err := DB.Create(role).Error; err != nil {
+		return err
+	}
+	InitAdminRoleCache()
+	return nil
+}
+
+// Update 更新角色描述与权限，角色名称创建后不可修改
+func (role *AdminRole) Update() error {
+	role.UpdatedAt = common.GetTimestamp()
+	if err := DB.Model(role).Select("description", "permissions", "updated_at").Updates(role).Error; err != nil {
+		return err
+	}
+	InitAdminRoleCache()
+	return nil
+}
+
+// Delete 删除角色，仍有管理员使用该角色时不允许删除
+func (role *AdminRole) Delete() error {
+	var count int64
+	if err := DB.Model(&User{}).Where("admin_role = ?", role.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("仍有管理员使用该角色，无法删除")
+	}
+	if err := DB.Delete(role).Error; err != nil {
+		return err
+	}
+	InitAdminRoleCache()
+	return nil
+}
+
+// SetUserAdminRole 设置用户的管理员角色，设置角色的普通用户同时提升为管理员
+func SetUserAdminRole(user *User, adminRole string) error {
+	updates := map[string]interface{}{
+		"admin_role": adminRole,
+	}
+	if adminRole != "" && user.Role < common.RoleAdminUser {
+		updates["role"] = common.RoleAdminUser
+	}
+	if err := DB.Model(&User{}).Where("id = ?", user.Id).Updates(updates).Error; err != nil {
+		return err
+	}
+	return invalidateUserCache(user.Id)
+}
+
+// GetUserPermissions 用户拥有的管理权限：超级管理员拥有全部权限，
+// 管理员按所属角色 adminRole 授予（未设置角色时为 admin），普通用户没有管理权限
+func GetUserPermissions(role int, adminRole string) []string {
+	if role >= common.RoleRootUser {
+		return common.AllPermissions
+	}
+	if role < common.RoleAdminUser {
+		return []string{}
+	}
+	r := getCachedAdminRole(common.GetStringIfEmpty(adminRole, AdminRoleAdmin))
+	// 角色已被删除时不授予任何权限
+	if r == nil {
+		return []string{}
+	}
+	return r.GetPermissions()
+}
+
+// UserHasAnyPermission 用户是否拥有任一指定权限
+func UserHasAnyPermission(role int, adminRole string, permissions ...string) bool {
+	granted := GetUserPermissions(role, adminRole)
+	for _, g := range granted {
+		for _, p := range permissions {
+			if g == p {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"one-api/common"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 审计日志的目标类型
+const (
+	AuditTargetChannel      = "channel"
+	AuditTargetOption       = "option"
+	AuditTargetUser         = "user"
+	AuditTargetRedemption   = "redemption"
+	AuditTargetRatio        = "ratio"
+	AuditTargetAdminRole    = "admin_role"
+	AuditTargetOrganization = "organization"
+	AuditTargetInviteReward = "invite_reward"
+	AuditTargetTopUp        = "top_up"
+)
+
+const auditMaskedValue = "******"
+
+// AuditLog 管理操作审计日志，保存在主库中，以便与被审计的修改写入同一事务。
+// Changes 为字段级差异 {"字段": {"before": 旧值, "after": 新值}}，敏感字段已脱敏
+type AuditLog struct {
+	Id         int    `json:"id"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+	ActorId    int    `json:"actor_id" gorm:"index"`
+	ActorName  string `json:"actor_name" gorm:"type:varchar(64)"`
+	Action     string `json:"action" gorm:"type:varchar(64);index"`
+	TargetType string `json:"target_type" gorm:"type:varchar(32);index:idx_audit_target"`
+	TargetId   string `json:"target_id" gorm:"type:varchar(64);index:idx_audit_target"`
+	Changes    string `json:"changes" gorm:"type:text"`
+	Ip         string `json:"ip" gorm:"type:varchar(64)"`
+}
+
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLogFilter 审计日志的筛选条件，零值表示不过滤
+type AuditLogFilter struct {
+	ActorId        int
+	Action         string
+	TargetType     string
+	TargetId       string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+func (filter *AuditLogFilter) apply(tx *gorm.DB) *gorm.DB {
+	if filter.ActorId != 0 {
+		tx = tx.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.Action != "" {
+		tx = tx.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		tx = tx.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		tx = tx.Where("target_id = ?", filter.TargetId)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", filter.EndTimestamp)
+	}
+	return tx
+}
+
+// isSensitiveAuditField 密钥、令牌、密码等字段脱敏，如 key、access_token、GitHubClientSecret
+func isSensitiveAuditField(field string) bool {
+	lower := strings.ToLower(field)
+	if strings.Contains(lower, "secret") || strings.Contains(lower, "password") {
+		return true
+	}
+	return strings.HasSuffix(lower, "key") || strings.HasSuffix(lower, "token")
+}
+
+func maskAuditValue(field string, value interface{}) interface{} {
+	if value == nil || value == "" || !isSensitiveAuditField(field) {
+		return value
+	}
+	return auditMaskedValue
+}
+
+// auditFields 将对象转换为字段映射，map 直接使用，结构体按 JSON 字段名展开
+func auditFields(v interface{}) map[string]interface{} {
+	if v == nil {
+		return map[string]interface{}{}
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return map[string]interface{}{}
+	}
+	return fields
+}
+
+// DiffAuditFields 计算 before 与 after 的字段差异，敏感字段只记录是否变化
+func DiffAuditFields(before interface{}, after interface{}) map[string]AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+	keys := make(map[string]struct{})
+	for k := range beforeFields {
+		keys[k] = struct{}{}
+	}
+	for k := range afterFields {
+		keys[k] = struct{}{}
+	}
+	changes := make(map[string]AuditChange)
+	for k := range keys {
+		b, a := beforeFields[k], afterFields[k]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		changes[k] = AuditChange{
+			Before: maskAuditValue(k, b),
+			After:  maskAuditValue(k, a),
+		}
+	}
+	return changes
+}
+
+// NewAuditLog 创建审计日志，before 为 nil 表示新建，after 为 nil 表示删除
+func NewAuditLog(actorId int, actorName string, ip string, action string, targetType string, targetId string, before interface{}, after interface{}) *AuditLog {
+	changes, _ := json.Marshal(DiffAuditFields(before, after))
+	return &AuditLog{
+		CreatedAt:  common.GetTimestamp(),
+		ActorId:    actorId,
+		ActorName:  actorName,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Changes:    string(changes),
+		Ip:         ip,
+	}
+}
+
+// Insert 写入审计日志，tx 为 nil 时使用 DB
+func (log *AuditLog) Insert(tx *gorm.DB) error {
+	if tx == nil {
+		tx = DB
+	}
+	return tx.Create(log).Error
+}
+
+// RecordAuditLog 在无法与修改共用事务时单独写入审计日志，失败只记录错误
+func RecordAuditLog(log *AuditLog) {
+	if err := log.Insert(nil); err != nil {
+		common.SysError("failed to record audit log: " + err.Error())
+	}
+}
+
+func SearchAuditLogs(filter AuditLogFilter, startIdx int, num int) (logs []*AuditLog, total int64, err error) {
+	tx := filter.apply(DB.Model(&AuditLog{}))
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&logs).Error
+	return logs, total, err
+}
+
+// GetAuditLogActions 已记录过的操作类型，供前端筛选
+func GetAuditLogActions() ([]string, error) {
+	var actions []string
+	err := DB.Model(&AuditLog{}).Distinct("action").Pluck("action", &actions).Error
+	sort.Strings(actions)
+	return actions, err
+}
+
+// IterateAuditLogs 按 id 倒序分批遍历审计日志，用于导出
+func IterateAuditLogs(ctx context.Context, filter AuditLogFilter, batchSize int, fn func(logs []*AuditLog) error) error {
+	lastId := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tx := filter.apply(DB.WithContext(ctx).Model(&AuditLog{}))
+		if lastId != 0 {
+			tx = tx.Where("id < ?", lastId)
+		}
+		var logs []*AuditLog
+		if err := tx.Order("id desc").Limit(batchSize).Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+		lastId = logs[len(logs)-1].Id
+		if err := fn(logs); err != nil {
+			return err
+		}
+	}
+}
@@ -19,7 +19,7 @@ import (
 type Channel struct {
 	Id                 int     `json:"id"`
 	Type               int     `json:"type" gorm:"default:0"`
-	Key                string  `json:"key" gorm:"not null"`
+	Key                string  `json:"key" gorm:"not null;serializer:secret"` // 以信封加密保存，读取时透明解密
 	OpenAIOrganization *string `json:"openai_organization"`
 	TestModel          *string `json:"test_model"`
 	Status             int     `json:"status" gorm:"default:1"`
@@ -284,7 +284,7 @@ func SearchChannels(keyword string, group string, model string, idSort bool) ([]
 	// 构造基础查询
 	baseQuery := DB.Model(&Channel{}).Omit("key")
 
-	// 构造WHERE子句
+	// 构造WHERE子句，密钥加密保存后无法按密钥匹配，因此只按 ID、名称与 base_url 搜索
 	var whereClause string
 	var args []interface{}
 	if group != "" && group != "null" {
@@ -295,11 +295,11 @@ func SearchChannels(keyword string, group string, model string, idSort bool) ([]
 			// sqlite, PostgreSQL
 			groupCondition = `(',' || ` + commonGroupCol + ` || ',') LIKE ?`
 		}
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
+		whereClause = "(id = ? OR name LIKE ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%", "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
 	} else {
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%")
+		whereClause = "(id = ? OR name LIKE ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%", "%"+keyword+"%", "%"+model+"%")
 	}
 
 	// 执行查询
@@ -328,38 +328,50 @@ func GetChannelById(id int, selectAll bool) (*Channel, error) {
 }
 
 func BatchInsertChannels(channels []Channel) error {
-	var err error
-	err = DB.Create(&channels).Error
-	if err != nil {
-		return err
-	}
-	for _, channel_ := range channels {
-		err = channel_.AddAbilities()
-		if err != nil {
+	return BatchInsertChannelsWithAudit(channels, nil)
+}
+
+// BatchInsertChannelsWithAudit 与 BatchInsertChannels 相同，newAudit 不为空时为每个新渠道生成审计日志，
+// 与渠道及其能力在同一事务中写入
+func BatchInsertChannelsWithAudit(channels []Channel, newAudit func(channel *Channel) *AuditLog) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&channels).Error; err != nil {
 			return err
 		}
-	}
-	return nil
+		for i := range channels {
+			if err := channels[i].addAbilities(tx); err != nil {
+				return err
+			}
+			if newAudit != nil {
+				if err := newAudit(&channels[i]).Insert(tx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
 }
 
 func BatchDeleteChannels(ids []int) error {
-	//使用事务 删除channel表和channel_ability表
-	tx := DB.Begin()
-	err := tx.Where("id in (?)", ids).Delete(&Channel{}).Error
-	if err != nil {
-		// 回滚事务
-		tx.Rollback()
-		return err
-	}
-	err = tx.Where("channel_id in (?)", ids).Delete(&Ability{}).Error
-	if err != nil {
-		// 回滚事务
-		tx.Rollback()
-		return err
-	}
-	// 提交事务
-	tx.Commit()
-	return err
+	return BatchDeleteChannelsWithAudit(ids, nil)
+}
+
+// BatchDeleteChannelsWithAudit 在同一事务中删除渠道、渠道能力并写入审计日志
+func BatchDeleteChannelsWithAudit(ids []int, audits []*AuditLog) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id in (?)", ids).Delete(&Channel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("channel_id in (?)", ids).Delete(&Ability{}).Error; err != nil {
+			return err
+		}
+		for _, audit := range audits {
+			if err := audit.Insert(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (channel *Channel) GetPriority() int64 {
@@ -408,6 +420,12 @@ func (channel *Channel) Insert() error {
 }
 
 func (channel *Channel) Update() error {
+	return channel.UpdateWithAudit(nil)
+}
+
+// UpdateWithAudit 与 Update 相同，newAudit 不为空时以更新后重新读取的渠道生成审计日志，
+// 与渠道及其能力在同一事务中写入
+func (channel *Channel) UpdateWithAudit(newAudit func(updated *Channel) *AuditLog) error {
 	// If this is a multi-key channel, recalculate MultiKeySize based on the current key list to avoid inconsistency after editing keys
 	if channel.ChannelInfo.IsMultiKey {
 		var keyStr string
@@ -446,14 +464,21 @@ func (channel *Channel) Update() error {
 			}
 		}
 	}
-	var err error
-	err = DB.Model(channel).Updates(channel).Error
-	if err != nil {
-		return err
-	}
-	DB.Model(channel).First(channel, "id = ?", channel.Id)
-	err = channel.UpdateAbilities(nil)
-	return err
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(channel).Updates(channel).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(channel).First(channel, "id = ?", channel.Id).Error; err != nil {
+			return err
+		}
+		if err := channel.UpdateAbilities(tx); err != nil {
+			return err
+		}
+		if newAudit != nil {
+			return newAudit(channel).Insert(tx)
+		}
+		return nil
+	})
 }
 
 func (channel *Channel) UpdateResponseTime(responseTime int64) {
@@ -477,13 +502,23 @@ func (channel *Channel) UpdateBalance(balance float64) {
 }
 
 func (channel *Channel) Delete() error {
-	var err error
-	err = DB.Delete(channel).Error
-	if err != nil {
-		return err
-	}
-	err = channel.DeleteAbilities()
-	return err
+	return channel.DeleteWithAudit(nil)
+}
+
+// DeleteWithAudit 与 Delete 相同，audit 不为空时与渠道及其能力的删除在同一事务中写入
+func (channel *Channel) DeleteWithAudit(audit *AuditLog) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(channel).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("channel_id = ?", channel.Id).Delete(&Ability{}).Error; err != nil {
+			return err
+		}
+		if audit != nil {
+			return audit.Insert(tx)
+		}
+		return nil
+	})
 }
 
 var channelStatusLock sync.Mutex
@@ -741,7 +776,7 @@ func SearchTags(keyword string, group string, model string, idSort bool) ([]*str
 	// 构造基础查询
 	baseQuery := DB.Model(&Channel{}).Omit("key")
 
-	// 构造WHERE子句
+	// 构造WHERE子句，密钥加密保存后无法按密钥匹配，因此只按 ID、名称与 base_url 搜索
 	var whereClause string
 	var args []interface{}
 	if group != "" && group != "null" {
@@ -752,11 +787,11 @@ func SearchTags(keyword string, group string, model string, idSort bool) ([]*str
 			// sqlite, PostgreSQL
 			groupCondition = `(',' || ` + commonGroupCol + ` || ',') LIKE ?`
 		}
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
+		whereClause = "(id = ? OR name LIKE ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%", "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
 	} else {
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%")
+		whereClause = "(id = ? OR name LIKE ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%", "%"+keyword+"%", "%"+model+"%")
 	}
 
 	subQuery := baseQuery.Where(whereClause, args...).
@@ -907,3 +942,21 @@ func CountChannelsGroupByType() (map[int64]int64, error) {
 	}
 	return counts, nil
 }
+
+// CountChannelsGroupByStatus Return map[status]count for all channels
+func CountChannelsGroupByStatus() (map[int]int64, error) {
+	type result struct {
+		Status int   `gorm:"column:status"`
+		Count  int64 `gorm:"column:count"`
+	}
+	var results []result
+	err := DB.Model(&Channel{}).Select("status, count(*) as count").Group("status").Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int64)
+	for _, r := range results {
+		counts[r.Status] = r.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,274 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	InviteRewardStatusPending  = 1 // 等待被邀请用户充值或达到消耗门槛
+	InviteRewardStatusVested   = 2 // 已发放
+	InviteRewardStatusFlagged  = 3 // 疑似刷邀请，等待管理员审核
+	InviteRewardStatusRejected = 4 // 审核拒绝，不再发放
+)
+
+// 异常标记
+const (
+	InviteRiskSharedIp     = "shared_ip"
+	InviteRiskInviterIp    = "inviter_ip"
+	InviteRiskSharedDevice = "shared_device"
+	InviteRiskBurst        = "burst"
+)
+
+// InviteSource 注册请求的来源，用于检测刷邀请。DeviceId 取自客户端传入的 X-Device-Id，可能为空
+type InviteSource struct {
+	Ip       string
+	DeviceId string
+}
+
+// InviteReward 邀请奖励台账，每个被邀请用户一条记录。
+// 邀请人奖励与被邀请人奖励在归属后一并发放，邀请人奖励计入邀请额度
+type InviteReward struct {
+	Id           int    `json:"id"`
+	InviterId    int    `json:"inviter_id" gorm:"index"`
+	InviteeId    int    `json:"invitee_id" gorm:"uniqueIndex"`
+	InviterQuota int    `json:"inviter_quota"`
+	InviteeQuota int    `json:"invitee_quota"`
+	Status       int    `json:"status" gorm:"type:int;default:1;index"`
+	RiskFlags    string `json:"risk_flags" gorm:"type:varchar(255);default:''"`
+	Ip           string `json:"ip" gorm:"type:varchar(64);index"`
+	DeviceId     string `json:"device_id" gorm:"type:varchar(128);index"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint;index"`
+	VestedAt     int64  `json:"vested_at" gorm:"bigint"`
+	ReviewedBy   int    `json:"reviewed_by"`
+	ReviewedAt   int64  `json:"reviewed_at" gorm:"bigint"`
+}
+
+func (reward *InviteReward) addRiskFlag(flag string) {
+	for _, f := range strings.Split(reward.RiskFlags, ",") {
+		if f == flag {
+			return
+		}
+	}
+	if reward.RiskFlags == "" {
+		reward.RiskFlags = flag
+	} else {
+		reward.RiskFlags += "," + flag
+	}
+}
+
+// inviteRiskCluster 命中异常规则的同簇奖励，如同一邀请人下同一 IP 注册的全部被邀请用户
+type inviteRiskCluster struct {
+	flag  string
+	query *gorm.DB
+}
+
+// detectInviteRisks 按 IP、设备与邀请频率检测异常，返回需要一并标记的同簇奖励
+func (reward *InviteReward) detectInviteRisks(tx *gorm.DB) (clusters []inviteRiskCluster, err error) {
+	setting := operation_setting.GetInviteRewardSetting()
+	var count int64
+	if reward.Ip != "" {
+		if setting.IpThreshold > 0 {
+			cluster := tx.Model(&InviteReward{}).Where("inviter_id = ? AND ip = ?", reward.InviterId, reward.Ip)
+			if err = cluster.Count(&count).Error; err != nil {
+				return nil, err
+			}
+			// 计入当前这一条
+			if count+1 >= int64(setting.IpThreshold) {
+				reward.addRiskFlag(InviteRiskSharedIp)
+				clusters = append(clusters, inviteRiskCluster{
+					flag:  InviteRiskSharedIp,
+					query: tx.Where("inviter_id = ? AND ip = ?", reward.InviterId, reward.Ip),
+				})
+			}
+		}
+		err = tx.Model(&UserSession{}).Where("user_id = ? AND ip = ?", reward.InviterId, reward.Ip).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			reward.addRiskFlag(InviteRiskInviterIp)
+		}
+	}
+	if reward.DeviceId != "" && setting.DeviceThreshold > 0 {
+		if err = tx.Model(&InviteReward{}).Where("device_id = ?", reward.DeviceId).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count+1 >= int64(setting.DeviceThreshold) {
+			reward.addRiskFlag(InviteRiskSharedDevice)
+			clusters = append(clusters, inviteRiskCluster{
+				flag:  InviteRiskSharedDevice,
+				query: tx.Where("device_id = ?", reward.DeviceId),
+			})
+		}
+	}
+	if setting.BurstThreshold > 0 {
+		err = tx.Model(&InviteReward{}).
+			Where("inviter_id = ? AND created_at >= ?", reward.InviterId, reward.CreatedAt-3600).
+			Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count+1 >= int64(setting.BurstThreshold) {
+			reward.addRiskFlag(InviteRiskBurst)
+		}
+	}
+	return clusters, nil
+}
+
+// createInviteReward 为新注册的被邀请用户记录待发放的奖励，命中异常规则时连同同簇的待发放奖励一起进入审核
+func createInviteReward(inviterId int, inviteeId int, source InviteSource) error {
+	reward := &InviteReward{
+		InviterId:    inviterId,
+		InviteeId:    inviteeId,
+		InviterQuota: common.QuotaForInviter,
+		InviteeQuota: common.QuotaForInvitee,
+		Status:       InviteRewardStatusPending,
+		Ip:           source.Ip,
+		DeviceId:     source.DeviceId,
+		CreatedAt:    common.GetTimestamp(),
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		clusters, err := reward.detectInviteRisks(tx)
+		if err != nil {
+			return err
+		}
+		if reward.RiskFlags != "" {
+			reward.Status = InviteRewardStatusFlagged
+		}
+		// 已发放或已拒绝的奖励保持不变
+		for _, cluster := range clusters {
+			var members []*InviteReward
+			err = cluster.query.Where("status IN ?", []int{InviteRewardStatusPending, InviteRewardStatusFlagged}).
+				Find(&members).Error
+			if err != nil {
+				return err
+			}
+			for _, member := range members {
+				member.addRiskFlag(cluster.flag)
+				err = tx.Model(member).Updates(map[string]interface{}{
+					"status":     InviteRewardStatusFlagged,
+					"risk_flags": member.RiskFlags,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Create(reward).Error
+	})
+}
+
+// GetVestableInviteRewards 被邀请用户已完成真实充值或消耗达到门槛的待发放奖励
+func GetVestableInviteRewards(usageThreshold int, limit int) (rewards []*InviteReward, err error) {
+	activeInvitees := DB.Model(&User{}).Select("id").Where("status = ?", common.UserStatusEnabled)
+	paidInvitees := DB.Model(&TopUp{}).Select("user_id").Where("status = ?", common.TopUpStatusSuccess)
+	tx := DB.Where("status = ? AND invitee_id IN (?)", InviteRewardStatusPending, activeInvitees)
+	if usageThreshold > 0 {
+		usedInvitees := DB.Model(&User{}).Select("id").Where("used_quota >= ?", usageThreshold)
+		tx = tx.Where("invitee_id IN (?) OR invitee_id IN (?)", paidInvitees, usedInvitees)
+	} else {
+		tx = tx.Where("invitee_id IN (?)", paidInvitees)
+	}
+	err = tx.Order("id").Limit(limit).Find(&rewards).Error
+	return rewards, err
+}
+
+// Vest 发放奖励：邀请人奖励计入邀请额度，被邀请人奖励计入额度
+func (reward *InviteReward) Vest() error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&InviteReward{}).
+			Where("id = ? AND status = ?", reward.Id, InviteRewardStatusPending).
+			Updates(map[string]interface{}{
+				"status":    InviteRewardStatusVested,
+				"vested_at": common.GetTimestamp(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("奖励不是待发放状态")
+		}
+		if reward.InviterQuota > 0 {
+			err := tx.Model(&User{}).Where("id = ?", reward.InviterId).Updates(map[string]interface{}{
+				"aff_quota":   gorm.Expr("aff_quota + ?", reward.InviterQuota),
+				"aff_history": gorm.Expr("aff_history + ?", reward.InviterQuota),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if reward.InviteeQuota > 0 {
+			err := tx.Model(&User{}).Where("id = ?", reward.InviteeId).
+				Update("quota", gorm.Expr("quota + ?", reward.InviteeQuota)).Error
+			if err != nil {
+				return err
+			}
+			if err := recordQuotaLedger(tx, reward.InviteeId, QuotaLedgerTypeInviteReward, reward.InviteeQuota); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if reward.InviterQuota > 0 {
+		RecordLog(reward.InviterId, LogTypeSystem, fmt.Sprintf("邀请用户 %d 奖励发放 %s", reward.InviteeId, common.LogQuota(reward.InviterQuota)))
+	}
+	if reward.InviteeQuota > 0 {
+		RecordLog(reward.InviteeId, LogTypeSystem, fmt.Sprintf("使用邀请码赠送 %s", common.LogQuota(reward.InviteeQuota)))
+		if err := invalidateUserCache(reward.InviteeId); err != nil {
+			common.SysError("failed to invalidate user cache: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func GetInviteRewardById(id int) (*InviteReward, error) {
+	var reward InviteReward
+	err := DB.First(&reward, "id = ?", id).Error
+	return &reward, err
+}
+
+// ReviewInviteReward 审核被标记的奖励：通过后恢复为待发放，仍需满足归属条件；拒绝后不再发放
+func ReviewInviteReward(id int, approve bool, reviewerId int) error {
+	status := InviteRewardStatusRejected
+	if approve {
+		status = InviteRewardStatusPending
+	}
+	result := DB.Model(&InviteReward{}).
+		Where("id = ? AND status = ?", id, InviteRewardStatusFlagged).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewerId,
+			"reviewed_at": common.GetTimestamp(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("奖励不是待审核状态")
+	}
+	return nil
+}
+
+func SearchInviteRewards(inviterId int, status int, startIdx int, num int) (rewards []*InviteReward, total int64, err error) {
+	tx := DB.Model(&InviteReward{})
+	if inviterId != 0 {
+		tx = tx.Where("inviter_id = ?", inviterId)
+	}
+	if status != 0 {
+		tx = tx.Where("status = ?", status)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&rewards).Error
+	return rewards, total, err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"one-api/common"
+	"one-api/common/metrics"
 	"os"
 	"strings"
 	"time"
@@ -151,6 +152,10 @@ type RecordConsumeLogParams struct {
 
 func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams) {
 	common.LogInfo(c, fmt.Sprintf("record consume log: userId=%d, params=%s", userId, common.GetJsonString(params)))
+	metrics.RecordQuota(params.ModelName, params.ChannelId, params.Group, params.Quota)
+	if frt, ok := params.Other["frt"].(float64); ok && params.IsStream {
+		metrics.RecordFirstToken(params.ModelName, params.ChannelId, params.Group, frt/1000)
+	}
 	if !common.LogConsumeEnabled {
 		return
 	}
@@ -236,6 +241,12 @@ func GetAllLogs(logType int, startTimestamp int64, endTimestamp int64, modelName
 		return nil, 0, err
 	}
 
+	err = fillLogChannelNames(logs)
+	return logs, total, err
+}
+
+// fillLogChannelNames 批量查询渠道名称并填充到日志中
+func fillLogChannelNames(logs []*Log) error {
 	channelIdsMap := make(map[int]struct{})
 	channelMap := make(map[int]string)
 	for _, log := range logs {
@@ -253,8 +264,8 @@ func GetAllLogs(logType int, startTimestamp int64, endTimestamp int64, modelName
 			Id   int    `gorm:"column:id"`
 			Name string `gorm:"column:name"`
 		}
-		if err = DB.Table("channels").Select("id, name").Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
-			return logs, total, err
+		if err := DB.Table("channels").Select("id, name").Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
+			return err
 		}
 		for _, channel := range channels {
 			channelMap[channel.Id] = channel.Name
@@ -263,8 +274,7 @@ func GetAllLogs(logType int, startTimestamp int64, endTimestamp int64, modelName
 			logs[i].ChannelName = channelMap[logs[i].ChannelId]
 		}
 	}
-
-	return logs, total, err
+	return nil
 }
 
 func GetUserLogs(userId int, logType int, startTimestamp int64, endTimestamp int64, modelName string, tokenName string, startIdx int, num int, group string) (logs []*Log, total int64, err error) {
@@ -303,6 +313,87 @@ func GetUserLogs(userId int, logType int, startTimestamp int64, endTimestamp int
 	return logs, total, err
 }
 
+// LogExportFilter 日志导出的筛选条件，UserId 为 0 时表示导出所有用户
+type LogExportFilter struct {
+	UserId         int
+	Type           int
+	StartTimestamp int64
+	EndTimestamp   int64
+	ModelName      string
+	Username       string
+	TokenName      string
+	Channel        int
+	Group          string
+}
+
+func (filter *LogExportFilter) apply(tx *gorm.DB) *gorm.DB {
+	if filter.UserId != 0 {
+		tx = tx.Where("logs.user_id = ?", filter.UserId)
+	}
+	if filter.Type != LogTypeUnknown {
+		tx = tx.Where("logs.type = ?", filter.Type)
+	}
+	if filter.ModelName != "" {
+		tx = tx.Where("logs.model_name like ?", filter.ModelName)
+	}
+	if filter.Username != "" {
+		tx = tx.Where("logs.username = ?", filter.Username)
+	}
+	if filter.TokenName != "" {
+		tx = tx.Where("logs.token_name = ?", filter.TokenName)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("logs.created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("logs.created_at <= ?", filter.EndTimestamp)
+	}
+	if filter.Channel != 0 {
+		tx = tx.Where("logs.channel_id = ?", filter.Channel)
+	}
+	if filter.Group != "" {
+		tx = tx.Where("logs."+logGroupCol+" = ?", filter.Group)
+	}
+	return tx
+}
+
+// IterateLogs 以 id 为游标倒序分批读取日志，每批交给 fn 处理，避免一次性加载全部数据。
+// 导出所有用户时会填充渠道名称，导出单个用户时会按用户视角清理日志。
+func IterateLogs(ctx context.Context, filter LogExportFilter, batchSize int, fn func(logs []*Log) error) error {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	lastId := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tx := filter.apply(LOG_DB.WithContext(ctx).Model(&Log{}))
+		if lastId != 0 {
+			tx = tx.Where("logs.id < ?", lastId)
+		}
+		var logs []*Log
+		if err := tx.Order("logs.id desc").Limit(batchSize).Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+		lastId = logs[len(logs)-1].Id
+		if filter.UserId != 0 {
+			formatUserLogs(logs)
+		} else if err := fillLogChannelNames(logs); err != nil {
+			return err
+		}
+		if err := fn(logs); err != nil {
+			return err
+		}
+		if len(logs) < batchSize {
+			return nil
+		}
+	}
+}
+
 func SearchAllLogs(keyword string) (logs []*Log, err error) {
 	err = LOG_DB.Where("type = ? or content LIKE ?", keyword, keyword+"%").Order("id desc").Limit(common.MaxRecentItems).Find(&logs).Error
 	return logs, err
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// LogArchive 归档清单，记录每个归档文件覆盖的日志范围及存储位置
+type LogArchive struct {
+	Id        int    `json:"id"`
+	StartId   int    `json:"start_id"`
+	EndId     int    `json:"end_id"`
+	StartTime int64  `json:"start_time" gorm:"bigint;index"`
+	EndTime   int64  `json:"end_time" gorm:"bigint;index"`
+	Count     int    `json:"count"`
+	Storage   string `json:"storage" gorm:"type:varchar(16)"`
+	Path      string `json:"path" gorm:"type:varchar(512)"`
+	Size      int64  `json:"size"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+}
+
+// GetLogsToArchive 按 id 升序读取早于 targetTimestamp 的日志，afterId 为上一批的最大 id
+func GetLogsToArchive(ctx context.Context, targetTimestamp int64, afterId int, limit int) (logs []*Log, err error) {
+	err = LOG_DB.WithContext(ctx).Where("created_at < ? AND id > ?", targetTimestamp, afterId).
+		Order("id asc").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// CommitLogArchive 在同一事务中写入归档清单并删除已归档的日志
+func CommitLogArchive(archive *LogArchive, targetTimestamp int64) error {
+	return LOG_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(archive).Error; err != nil {
+			return err
+		}
+		return tx.Where("id >= ? AND id <= ? AND created_at < ?", archive.StartId, archive.EndId, targetTimestamp).
+			Delete(&Log{}).Error
+	})
+}
+
+// GetLogArchives 返回与时间范围有交集的归档清单，时间戳为 0 表示不限制
+func GetLogArchives(startTimestamp int64, endTimestamp int64) (archives []*LogArchive, err error) {
+	tx := LOG_DB.Model(&LogArchive{})
+	if startTimestamp != 0 {
+		tx = tx.Where("end_time >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("start_time <= ?", endTimestamp)
+	}
+	err = tx.Order("start_time asc").Find(&archives).Error
+	return archives, err
+}
@@ -78,7 +78,9 @@ func createRootAccountIfNeed() error {
 			Quota:       100000000,
 			IsFirstUse:  1, // 确保root用户 is_first_use 为 1
 		}
-		DB.Create(&rootUser)
+		if DB.Create(&rootUser).Error == nil {
+			RecordQuotaLedger(rootUser.Id, QuotaLedgerTypeRegister, rootUser.Quota)
+		}
 	}
 	return nil
 }
@@ -214,6 +216,11 @@ func InitDB() (err error) {
 			common.SysLog("warning: failed to check/fix foreign keys: " + err.Error())
 		}
 
+		// 将旧数据中未携带国家码的手机号迁移为 E.164 格式
+		if err := MigrateUserPhones(); err != nil {
+			common.SysLog("warning: failed to migrate user phones: " + err.Error())
+		}
+
 		// 初始化系统推荐数据
 		if err := InitializeSystemRecommendations(); err != nil {
 			common.SysLog("warning: failed to initialize system recommendations: " + err.Error())
@@ -264,15 +271,28 @@ func migrateDB() error {
 		&Channel{},
 		&Token{},
 		&User{},
+		&UserSession{},
+		&TwoFA{},
+		&AdminRole{},
+		&AuditLog{},
+		&AccountDeletion{},
+		&Organization{},
+		&OrganizationMember{},
+		&InviteReward{},
+		&QuotaLedger{},
 		&Option{},
 		&Redemption{},
 		&Ability{},
 		&Log{},
+		&LogArchive{},
+		&StoredResponse{},
 		&Midjourney{},
 		&TopUp{},
 		&QuotaData{},
 		&Task{},
 		&Setup{},
+		&StripeSubscription{},
+		&StripeEvent{},
 		// UserSubscription 由 SQLite 钩子处理
 		&Subscription{},
 		&SubscriptionArticle{},
@@ -295,15 +315,28 @@ func migrateDBFast() error {
 		{&Channel{}, "Channel"},
 		{&Token{}, "Token"},
 		{&User{}, "User"},
+		{&UserSession{}, "UserSession"},
+		{&TwoFA{}, "TwoFA"},
+		{&AdminRole{}, "AdminRole"},
+		{&AuditLog{}, "AuditLog"},
+		{&AccountDeletion{}, "AccountDeletion"},
+		{&Organization{}, "Organization"},
+		{&OrganizationMember{}, "OrganizationMember"},
+		{&InviteReward{}, "InviteReward"},
+		{&QuotaLedger{}, "QuotaLedger"},
 		{&Option{}, "Option"},
 		{&Redemption{}, "Redemption"},
 		{&Ability{}, "Ability"},
 		{&Log{}, "Log"},
+		{&LogArchive{}, "LogArchive"},
+		{&StoredResponse{}, "StoredResponse"},
 		{&Midjourney{}, "Midjourney"},
 		{&TopUp{}, "TopUp"},
 		{&QuotaData{}, "QuotaData"},
 		{&Task{}, "Task"},
 		{&Setup{}, "Setup"},
+		{&StripeSubscription{}, "StripeSubscription"},
+		{&StripeEvent{}, "StripeEvent"},
 		// UserSubscription 由 SQLite 钩子处理
 		// 跳过有外键约束的模型，由SQLite钩子处理
 		// {&Subscription{}, "Subscription"},
@@ -341,7 +374,7 @@ func migrateDBFast() error {
 
 func migrateLOGDB() error {
 	var err error
-	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
+	if err = LOG_DB.AutoMigrate(&Log{}, &LogArchive{}); err != nil {
 		return err
 	}
 	return nil
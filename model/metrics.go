@@ -0,0 +1,49 @@
+package model
+
+import (
+	"one-api/common"
+	"one-api/common/metrics"
+	"strconv"
+)
+
+var batchUpdateTypeNames = map[int]string{
+	BatchUpdateTypeUserQuota:        "user_quota",
+	BatchUpdateTypeTokenQuota:       "token_quota",
+	BatchUpdateTypeUsedQuota:        "used_quota",
+	BatchUpdateTypeChannelUsedQuota: "channel_used_quota",
+	BatchUpdateTypeRequestCount:     "request_count",
+}
+
+var channelStatusNames = map[int]string{
+	common.ChannelStatusUnknown:          "unknown",
+	common.ChannelStatusEnabled:          "enabled",
+	common.ChannelStatusManuallyDisabled: "manually_disabled",
+	common.ChannelStatusAutoDisabled:     "auto_disabled",
+}
+
+// RegisterMetrics 注册需要在采集时读取数据库或内存状态的指标
+func RegisterMetrics() {
+	metrics.RegisterGaugeFunc("channels", "Number of channels by status.", "status", func() map[string]float64 {
+		counts, err := CountChannelsGroupByStatus()
+		if err != nil {
+			common.SysError("failed to count channels for metrics: " + err.Error())
+			return nil
+		}
+		values := make(map[string]float64, len(channelStatusNames))
+		for status, name := range channelStatusNames {
+			values[name] = float64(counts[status])
+		}
+		return values
+	})
+	metrics.RegisterGaugeFunc("batch_update_queue_size", "Number of pending records in the batch updater queues.", "type", func() map[string]float64 {
+		values := make(map[string]float64, BatchUpdateTypeCount)
+		for updateType, size := range GetBatchUpdateQueueSizes() {
+			name, ok := batchUpdateTypeNames[updateType]
+			if !ok {
+				name = strconv.Itoa(updateType)
+			}
+			values[name] = float64(size)
+		}
+		return values
+	})
+}
@@ -9,6 +9,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Option struct {
@@ -16,11 +18,33 @@ type Option struct {
 	Value string `json:"value"`
 }
 
+// IsSecretOption 名称以 secret、key 或 token 结尾（不区分大小写）的配置项，
+// 如 TurnstileSecretKey、oidc.client_secret。这些配置项加密保存，且不通过 GetOptions 返回
+func IsSecretOption(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasSuffix(key, "secret") || strings.HasSuffix(key, "key") || strings.HasSuffix(key, "token")
+}
+
 func AllOption() ([]*Option, error) {
 	var options []*Option
 	var err error
 	err = DB.Find(&options).Error
-	return options, err
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Option, 0, len(options))
+	for _, option := range options {
+		if IsSecretOption(option.Key) {
+			value, err := common.DecryptSecret(option.Value)
+			if err != nil {
+				common.SysError("failed to decrypt option " + option.Key + ": " + err.Error())
+				continue
+			}
+			option.Value = value
+		}
+		result = append(result, option)
+	}
+	return result, nil
 }
 
 func InitOptionMap() {
@@ -33,6 +57,7 @@ func InitOptionMap() {
 	common.OptionMap["ImageUploadPermission"] = strconv.Itoa(common.ImageUploadPermission)
 	common.OptionMap["ImageDownloadPermission"] = strconv.Itoa(common.ImageDownloadPermission)
 	common.OptionMap["PhoneVerificationEnabled"] = strconv.FormatBool(common.PhoneVerificationEnabled)
+	common.OptionMap["AdminTwoFAEnforcementEnabled"] = strconv.FormatBool(common.AdminTwoFAEnforcementEnabled)
 	common.OptionMap["EmailVerificationEnabled"] = strconv.FormatBool(common.EmailVerificationEnabled)
 	common.OptionMap["GitHubOAuthEnabled"] = strconv.FormatBool(common.GitHubOAuthEnabled)
 	common.OptionMap["LinuxDOOAuthEnabled"] = strconv.FormatBool(common.LinuxDOOAuthEnabled)
@@ -68,6 +93,7 @@ func InitOptionMap() {
 	common.OptionMap["WorkerUrl"] = setting.WorkerUrl
 	common.OptionMap["WorkerValidKey"] = setting.WorkerValidKey
 	common.OptionMap["WorkerAllowHttpImageRequestEnabled"] = strconv.FormatBool(setting.WorkerAllowHttpImageRequestEnabled)
+	common.OptionMap["MetricsToken"] = ""
 	common.OptionMap["PayAddress"] = ""
 	common.OptionMap["CustomCallbackAddress"] = ""
 	common.OptionMap["EpayId"] = ""
@@ -80,6 +106,8 @@ func InitOptionMap() {
 	common.OptionMap["StripeWebhookSecret"] = setting.StripeWebhookSecret
 	common.OptionMap["StripePriceId"] = setting.StripePriceId
 	common.OptionMap["StripeUnitPrice"] = strconv.FormatFloat(setting.StripeUnitPrice, 'f', -1, 64)
+	common.OptionMap["StripeSubscriptionPriceId"] = setting.StripeSubscriptionPriceId
+	common.OptionMap["StripeSubscriptionQuota"] = strconv.Itoa(setting.StripeSubscriptionQuota)
 	common.OptionMap["TopupGroupRatio"] = common.TopupGroupRatio2JSONString()
 	common.OptionMap["Chats"] = setting.Chats2JsonString()
 	common.OptionMap["AutoGroups"] = setting.AutoGroups2JsonString()
@@ -163,17 +191,43 @@ func SyncOptions(frequency int) {
 }
 
 func UpdateOption(key string, value string) error {
+	return UpdateOptionWithAudit(key, value, nil)
+}
+
+// UpdateOptionWithAudit 与 UpdateOption 相同，audit 不为空时与配置在同一事务中写入
+func UpdateOptionWithAudit(key string, value string, audit *AuditLog) error {
+	storedValue := value
+	if IsSecretOption(key) {
+		encrypted, err := common.EncryptSecret(value)
+		if err != nil {
+			return err
+		}
+		storedValue = encrypted
+	}
 	// Save to database first
-	option := Option{
-		Key: key,
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		option := Option{
+			Key: key,
+		}
+		// https://gorm.io/docs/update.html#Save-All-Fields
+		if err := tx.FirstOrCreate(&option, Option{Key: key}).Error; err != nil {
+			return err
+		}
+		option.Value = storedValue
+		// Save is a combination function.
+		// If save value does not contain primary key, it will execute Create,
+		// otherwise it will execute Update (with all fields).
+		if err := tx.Save(&option).Error; err != nil {
+			return err
+		}
+		if audit != nil {
+			return audit.Insert(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	// https://gorm.io/docs/update.html#Save-All-Fields
-	DB.FirstOrCreate(&option, Option{Key: key})
-	option.Value = value
-	// Save is a combination function.
-	// If save value does not contain primary key, it will execute Create,
-	// otherwise it will execute Update (with all fields).
-	DB.Save(&option)
 	// Update OptionMap
 	return updateOptionMap(key, value)
 }
@@ -207,6 +261,8 @@ func updateOptionMap(key string, value string) (err error) {
 		switch key {
 		case "PhoneVerificationEnabled":
 			common.PhoneVerificationEnabled = boolValue
+		case "AdminTwoFAEnforcementEnabled":
+			common.AdminTwoFAEnforcementEnabled = boolValue
 		case "EmailVerificationEnabled":
 			common.EmailVerificationEnabled = boolValue
 		case "GitHubOAuthEnabled":
@@ -295,6 +351,8 @@ func updateOptionMap(key string, value string) (err error) {
 		setting.WorkerUrl = value
 	case "WorkerValidKey":
 		setting.WorkerValidKey = value
+	case "MetricsToken":
+		setting.MetricsToken = value
 	case "PayAddress":
 		setting.PayAddress = value
 	case "Chats":
@@ -323,6 +381,10 @@ func updateOptionMap(key string, value string) (err error) {
 		setting.StripeUnitPrice, _ = strconv.ParseFloat(value, 64)
 	case "StripeMinTopUp":
 		setting.StripeMinTopUp, _ = strconv.Atoi(value)
+	case "StripeSubscriptionPriceId":
+		setting.StripeSubscriptionPriceId = value
+	case "StripeSubscriptionQuota":
+		setting.StripeSubscriptionQuota, _ = strconv.Atoi(value)
 	case "TopupGroupRatio":
 		err = common.UpdateTopupGroupRatioByJSONString(value)
 	case "GitHubClientId":
@@ -0,0 +1,412 @@
+package model
+
+import (
+	"errors"
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+// 组织成员角色，owner 与 admin 可管理成员
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+const (
+	OrganizationMemberStatusInvited = 1
+	OrganizationMemberStatusActive  = 2
+)
+
+const (
+	OrganizationStatusEnabled  = 1
+	OrganizationStatusDisabled = 2
+)
+
+// 组织令牌不可用的原因，计费时据此返回拒绝访问而非查询失败
+var (
+	ErrNotOrganizationMember = errors.New("已不是该令牌所属组织的成员")
+	ErrOrganizationDisabled  = errors.New("组织已被禁用")
+	// ErrOrganizationQuotaNotEnough 扣费时组织额度不足或超出成员额度上限
+	ErrOrganizationQuotaNotEnough = errors.New("组织额度不足或已超出成员额度上限")
+)
+
+// Organization 组织，成员使用组织令牌时从组织额度扣费，用量仍记在成员名下
+type Organization struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(64)"`
+	OwnerId     int    `json:"owner_id" gorm:"index"`
+	Quota       int    `json:"quota" gorm:"type:int;default:0"`
+	UsedQuota   int    `json:"used_quota" gorm:"type:int;default:0"`
+	Status      int    `json:"status" gorm:"type:int;default:1"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+// OrganizationMember 组织成员，一个用户同时只能加入或被邀请加入一个组织。
+// QuotaLimit 为成员可使用的组织额度上限，0 表示不限制
+type OrganizationMember struct {
+	Id             int    `json:"id"`
+	OrganizationId int    `json:"organization_id" gorm:"index"`
+	UserId         int    `json:"user_id" gorm:"uniqueIndex"`
+	Role           string `json:"role" gorm:"type:varchar(16)"`
+	Status         int    `json:"status" gorm:"type:int;default:1"`
+	QuotaLimit     int    `json:"quota_limit" gorm:"type:int;default:0"`
+	UsedQuota      int    `json:"used_quota" gorm:"type:int;default:0"`
+	InvitedBy      int    `json:"invited_by"`
+	CreatedTime    int64  `json:"created_time" gorm:"bigint"`
+	Username       string `json:"username" gorm:"->"`
+	DisplayName    string `json:"display_name" gorm:"->"`
+	Phone          string `json:"phone" gorm:"->"`
+}
+
+// OrganizationUsage 组织用量报表的一行，按成员与模型汇总
+type OrganizationUsage struct {
+	UserId           int    `json:"user_id"`
+	Username         string `json:"username"`
+	ModelName        string `json:"model_name"`
+	Quota            int    `json:"quota"`
+	Count            int    `json:"count"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+}
+
+func IsValidOrganizationRole(role string) bool {
+	return role == OrganizationRoleAdmin || role == OrganizationRoleMember
+}
+
+func (member *OrganizationMember) IsActive() bool {
+	return member.Status == OrganizationMemberStatusActive
+}
+
+func (member *OrganizationMember) CanManage() bool {
+	return member.IsActive() && (member.Role == OrganizationRoleOwner || member.Role == OrganizationRoleAdmin)
+}
+
+// CreateOrganization 创建组织，创建者成为所有者
+func CreateOrganization(name string, ownerId int) (*Organization, error) {
+	now := common.GetTimestamp()
+	org := &Organization{
+		Name:        name,
+		OwnerId:     ownerId,
+		Status:      OrganizationStatusEnabled,
+		CreatedTime: now,
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&OrganizationMember{
+			OrganizationId: org.Id,
+			UserId:         ownerId,
+			Role:           OrganizationRoleOwner,
+			Status:         OrganizationMemberStatusActive,
+			CreatedTime:    now,
+		}).Error
+	})
+	return org, err
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	var org Organization
+	err := DB.First(&org, "id = ?", id).Error
+	return &org, err
+}
+
+func GetAllOrganizations(startIdx int, num int) (orgs []*Organization, total int64, err error) {
+	tx := DB.Model(&Organization{})
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&orgs).Error
+	return orgs, total, err
+}
+
+// UpdateOrganizationByAdmin 管理员修改组织额度与状态
+func UpdateOrganizationByAdmin(org *Organization) error {
+	return DB.Model(org).Select("name", "quota", "status").Updates(org).Error
+}
+
+// GetUserOrganizationMember 获取用户的组织成员记录（含待接受的邀请），不存在时返回 nil
+func GetUserOrganizationMember(userId int) (*OrganizationMember, error) {
+	var member OrganizationMember
+	err := DB.Where("user_id = ?", userId).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func GetOrganizationMembers(orgId int) (members []*OrganizationMember, err error) {
+	err = DB.Model(&OrganizationMember{}).
+		Select("organization_members.*, users.username, users.display_name, users.phone").
+		Joins("LEFT JOIN users ON users.id = organization_members.user_id").
+		Where("organization_members.organization_id = ?", orgId).
+		Order("organization_members.id").
+		Find(&members).Error
+	return members, err
+}
+
+// InviteOrganizationMember 邀请用户加入组织，用户接受后生效
+func InviteOrganizationMember(orgId int, userId int, role string, quotaLimit int, invitedBy int) (*OrganizationMember, error) {
+	exist, err := GetUserOrganizationMember(userId)
+	if err != nil {
+		return nil, err
+	}
+	if exist != nil {
+		return nil, errors.New("该用户已加入或已被邀请加入其他组织")
+	}
+	member := &OrganizationMember{
+		OrganizationId: orgId,
+		UserId:         userId,
+		Role:           role,
+		Status:         OrganizationMemberStatusInvited,
+		QuotaLimit:     quotaLimit,
+		InvitedBy:      invitedBy,
+		CreatedTime:    common.GetTimestamp(),
+	}
+	return member, DB.Create(member).Error
+}
+
+func AcceptOrganizationInvitation(userId int) error {
+	result := DB.Model(&OrganizationMember{}).
+		Where("user_id = ? AND status = ?", userId, OrganizationMemberStatusInvited).
+		Update("status", OrganizationMemberStatusActive)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("没有待接受的组织邀请")
+	}
+	return nil
+}
+
+func DeclineOrganizationInvitation(userId int) error {
+	result := DB.Where("user_id = ? AND status = ?", userId, OrganizationMemberStatusInvited).Delete(&OrganizationMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("没有待接受的组织邀请")
+	}
+	return nil
+}
+
+// UpdateOrganizationMember 修改成员角色与额度上限，所有者的角色不可修改
+func UpdateOrganizationMember(orgId int, userId int, role string, quotaLimit int) error {
+	result := DB.Model(&OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ? AND role <> ?", orgId, userId, OrganizationRoleOwner).
+		Updates(map[string]interface{}{
+			"role":        role,
+			"quota_limit": quotaLimit,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("成员不存在或无法修改")
+	}
+	return nil
+}
+
+// RemoveOrganizationMember 移除成员或撤回邀请，所有者不可移除。
+// 成员的组织令牌保留以便统计历史用量，但成员离开后无法再使用
+func RemoveOrganizationMember(orgId int, userId int) error {
+	result := DB.Where("organization_id = ? AND user_id = ? AND role <> ?", orgId, userId, OrganizationRoleOwner).
+		Delete(&OrganizationMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("成员不存在或无法移除")
+	}
+	return nil
+}
+
+// DepositOrganizationQuota 将用户的个人额度转入组织额度
+func DepositOrganizationQuota(orgId int, userId int, quota int) error {
+	if quota <= 0 {
+		return errors.New("转入额度必须大于 0")
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ? AND quota >= ?", userId, quota).
+			Update("quota", gorm.Expr("quota - ?", quota))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("个人额度不足")
+		}
+		if err := recordQuotaLedger(tx, userId, QuotaLedgerTypeOrganizationDeposit, -quota); err != nil {
+			return err
+		}
+		return tx.Model(&Organization{}).Where("id = ?", orgId).
+			Update("quota", gorm.Expr("quota + ?", quota)).Error
+	})
+	if err != nil {
+		return err
+	}
+	return invalidateUserCache(userId)
+}
+
+// DeleteOrganization 解散组织：剩余额度退回所有者，移除全部成员并禁用组织令牌
+func DeleteOrganization(org *Organization) error {
+	var tokenKeys []string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// 重新读取，避免退回过期的额度
+		if err := tx.First(org, "id = ?", org.Id).Error; err != nil {
+			return err
+		}
+		if org.Quota > 0 {
+			err := tx.Model(&User{}).Where("id = ?", org.OwnerId).
+				Update("quota", gorm.Expr("quota + ?", org.Quota)).Error
+			if err != nil {
+				return err
+			}
+			if err := recordQuotaLedger(tx, org.OwnerId, QuotaLedgerTypeOrganizationRefund, org.Quota); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("organization_id = ?", org.Id).Delete(&OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Token{}).Where("organization_id = ?", org.Id).Pluck("key", &tokenKeys).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&Token{}).Where("organization_id = ?", org.Id).
+			Update("status", common.TokenStatusDisabled).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(org).Error
+	})
+	if err != nil {
+		return err
+	}
+	if common.RedisEnabled {
+		for _, key := range tokenKeys {
+			_ = cacheDeleteToken(key)
+		}
+	}
+	return invalidateUserCache(org.OwnerId)
+}
+
+// GetOrganizationMemberQuota 成员当前可使用的组织额度：组织剩余额度与成员剩余上限中的较小值
+func GetOrganizationMemberQuota(orgId int, userId int) (int, error) {
+	quota, _, err := GetOrganizationMemberBalance(orgId, userId)
+	return quota, err
+}
+
+// GetOrganizationMemberBalance 成员当前可使用的组织额度与已使用的组织额度
+func GetOrganizationMemberBalance(orgId int, userId int) (quota int, usedQuota int, err error) {
+	var member OrganizationMember
+	err = DB.Where("organization_id = ? AND user_id = ? AND status = ?", orgId, userId, OrganizationMemberStatusActive).
+		First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, 0, ErrNotOrganizationMember
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	org, err := GetOrganizationById(orgId)
+	if err != nil {
+		return 0, 0, err
+	}
+	if org.Status != OrganizationStatusEnabled {
+		return 0, 0, ErrOrganizationDisabled
+	}
+	quota = org.Quota
+	if member.QuotaLimit > 0 && member.QuotaLimit-member.UsedQuota < quota {
+		quota = member.QuotaLimit - member.UsedQuota
+	}
+	return quota, member.UsedQuota, nil
+}
+
+// DecreaseOrganizationQuota 从组织额度扣费，并计入成员已用额度。
+// 组织额度不足或扣费后超出成员额度上限时不扣费，返回 ErrOrganizationQuotaNotEnough
+func DecreaseOrganizationQuota(orgId int, userId int, quota int) error {
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	return updateOrganizationQuota(orgId, userId, -quota)
+}
+
+// IncreaseOrganizationQuota 退还组织额度，并从成员已用额度中扣除
+func IncreaseOrganizationQuota(orgId int, userId int, quota int) error {
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	return updateOrganizationQuota(orgId, userId, quota)
+}
+
+// RefundQuota 退还异步任务的额度，组织令牌发起的任务退回组织
+func RefundQuota(userId int, organizationId int, quota int) error {
+	if organizationId != 0 {
+		return IncreaseOrganizationQuota(organizationId, userId, quota)
+	}
+	if err := IncreaseUserQuota(userId, quota, false); err != nil {
+		return err
+	}
+	RecordQuotaLedger(userId, QuotaLedgerTypeTaskRefund, quota)
+	return nil
+}
+
+// updateOrganizationQuota 在同一事务中修改组织额度与成员已用额度。
+// 扣费时以条件更新检查组织余额与成员上限，避免并发请求基于同一次读取同时通过检查
+func updateOrganizationQuota(orgId int, userId int, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		orgTx := tx.Model(&Organization{}).Where("id = ?", orgId)
+		memberTx := tx.Model(&OrganizationMember{}).Where("organization_id = ? AND user_id = ?", orgId, userId)
+		if delta < 0 {
+			orgTx = orgTx.Where("quota >= ?", -delta)
+			memberTx = memberTx.Where("(quota_limit = 0 OR used_quota + ? <= quota_limit)", -delta)
+		}
+		result := orgTx.Updates(map[string]interface{}{
+			"quota":      gorm.Expr("quota + ?", delta),
+			"used_quota": gorm.Expr("used_quota - ?", delta),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if delta < 0 && result.RowsAffected == 0 {
+			return ErrOrganizationQuotaNotEnough
+		}
+		result = memberTx.Update("used_quota", gorm.Expr("used_quota - ?", delta))
+		if result.Error != nil {
+			return result.Error
+		}
+		if delta < 0 && result.RowsAffected == 0 {
+			return ErrOrganizationQuotaNotEnough
+		}
+		return nil
+	})
+}
+
+// GetOrganizationUsage 按成员与模型汇总组织令牌产生的消费日志
+func GetOrganizationUsage(orgId int, startTimestamp int64, endTimestamp int64) (usages []*OrganizationUsage, err error) {
+	var tokenIds []int
+	err = DB.Unscoped().Model(&Token{}).Where("organization_id = ?", orgId).Pluck("id", &tokenIds).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(tokenIds) == 0 {
+		return []*OrganizationUsage{}, nil
+	}
+	tx := LOG_DB.Model(&Log{}).
+		Select("user_id, username, model_name, sum(quota) as quota, count(*) as count, sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens").
+		Where("type = ? AND token_id IN ?", LogTypeConsume, tokenIds)
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	err = tx.Group("user_id, username, model_name").Order("user_id, model_name").Scan(&usages).Error
+	return usages, err
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+// 额度流水类型
+const (
+	QuotaLedgerTypeRegister            = "register"             // 注册赠送或初始化额度
+	QuotaLedgerTypeTopUp               = "top_up"               // 在线充值与订阅续费
+	QuotaLedgerTypeTopUpRefund         = "top_up_refund"        // 充值退款扣回
+	QuotaLedgerTypeRedemption          = "redemption"           // 兑换码
+	QuotaLedgerTypeAdminAdjust         = "admin_adjust"         // 管理员修改额度
+	QuotaLedgerTypeAffTransfer         = "aff_transfer"         // 邀请额度划转
+	QuotaLedgerTypeInviteReward        = "invite_reward"        // 被邀请奖励
+	QuotaLedgerTypeOrganizationDeposit = "organization_deposit" // 转入组织
+	QuotaLedgerTypeOrganizationRefund  = "organization_refund"  // 解散组织退回
+	QuotaLedgerTypeTaskRefund          = "task_refund"          // 异步任务失败退款
+)
+
+// QuotaLedger 用户额度流水，记录模型调用消费以外的全部额度变动，Quota 为正表示增加、为负表示减少。
+// 模型调用消费以消费日志为准，两者合计即为用户额度的全部变动
+type QuotaLedger struct {
+	Id        int    `json:"id"`
+	UserId    int    `json:"user_id" gorm:"index:idx_quota_ledger_user_time,priority:1"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;index:idx_quota_ledger_user_time,priority:2"`
+	Type      string `json:"type" gorm:"type:varchar(32)"`
+	Quota     int    `json:"quota"`
+}
+
+// recordQuotaLedger 在事务 tx 中记录额度流水，调用方须与额度修改使用同一事务
+func recordQuotaLedger(tx *gorm.DB, userId int, ledgerType string, quota int) error {
+	if quota == 0 {
+		return nil
+	}
+	return tx.Create(&QuotaLedger{
+		UserId:    userId,
+		CreatedAt: common.GetTimestamp(),
+		Type:      ledgerType,
+		Quota:     quota,
+	}).Error
+}
+
+// RecordQuotaLedger 记录不在事务中完成的额度变动，失败时只记录错误
+func RecordQuotaLedger(userId int, ledgerType string, quota int) {
+	if err := recordQuotaLedger(DB, userId, ledgerType, quota); err != nil {
+		common.SysError("failed to record quota ledger: " + err.Error())
+	}
+}
+
+// QuotaLedgerSum 按类型汇总的额度流水
+type QuotaLedgerSum struct {
+	Type  string `json:"type"`
+	Quota int    `json:"quota"`
+	Count int    `json:"count"`
+}
+
+// SumUserQuotaLedger 按类型汇总用户在 [start, end) 内的额度流水，end 为 0 表示不限
+func SumUserQuotaLedger(userId int, start int64, end int64) (sums []*QuotaLedgerSum, err error) {
+	tx := DB.Model(&QuotaLedger{}).
+		Select("type, sum(quota) as quota, count(*) as count").
+		Where("user_id = ? AND created_at >= ?", userId, start)
+	if end != 0 {
+		tx = tx.Where("created_at < ?", end)
+	}
+	err = tx.Group("type").Order("type").Scan(&sums).Error
+	return sums, err
+}
+
+// GetQuotaLedgerStartTime 最早一条额度流水的时间，流水为空时返回 0。
+// 升级前的额度变动没有流水，早于该时间的月份无法由流水推算余额
+func GetQuotaLedgerStartTime() (startTime int64, err error) {
+	err = DB.Model(&QuotaLedger{}).Select("coalesce(min(created_at), 0)").Scan(&startTime).Error
+	return startTime, err
+}
@@ -142,8 +142,10 @@ func Redeem(key string, userId int) (quota int, err error) {
 		redemption.RedeemedTime = common.GetTimestamp()
 		redemption.Status = common.RedemptionCodeStatusUsed
 		redemption.UsedUserId = userId
-		err = tx.Save(redemption).Error
-		return err
+		if err = tx.Save(redemption).Error; err != nil {
+			return err
+		}
+		return recordQuotaLedger(tx, userId, QuotaLedgerTypeRedemption, redemption.Quota)
 	})
 	if err != nil {
 		return 0, errors.New("兑换失败，" + err.Error())
@@ -158,6 +160,17 @@ func (redemption *Redemption) Insert() error {
 	return err
 }
 
+// InsertWithAudit 创建兑换码并在同一事务中写入审计日志，审计目标 ID 为新兑换码的 ID
+func (redemption *Redemption) InsertWithAudit(audit *AuditLog) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(redemption).Error; err != nil {
+			return err
+		}
+		audit.TargetId = strconv.Itoa(redemption.Id)
+		return audit.Insert(tx)
+	})
+}
+
 func (redemption *Redemption) SelectUpdate() error {
 	// This can update zero values
 	return DB.Model(redemption).Select("redeemed_time", "status").Updates(redemption).Error
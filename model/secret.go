@@ -0,0 +1,132 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("secret", secretSerializer{})
+}
+
+// secretSerializer 字段写入数据库时使用主密钥加密，读取时解密，结构体中始终为明文
+type secretSerializer struct{}
+
+func (secretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("failed to scan secret field %s: unsupported type %T", field.Name, dbValue)
+	}
+	plaintext, err := common.DecryptSecret(value)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt field %s: %w", field.Name, err)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+func (secretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(string)
+	return common.EncryptSecret(value)
+}
+
+// secretRow 迁移时按原始值读取，不经过 secretSerializer
+type secretRow struct {
+	Id    int
+	Key   string
+	Value string
+}
+
+// EncryptStoredSecrets 使用当前主密钥加密数据库中尚未加密的渠道密钥、两步验证密钥与密钥配置，
+// 由旧主密钥加密的值重新加密 DEK。以原值为条件更新，可在服务运行时执行
+func EncryptStoredSecrets() error {
+	if !common.IsSecretEncryptionEnabled() {
+		return fmt.Errorf("ENCRYPTION_MASTER_KEY is not set")
+	}
+	channelCount := 0
+	lastId := 0
+	for {
+		var rows []secretRow
+		err := DB.Table("channels").Select("id, "+commonKeyCol).
+			Where("id > ?", lastId).Order("id").Limit(100).Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			lastId = row.Id
+			encrypted, err := common.ReencryptSecret(row.Key)
+			if err != nil {
+				return fmt.Errorf("channel %d: %w", row.Id, err)
+			}
+			if encrypted == row.Key {
+				continue
+			}
+			result := DB.Table("channels").Where("id = ? AND "+commonKeyCol+" = ?", row.Id, row.Key).
+				Update("key", encrypted)
+			if result.Error != nil {
+				return fmt.Errorf("channel %d: %w", row.Id, result.Error)
+			}
+			channelCount += int(result.RowsAffected)
+		}
+	}
+
+	twoFACount := 0
+	var twoFAs []secretRow
+	if err := DB.Table("two_fas").Select("id, secret AS value").Scan(&twoFAs).Error; err != nil {
+		return err
+	}
+	for _, row := range twoFAs {
+		encrypted, err := common.ReencryptSecret(row.Value)
+		if err != nil {
+			return fmt.Errorf("two_fa %d: %w", row.Id, err)
+		}
+		if encrypted == row.Value {
+			continue
+		}
+		result := DB.Table("two_fas").Where("id = ? AND secret = ?", row.Id, row.Value).
+			Update("secret", encrypted)
+		if result.Error != nil {
+			return fmt.Errorf("two_fa %d: %w", row.Id, result.Error)
+		}
+		twoFACount += int(result.RowsAffected)
+	}
+
+	var options []secretRow
+	if err := DB.Table("options").Select(commonKeyCol + ", value").Scan(&options).Error; err != nil {
+		return err
+	}
+	optionCount := 0
+	for _, option := range options {
+		if !IsSecretOption(option.Key) {
+			continue
+		}
+		encrypted, err := common.ReencryptSecret(option.Value)
+		if err != nil {
+			return fmt.Errorf("option %s: %w", option.Key, err)
+		}
+		if encrypted == option.Value {
+			continue
+		}
+		result := DB.Table("options").Where(commonKeyCol+" = ? AND value = ?", option.Key, option.Value).
+			Update("value", encrypted)
+		if result.Error != nil {
+			return fmt.Errorf("option %s: %w", option.Key, result.Error)
+		}
+		optionCount += int(result.RowsAffected)
+	}
+	common.SysLog(fmt.Sprintf("encrypted %d channel keys, %d two-factor secrets and %d secret options", channelCount, twoFACount, optionCount))
+	return nil
+}
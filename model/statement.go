@@ -0,0 +1,201 @@
+package model
+
+import (
+	"errors"
+	"one-api/common"
+	"one-api/setting/ratio_setting"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Statement 用户月度对账单，额度单位均为 quota。
+// AdjustmentQuota 为充值以外的其他额度变动（兑换码、管理员修改、邀请奖励、组织转入等），按类型列在 Adjustments 中。
+// 余额由额度流水推算，BalanceAvailable 为 false 表示该月早于额度流水启用，期初、期末余额不可用，均为 0
+type Statement struct {
+	UserId           int                    `json:"user_id"`
+	Username         string                 `json:"username"`
+	Month            string                 `json:"month"`
+	StartTime        int64                  `json:"start_time"`
+	EndTime          int64                  `json:"end_time"`
+	OpeningBalance   int                    `json:"opening_balance"`
+	TopUpQuota       int                    `json:"top_up_quota"`
+	AdjustmentQuota  int                    `json:"adjustment_quota"`
+	ConsumedQuota    int                    `json:"consumed_quota"`
+	ClosingBalance   int                    `json:"closing_balance"`
+	BalanceAvailable bool                   `json:"balance_available"`
+	TopUps           []*StatementTopUp      `json:"top_ups"`
+	Adjustments      []*QuotaLedgerSum      `json:"adjustments"`
+	ModelSpends      []*StatementModelSpend `json:"model_spends"`
+	USD              StatementAmount        `json:"usd"`
+	RMB              StatementAmount        `json:"rmb"`
+}
+
+type StatementTopUp struct {
+	TradeNo      string  `json:"trade_no"`
+	Money        float64 `json:"money"`
+	Quota        int     `json:"quota"`
+	CompleteTime int64   `json:"complete_time"`
+	Status       string  `json:"status"`
+}
+
+type StatementModelSpend struct {
+	ModelName        string `json:"model_name"`
+	Requests         int    `json:"requests"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Quota            int    `json:"quota"`
+}
+
+// StatementAmount 对账单金额换算结果
+type StatementAmount struct {
+	OpeningBalance float64 `json:"opening_balance"`
+	TopUp          float64 `json:"top_up"`
+	Adjustment     float64 `json:"adjustment"`
+	Consumed       float64 `json:"consumed"`
+	ClosingBalance float64 `json:"closing_balance"`
+}
+
+// ParseStatementMonth 解析形如 2006-01 的月份，为空时返回当前月份
+func ParseStatementMonth(month string) (time.Time, error) {
+	if month == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local), nil
+	}
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("月份格式错误，应为 YYYY-MM")
+	}
+	return start, nil
+}
+
+// GetUserStatement 生成用户指定月份的对账单。充值取自充值订单，其他额度变动取自额度流水，消费取自消费日志，
+// 组织令牌的消费由组织额度支付，不计入个人对账单。
+// 期末余额由当前余额倒推：减去月末之后的额度流水、加回月末之后的消费，期初余额同理由期末余额倒推。
+// 月初早于第一条额度流水时，升级前的额度变动无从得知，余额标记为不可用
+func GetUserStatement(userId int, monthStart time.Time) (*Statement, error) {
+	user, err := GetUserById(userId, true)
+	if err != nil {
+		return nil, err
+	}
+	startTime := monthStart.Unix()
+	endTime := monthStart.AddDate(0, 1, 0).Unix()
+	if startTime > time.Now().Unix() {
+		return nil, errors.New("不能生成未来月份的对账单")
+	}
+
+	statement := &Statement{
+		UserId:    user.Id,
+		Username:  user.Username,
+		Month:     monthStart.Format("2006-01"),
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	var topUps []*TopUp
+	err = DB.Where("user_id = ? AND complete_time >= ? AND complete_time < ? AND status IN ?", userId, startTime, endTime,
+		[]string{common.TopUpStatusSuccess, common.TopUpStatusRefunded}).Order("complete_time asc").Find(&topUps).Error
+	if err != nil {
+		return nil, err
+	}
+	statement.TopUps = make([]*StatementTopUp, 0, len(topUps))
+	for _, topUp := range topUps {
+		statement.TopUpQuota += topUp.GetCreditedQuota()
+		statement.TopUps = append(statement.TopUps, &StatementTopUp{
+			TradeNo:      topUp.TradeNo,
+			Money:        topUp.Money,
+			Quota:        topUp.GetCreditedQuota(),
+			CompleteTime: topUp.CompleteTime,
+			Status:       topUp.Status,
+		})
+	}
+
+	ledgerSums, err := SumUserQuotaLedger(userId, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	statement.Adjustments = make([]*QuotaLedgerSum, 0, len(ledgerSums))
+	for _, sum := range ledgerSums {
+		if sum.Type == QuotaLedgerTypeTopUp {
+			continue
+		}
+		statement.Adjustments = append(statement.Adjustments, sum)
+		statement.AdjustmentQuota += sum.Quota
+	}
+
+	orgTokenIds, err := getUserOrganizationTokenIds(userId)
+	if err != nil {
+		return nil, err
+	}
+	statement.ModelSpends = make([]*StatementModelSpend, 0)
+	err = personalConsumeLogs(userId, orgTokenIds).
+		Select("model_name, count(*) as requests, sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(quota) as quota").
+		Where("created_at >= ? AND created_at < ?", startTime, endTime).
+		Group("model_name").Order("quota desc").Scan(&statement.ModelSpends).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, spend := range statement.ModelSpends {
+		statement.ConsumedQuota += spend.Quota
+	}
+
+	ledgerStartTime, err := GetQuotaLedgerStartTime()
+	if err != nil {
+		return nil, err
+	}
+	statement.BalanceAvailable = ledgerStartTime != 0 && ledgerStartTime <= startTime
+	if statement.BalanceAvailable {
+		ledgerAfter, err := SumUserQuotaLedger(userId, endTime, 0)
+		if err != nil {
+			return nil, err
+		}
+		changedAfter := 0
+		for _, sum := range ledgerAfter {
+			changedAfter += sum.Quota
+		}
+		var consumedAfter int
+		err = personalConsumeLogs(userId, orgTokenIds).Select("coalesce(sum(quota), 0)").
+			Where("created_at >= ?", endTime).Scan(&consumedAfter).Error
+		if err != nil {
+			return nil, err
+		}
+		statement.ClosingBalance = user.Quota - changedAfter + consumedAfter
+		statement.OpeningBalance = statement.ClosingBalance - statement.TopUpQuota - statement.AdjustmentQuota + statement.ConsumedQuota
+	}
+
+	statement.USD = StatementAmount{
+		OpeningBalance: quotaToUSD(statement.OpeningBalance),
+		TopUp:          quotaToUSD(statement.TopUpQuota),
+		Adjustment:     quotaToUSD(statement.AdjustmentQuota),
+		Consumed:       quotaToUSD(statement.ConsumedQuota),
+		ClosingBalance: quotaToUSD(statement.ClosingBalance),
+	}
+	statement.RMB = StatementAmount{
+		OpeningBalance: statement.USD.OpeningBalance * ratio_setting.USD2RMB,
+		TopUp:          statement.USD.TopUp * ratio_setting.USD2RMB,
+		Adjustment:     statement.USD.Adjustment * ratio_setting.USD2RMB,
+		Consumed:       statement.USD.Consumed * ratio_setting.USD2RMB,
+		ClosingBalance: statement.USD.ClosingBalance * ratio_setting.USD2RMB,
+	}
+	return statement, nil
+}
+
+// getUserOrganizationTokenIds 用户创建的组织令牌，包括已删除的令牌
+func getUserOrganizationTokenIds(userId int) (tokenIds []int, err error) {
+	err = DB.Unscoped().Model(&Token{}).Where("user_id = ? AND organization_id <> 0", userId).Pluck("id", &tokenIds).Error
+	return tokenIds, err
+}
+
+// personalConsumeLogs 用户由个人额度支付的消费日志
+func personalConsumeLogs(userId int, orgTokenIds []int) *gorm.DB {
+	tx := LOG_DB.Table("logs").Where("user_id = ? AND type = ?", userId, LogTypeConsume)
+	if len(orgTokenIds) > 0 {
+		tx = tx.Where("token_id NOT IN ?", orgTokenIds)
+	}
+	return tx
+}
+
+// quotaToUSD 将额度换算为美元，QuotaPerUnit 额度对应 1 美元
+func quotaToUSD(quota int) float64 {
+	return float64(quota) / common.QuotaPerUnit
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"errors"
+	"one-api/common"
+)
+
+// 续接对话时向前追溯的最大轮数
+const maxStoredResponseChain = 100
+
+// StoredResponse 本地保存的 Responses API 响应，用于 previous_response_id 跨渠道续接对话。
+// Input 与 Output 只保存本轮的条目，完整上下文沿 PreviousResponseId 向前追溯得到。
+type StoredResponse struct {
+	Id                 int    `json:"id"`
+	ResponseId         string `json:"response_id" gorm:"type:varchar(128);uniqueIndex"`
+	UserId             int    `json:"user_id" gorm:"index"`
+	ChannelId          int    `json:"channel_id"`
+	KeyHash            string `json:"-" gorm:"type:varchar(64)"`
+	UpstreamStored     bool   `json:"upstream_stored"` // 上游是否原生保存了该响应
+	ModelName          string `json:"model_name" gorm:"type:varchar(255)"`
+	PreviousResponseId string `json:"previous_response_id" gorm:"type:varchar(128)"`
+	Input              string `json:"input" gorm:"type:text"`
+	Output             string `json:"output" gorm:"type:text"`
+	Response           string `json:"response" gorm:"type:text"`
+	CreatedAt          int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (r *StoredResponse) Insert() error {
+	r.CreatedAt = common.GetTimestamp()
+	return DB.Create(r).Error
+}
+
+func GetStoredResponse(userId int, responseId string) (*StoredResponse, error) {
+	if responseId == "" {
+		return nil, errors.New("response id 为空")
+	}
+	var response StoredResponse
+	err := DB.Where("user_id = ? AND response_id = ?", userId, responseId).First(&response).Error
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetStoredResponseChain 从 responseId 开始沿 previous_response_id 向前追溯，按时间正序返回整条对话链
+func GetStoredResponseChain(userId int, responseId string) ([]*StoredResponse, error) {
+	chain := make([]*StoredResponse, 0)
+	for responseId != "" {
+		if len(chain) >= maxStoredResponseChain {
+			return nil, errors.New("对话轮数超出限制")
+		}
+		response, err := GetStoredResponse(userId, responseId)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, response)
+		responseId = response.PreviousResponseId
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+func DeleteStoredResponse(userId int, responseId string) (bool, error) {
+	result := DB.Where("user_id = ? AND response_id = ?", userId, responseId).Delete(&StoredResponse{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -26,6 +26,7 @@ type Token struct {
 	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
 	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group              string         `json:"group" gorm:"default:''"`
+	OrganizationId     int            `json:"organization_id" gorm:"default:0;index"` // 组织令牌，使用时从组织额度扣费
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
 }
 
@@ -4,21 +4,32 @@ import (
 	"errors"
 	"fmt"
 	"one-api/common"
+	"strings"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 type TopUp struct {
-	Id           int     `json:"id"`
-	UserId       int     `json:"user_id" gorm:"index"`
-	Amount       int64   `json:"amount"`
-	Money        float64 `json:"money"`
-	TradeNo      string  `json:"trade_no" gorm:"unique;type:varchar(255);index"`
-	CreateTime   int64   `json:"create_time"`
-	CompleteTime int64   `json:"complete_time"`
-	Status       string  `json:"status"`
+	Id            int     `json:"id"`
+	UserId        int     `json:"user_id" gorm:"index"`
+	Amount        int64   `json:"amount"`
+	Money         float64 `json:"money"`
+	TradeNo       string  `json:"trade_no" gorm:"unique;type:varchar(255);index"`
+	CreateTime    int64   `json:"create_time"`
+	CompleteTime  int64   `json:"complete_time"`
+	Status        string  `json:"status"`
+	Quota         int     `json:"quota" gorm:"type:int;default:0"`               // 实际到账额度
+	RefundedQuota int     `json:"refunded_quota" gorm:"type:int;default:0"`      // 已退款扣回的额度
+	PaymentIntent string  `json:"payment_intent" gorm:"type:varchar(255);index"` // Stripe PaymentIntent，用于退款匹配
 }
 
+var (
+	ErrTopUpNotFound = errors.New("充值订单不存在")
+	// ErrTopUpStatusInvalid 订单不处于可操作的状态，如重复通知已完成的订单
+	ErrTopUpStatusInvalid = errors.New("充值订单状态错误")
+)
+
 func (topUp *TopUp) Insert() error {
 	var err error
 	err = DB.Create(topUp).Error
@@ -51,7 +62,75 @@ func GetTopUpByTradeNo(tradeNo string) *TopUp {
 	return topUp
 }
 
-func Recharge(referenceId string, customerId string) (err error) {
+func GetTopUpByPaymentIntent(paymentIntent string) *TopUp {
+	if paymentIntent == "" {
+		return nil
+	}
+	var topUp *TopUp
+	var err error
+	err = DB.Where("payment_intent = ?", paymentIntent).First(&topUp).Error
+	if err != nil {
+		return nil
+	}
+	return topUp
+}
+
+// GetCreditedQuota 返回该订单实际到账的额度，兼容未记录 Quota 的历史订单
+func (topUp *TopUp) GetCreditedQuota() int {
+	if topUp.Quota > 0 {
+		return topUp.Quota
+	}
+	return int(topUp.Money * common.QuotaPerUnit)
+}
+
+// GetPendingQuota 返回待支付订单完成后应到账的额度，与支付回调一致：
+// Stripe 订单（订单号以 ref_ 开头）按支付金额计算，易支付订单按充值数量计算
+func (topUp *TopUp) GetPendingQuota() int {
+	if strings.HasPrefix(topUp.TradeNo, "ref_") {
+		return int(topUp.Money * common.QuotaPerUnit)
+	}
+	return int(decimal.NewFromInt(topUp.Amount).Mul(decimal.NewFromFloat(common.QuotaPerUnit)).IntPart())
+}
+
+// CompleteTopUpWithAudit 管理员手动补单：将待支付订单置为成功并为用户充值，审计日志在同一事务中写入。
+// 订单已被支付回调或其他管理员处理时返回 ErrTopUpStatusInvalid
+func CompleteTopUpWithAudit(id int, audit *AuditLog) (*TopUp, error) {
+	topUp := &TopUp{}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", id).First(topUp).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTopUpNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if topUp.Status != common.TopUpStatusPending {
+			return ErrTopUpStatusInvalid
+		}
+		topUp.Quota = topUp.GetPendingQuota()
+		topUp.CompleteTime = common.GetTimestamp()
+		topUp.Status = common.TopUpStatusSuccess
+		if err := tx.Save(topUp).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("id = ?", topUp.UserId).Update("quota", gorm.Expr("quota + ?", topUp.Quota)).Error; err != nil {
+			return err
+		}
+		if err := recordQuotaLedger(tx, topUp.UserId, QuotaLedgerTypeTopUp, topUp.Quota); err != nil {
+			return err
+		}
+		return audit.Insert(tx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("补单失败，%w", err)
+	}
+	_ = invalidateUserCache(topUp.UserId)
+
+	RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("管理员手动补单成功，订单号 %s，充值额度: %v", topUp.TradeNo, common.FormatQuota(topUp.Quota)))
+	return topUp, nil
+}
+
+func Recharge(referenceId string, customerId string, paymentIntent string) (err error) {
 	if referenceId == "" {
 		return errors.New("未提供支付单号")
 	}
@@ -66,35 +145,60 @@ func Recharge(referenceId string, customerId string) (err error) {
 
 	err = DB.Transaction(func(tx *gorm.DB) error {
 		err := tx.Set("gorm:query_option", "FOR UPDATE").Where(refCol+" = ?", referenceId).First(topUp).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTopUpNotFound
+		}
 		if err != nil {
-			return errors.New("充值订单不存在")
+			return err
 		}
 
 		if topUp.Status != common.TopUpStatusPending {
-			return errors.New("充值订单状态错误")
+			return ErrTopUpStatusInvalid
 		}
 
+		quota = topUp.Money * common.QuotaPerUnit
 		topUp.CompleteTime = common.GetTimestamp()
 		topUp.Status = common.TopUpStatusSuccess
+		topUp.Quota = int(quota)
+		topUp.PaymentIntent = paymentIntent
 		err = tx.Save(topUp).Error
 		if err != nil {
 			return err
 		}
 
-		quota = topUp.Money * common.QuotaPerUnit
 		err = tx.Model(&User{}).Where("id = ?", topUp.UserId).Updates(map[string]interface{}{"stripe_customer": customerId, "quota": gorm.Expr("quota + ?", quota)}).Error
 		if err != nil {
 			return err
 		}
 
-		return nil
+		return recordQuotaLedger(tx, topUp.UserId, QuotaLedgerTypeTopUp, int(quota))
 	})
 
 	if err != nil {
-		return errors.New("充值失败，" + err.Error())
+		return fmt.Errorf("充值失败，%w", err)
 	}
 
 	RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("使用在线充值成功，充值金额: %v，支付金额：%d", common.FormatQuota(int(quota)), topUp.Amount))
 
 	return nil
 }
+
+// GetAllTopUps 分页查询充值订单，userId 为 0、status 为空时不过滤
+func GetAllTopUps(userId int, status string, startIdx int, num int) (topUps []*TopUp, total int64, err error) {
+	query := DB.Model(&TopUp{})
+	if userId != 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err = query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("id desc").Limit(num).Offset(startIdx).Find(&topUps).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return topUps, total, nil
+}
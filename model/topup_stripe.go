@@ -0,0 +1,213 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+// StripeSubscription 记录用户的 Stripe 订阅，每个计费周期按固定额度充值
+type StripeSubscription struct {
+	Id               int    `json:"id"`
+	UserId           int    `json:"user_id" gorm:"index"`
+	ReferenceId      string `json:"reference_id" gorm:"unique;type:varchar(255)"`
+	SubscriptionId   string `json:"subscription_id" gorm:"type:varchar(255);index"`
+	CustomerId       string `json:"customer_id" gorm:"type:varchar(64)"`
+	PriceId          string `json:"price_id" gorm:"type:varchar(255)"`
+	Quota            int    `json:"quota" gorm:"type:int;default:0"` // 每个计费周期充值的额度
+	Status           string `json:"status" gorm:"type:varchar(32);index"`
+	CurrentPeriodEnd int64  `json:"current_period_end"`
+	CreateTime       int64  `json:"create_time"`
+	UpdateTime       int64  `json:"update_time"`
+}
+
+// StripeEvent 记录已处理的 Stripe Webhook 事件，保证重试时幂等
+type StripeEvent struct {
+	Id         string `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	Type       string `json:"type" gorm:"type:varchar(64)"`
+	CreateTime int64  `json:"create_time"`
+}
+
+func (sub *StripeSubscription) Insert() error {
+	sub.CreateTime = common.GetTimestamp()
+	sub.UpdateTime = sub.CreateTime
+	return DB.Create(sub).Error
+}
+
+func (sub *StripeSubscription) Update() error {
+	sub.UpdateTime = common.GetTimestamp()
+	return DB.Save(sub).Error
+}
+
+func GetStripeSubscriptionByReferenceId(referenceId string) *StripeSubscription {
+	if referenceId == "" {
+		return nil
+	}
+	var sub *StripeSubscription
+	err := DB.Where("reference_id = ?", referenceId).First(&sub).Error
+	if err != nil {
+		return nil
+	}
+	return sub
+}
+
+func GetStripeSubscriptionBySubscriptionId(subscriptionId string) *StripeSubscription {
+	if subscriptionId == "" {
+		return nil
+	}
+	var sub *StripeSubscription
+	err := DB.Where("subscription_id = ?", subscriptionId).First(&sub).Error
+	if err != nil {
+		return nil
+	}
+	return sub
+}
+
+func GetUserStripeSubscriptions(userId int) ([]*StripeSubscription, error) {
+	var subs []*StripeSubscription
+	err := DB.Where("user_id = ? AND status <> ?", userId, common.StripeSubscriptionStatusPending).Order("id desc").Find(&subs).Error
+	return subs, err
+}
+
+// ClaimStripeEvent 尝试登记一个 Stripe 事件，返回 false 表示该事件已被处理过
+func ClaimStripeEvent(eventId string, eventType string) (bool, error) {
+	if eventId == "" {
+		return false, errors.New("未提供事件ID")
+	}
+	result := DB.Where(StripeEvent{Id: eventId}).Attrs(StripeEvent{
+		Type:       eventType,
+		CreateTime: common.GetTimestamp(),
+	}).FirstOrCreate(&StripeEvent{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseStripeEvent 处理失败时移除事件记录，以便 Stripe 重试时可以再次处理
+func ReleaseStripeEvent(eventId string) error {
+	return DB.Where("id = ?", eventId).Delete(&StripeEvent{}).Error
+}
+
+// ActivateStripeSubscription 在订阅 Checkout 完成后关联 Stripe 订阅ID
+func ActivateStripeSubscription(referenceId string, subscriptionId string, customerId string) error {
+	sub := GetStripeSubscriptionByReferenceId(referenceId)
+	if sub == nil {
+		return errors.New("订阅记录不存在")
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		sub.SubscriptionId = subscriptionId
+		sub.CustomerId = customerId
+		if sub.Status == common.StripeSubscriptionStatusPending {
+			sub.Status = common.StripeSubscriptionStatusActive
+		}
+		sub.UpdateTime = common.GetTimestamp()
+		if err := tx.Save(sub).Error; err != nil {
+			return err
+		}
+		return tx.Model(&User{}).Where("id = ?", sub.UserId).Update("stripe_customer", customerId).Error
+	})
+	return err
+}
+
+// RechargeStripeInvoice 为订阅的一个已支付账单充值，账单ID作为订单号保证同一账单只充值一次
+func RechargeStripeInvoice(sub *StripeSubscription, invoiceId string, paymentIntent string, money float64, periodEnd int64) (err error) {
+	if invoiceId == "" {
+		return errors.New("未提供账单ID")
+	}
+	if GetTopUpByTradeNo(invoiceId) != nil {
+		return nil
+	}
+	now := common.GetTimestamp()
+	topUp := &TopUp{
+		UserId:        sub.UserId,
+		Amount:        int64(float64(sub.Quota) / common.QuotaPerUnit),
+		Money:         money,
+		TradeNo:       invoiceId,
+		CreateTime:    now,
+		CompleteTime:  now,
+		Status:        common.TopUpStatusSuccess,
+		Quota:         sub.Quota,
+		PaymentIntent: paymentIntent,
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(topUp).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"status":      common.StripeSubscriptionStatusActive,
+			"update_time": now,
+		}
+		if periodEnd > 0 {
+			updates["current_period_end"] = periodEnd
+		}
+		if err := tx.Model(&StripeSubscription{}).Where("id = ?", sub.Id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("id = ?", sub.UserId).Update("quota", gorm.Expr("quota + ?", sub.Quota)).Error; err != nil {
+			return err
+		}
+		return recordQuotaLedger(tx, sub.UserId, QuotaLedgerTypeTopUp, sub.Quota)
+	})
+	if err != nil {
+		return errors.New("订阅充值失败，" + err.Error())
+	}
+	_ = invalidateUserCache(sub.UserId)
+
+	RecordLog(sub.UserId, LogTypeTopup, fmt.Sprintf("订阅续费成功，充值金额: %v，支付金额：%.2f", common.FormatQuota(sub.Quota), money))
+	return nil
+}
+
+// RefundTopUp 按退款比例扣回额度，refunded/total 为 Stripe 中累计退款金额与原始金额（单位：分）
+func RefundTopUp(paymentIntent string, refunded int64, total int64) (err error) {
+	if total <= 0 {
+		return errors.New("退款金额错误")
+	}
+	topUp := &TopUp{}
+	var clawback int
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Set("gorm:query_option", "FOR UPDATE").Where("payment_intent = ?", paymentIntent).First(topUp).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTopUpNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// 已全额退款的订单再次收到通知时按累计退款金额计算，不会重复扣回
+		if topUp.Status != common.TopUpStatusSuccess && topUp.Status != common.TopUpStatusRefunded {
+			return ErrTopUpStatusInvalid
+		}
+		credited := topUp.GetCreditedQuota()
+		shouldRefund := int(float64(credited) * float64(refunded) / float64(total))
+		if shouldRefund > credited {
+			shouldRefund = credited
+		}
+		clawback = shouldRefund - topUp.RefundedQuota
+		if clawback <= 0 {
+			return nil
+		}
+		topUp.RefundedQuota = shouldRefund
+		if refunded >= total {
+			topUp.Status = common.TopUpStatusRefunded
+		}
+		if err := tx.Save(topUp).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("id = ?", topUp.UserId).Update("quota", gorm.Expr("quota - ?", clawback)).Error; err != nil {
+			return err
+		}
+		return recordQuotaLedger(tx, topUp.UserId, QuotaLedgerTypeTopUpRefund, -clawback)
+	})
+	if err != nil {
+		return fmt.Errorf("退款扣回额度失败，%w", err)
+	}
+	if clawback <= 0 {
+		return nil
+	}
+	_ = invalidateUserCache(topUp.UserId)
+
+	RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("充值订单 %s 退款，扣回额度: %v", topUp.TradeNo, common.FormatQuota(clawback)))
+	return nil
+}
@@ -0,0 +1,264 @@
+package model
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 恢复码数量
+const twoFARecoveryCodeCount = 10
+
+// UserSudoDurationSeconds 二次验证（sudo）通过后可执行敏感操作的时长
+const UserSudoDurationSeconds = 5 * 60
+
+// UserSudoSessionKey 网页会话中记录二次验证有效期的键，JWT 会话记录在 user_sessions 表中
+const UserSudoSessionKey = "sudo_expires_at"
+
+// TwoFA 用户的 TOTP 两步验证。Secret 与渠道密钥一样使用主密钥信封加密保存，
+// RecoveryCodes 只保存恢复码的哈希，以换行分隔，使用后即删除
+type TwoFA struct {
+	Id            int    `json:"id"`
+	UserId        int    `json:"user_id" gorm:"uniqueIndex"`
+	Secret        string `json:"-" gorm:"type:text;serializer:secret"`
+	RecoveryCodes string `json:"-" gorm:"type:text"`
+	Enabled       bool   `json:"enabled"`
+	LastUsedStep  int64  `json:"-" gorm:"bigint;default:0"`
+	CreatedAt     int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt     int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func (TwoFA) TableName() string {
+	return "two_fas"
+}
+
+var ErrTwoFACodeInvalid = errors.New("验证码错误或已使用")
+
+// GetTwoFAByUserId 获取用户的两步验证配置，未设置时返回 nil
+func GetTwoFAByUserId(userId int) (*TwoFA, error) {
+	var twoFA TwoFA
+	err := DB.Where("user_id = ?", userId).First(&twoFA).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &twoFA, nil
+}
+
+func getTwoFACacheKey(userId int) string {
+	return fmt.Sprintf("user_2fa:%d", userId)
+}
+
+// invalidateTwoFACache 启用或关闭两步验证后清除缓存的状态
+func invalidateTwoFACache(userId int) {
+	if !common.RedisEnabled {
+		return
+	}
+	if err := common.RedisDel(getTwoFACacheKey(userId)); err != nil {
+		common.SysError("failed to invalidate 2fa cache: " + err.Error())
+	}
+}
+
+// IsTwoFAEnabled 用户是否已启用两步验证。管理员每次请求都会检查，启用 Redis 时缓存查询结果
+func IsTwoFAEnabled(userId int) bool {
+	if common.RedisEnabled {
+		if cached, err := common.RedisGet(getTwoFACacheKey(userId)); err == nil {
+			return cached == "1"
+		}
+	}
+	var count int64
+	if err := DB.Model(&TwoFA{}).Where("user_id = ? AND enabled = ?", userId, true).Count(&count).Error; err != nil {
+		return false
+	}
+	enabled := count > 0
+	if common.RedisEnabled {
+		value := "0"
+		if enabled {
+			value = "1"
+		}
+		if err := common.RedisSet(getTwoFACacheKey(userId), value, time.Duration(common.RedisKeyCacheSeconds())*time.Second); err != nil {
+			common.SysError("failed to cache 2fa status: " + err.Error())
+		}
+	}
+	return enabled
+}
+
+// SetupTwoFA 为用户生成新的 TOTP 密钥，启用前需用验证码确认。已启用时不允许重新生成
+func SetupTwoFA(userId int) (string, error) {
+	twoFA, err := GetTwoFAByUserId(userId)
+	if err != nil {
+		return "", err
+	}
+	if twoFA != nil && twoFA.Enabled {
+		return "", errors.New("两步验证已启用，请先关闭后再重新设置")
+	}
+	secret, err := common.GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	now := common.GetTimestamp()
+	if twoFA == nil {
+		twoFA = &TwoFA{UserId: userId, CreatedAt: now}
+	}
+	twoFA.Secret = secret
+	twoFA.RecoveryCodes = ""
+	twoFA.LastUsedStep = 0
+	twoFA.UpdatedAt = now
+	if err := DB.Save(twoFA).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// EnableTwoFA 使用验证码确认密钥并启用两步验证，返回明文恢复码（仅此一次）
+func EnableTwoFA(userId int, code string) ([]string, error) {
+	twoFA, err := GetTwoFAByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+	if twoFA == nil {
+		return nil, errors.New("请先生成两步验证密钥")
+	}
+	if twoFA.Enabled {
+		return nil, errors.New("两步验证已启用")
+	}
+	if err := twoFA.verifyTOTP(code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = DB.Model(twoFA).Updates(map[string]interface{}{
+		"enabled":        true,
+		"recovery_codes": hashes,
+		"updated_at":     common.GetTimestamp(),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	invalidateTwoFACache(userId)
+	return codes, nil
+}
+
+// DisableTwoFA 关闭两步验证
+func DisableTwoFA(userId int) error {
+	if err := DB.Where("user_id = ?", userId).Delete(&TwoFA{}).Error; err != nil {
+		return err
+	}
+	invalidateTwoFACache(userId)
+	return nil
+}
+
+// RegenerateTwoFARecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func RegenerateTwoFARecoveryCodes(userId int) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	result := DB.Model(&TwoFA{}).Where("user_id = ? AND enabled = ?", userId, true).
+		Updates(map[string]interface{}{
+			"recovery_codes": hashes,
+			"updated_at":     common.GetTimestamp(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("未启用两步验证")
+	}
+	return codes, nil
+}
+
+// VerifyTwoFA 校验用户的 TOTP 验证码或恢复码
+func VerifyTwoFA(userId int, code string) error {
+	twoFA, err := GetTwoFAByUserId(userId)
+	if err != nil {
+		return err
+	}
+	if twoFA == nil || !twoFA.Enabled {
+		return errors.New("未启用两步验证")
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == common.TOTPDigits {
+		return twoFA.verifyTOTP(code)
+	}
+	return twoFA.useRecoveryCode(code)
+}
+
+// verifyTOTP 校验验证码，同一时间步的验证码只能使用一次
+func (t *TwoFA) verifyTOTP(code string) error {
+	step, ok := common.ValidateTOTPCode(t.Secret, code, time.Now())
+	if !ok {
+		return ErrTwoFACodeInvalid
+	}
+	result := DB.Model(&TwoFA{}).Where("id = ? AND last_used_step < ?", t.Id, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFACodeInvalid
+	}
+	return nil
+}
+
+func (t *TwoFA) useRecoveryCode(code string) error {
+	hash := hashRecoveryCode(code)
+	hashes := strings.Split(t.RecoveryCodes, "\n")
+	remaining := make([]string, 0, len(hashes))
+	found := false
+	for _, h := range hashes {
+		if h == "" {
+			continue
+		}
+		if h == hash && !found {
+			found = true
+			continue
+		}
+		remaining = append(remaining, h)
+	}
+	if !found {
+		return ErrTwoFACodeInvalid
+	}
+	// 以原值为条件更新，避免同一恢复码被并发使用两次
+	result := DB.Model(&TwoFA{}).Where("id = ? AND recovery_codes = ?", t.Id, t.RecoveryCodes).
+		Update("recovery_codes", strings.Join(remaining, "\n"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFACodeInvalid
+	}
+	return nil
+}
+
+// hashRecoveryCode 恢复码本身是足够长的随机串，直接使用 SHA-256，
+// 不依赖 CryptoSecret，避免未配置固定密钥时重启后恢复码失效
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return hex.EncodeToString(common.Sha256Raw([]byte(code)))
+}
+
+// generateRecoveryCodes 生成 xxxxx-xxxxx 格式的恢复码，返回明文与以换行连接的哈希
+func generateRecoveryCodes() ([]string, string, error) {
+	codes := make([]string, 0, twoFARecoveryCodeCount)
+	hashes := make([]string, 0, twoFARecoveryCodeCount)
+	for i := 0; i < twoFARecoveryCodeCount; i++ {
+		raw, err := common.GenerateRandomCharsKey(10)
+		if err != nil {
+			return nil, "", err
+		}
+		raw = strings.ToLower(raw)
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	return codes, strings.Join(hashes, "\n"), nil
+}
@@ -20,6 +20,7 @@ type User struct {
 	Username              string         `json:"username" gorm:"unique;index" validate:"max=12"`
 	DisplayName           string         `json:"display_name" gorm:"index" validate:"max=20"`
 	Role                  int            `json:"role" gorm:"type:int;default:1"`   // admin, common
+	AdminRole             string         `json:"admin_role" gorm:"type:varchar(32);default:''"` // 管理员角色，为空时拥有全部管理权限
 	Status                int            `json:"status" gorm:"type:int;default:1"` // enabled, disabled
 	Email                 string         `json:"email" gorm:"index" validate:"max=50"`
 	GitHubId              string         `json:"github_id" gorm:"column:github_id;index"`
@@ -45,19 +46,20 @@ type User struct {
 	StripeCustomer        string         `json:"stripe_customer" gorm:"type:varchar(64);column:stripe_customer;index"`
 	School                string         `json:"school" gorm:"type:varchar(100);column:school" validate:"max=100"`
 	College               string         `json:"college" gorm:"type:varchar(100);column:college" validate:"max=100"`
-	Phone                 string         `json:"phone" gorm:"type:varchar(20);column:phone;index" validate:"required,len=11"`
+	Phone                 string         `json:"phone" gorm:"type:varchar(20);column:phone;index" validate:"required,phone"`
     IsFirstUse            int            `json:"is_first_use" gorm:"type:int;default:1;column:is_first_use"` // 1: 首次使用, 0: 非首次
 }
 
 func (user *User) ToBaseUser() *UserBase {
 	cache := &UserBase{
-		Id:       user.Id,
-		Group:    user.Group,
-		Quota:    user.Quota,
-		Status:   user.Status,
-		Username: user.Username,
-		Setting:  user.Setting,
-		Email:    user.Email,
+		Id:        user.Id,
+		Group:     user.Group,
+		Quota:     user.Quota,
+		Status:    user.Status,
+		Username:  user.Username,
+		Setting:   user.Setting,
+		Email:     user.Email,
+		AdminRole: user.AdminRole,
 	}
 	return cache
 }
@@ -258,14 +260,7 @@ func HardDeleteUserById(id int) error {
 }
 
 func inviteUser(inviterId int) (err error) {
-	user, err := GetUserById(inviterId, true)
-	if err != nil {
-		return err
-	}
-	user.AffCount++
-	user.AffQuota += common.QuotaForInviter
-	user.AffHistoryQuota += common.QuotaForInviter
-	return DB.Save(user).Error
+	return DB.Model(&User{}).Where("id = ?", inviterId).Update("aff_count", gorm.Expr("aff_count + ?", 1)).Error
 }
 
 func (user *User) TransferAffQuotaToQuota(quota int) error {
@@ -300,41 +295,67 @@ func (user *User) TransferAffQuotaToQuota(quota int) error {
 	if err := tx.Save(user).Error; err != nil {
 		return err
 	}
+	if err := recordQuotaLedger(tx, user.Id, QuotaLedgerTypeAffTransfer, quota); err != nil {
+		return err
+	}
 
 	// 提交事务
 	return tx.Commit().Error
 }
 
 func (user *User) Insert(inviterId int) error {
+	return user.InsertWithInvite(inviterId, InviteSource{})
+}
+
+// InsertWithInvite 创建用户，inviterId 不为 0 时记录邀请奖励，奖励在被邀请用户充值或消耗达到门槛后发放
+func (user *User) InsertWithInvite(inviterId int, source InviteSource) error {
 	user.Quota = common.QuotaForNewUser
 	//user.SetAccessToken(common.GetUUID())
 	user.AffCode = common.GetRandomString(4)
 	user.IsFirstUse = 1 // 确保新用户注册时 is_first_use 为 1
-	result := DB.Create(user)
-	if result.Error != nil {
-		return result.Error
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return recordQuotaLedger(tx, user.Id, QuotaLedgerTypeRegister, user.Quota)
+	})
+	if err != nil {
+		return err
 	}
 	if common.QuotaForNewUser > 0 {
 		RecordLog(user.Id, LogTypeSystem, fmt.Sprintf("新用户注册赠送 %s", common.LogQuota(common.QuotaForNewUser)))
 	}
 	if inviterId != 0 {
-		if common.QuotaForInvitee > 0 {
-			_ = IncreaseUserQuota(user.Id, common.QuotaForInvitee, true)
-			RecordLog(user.Id, LogTypeSystem, fmt.Sprintf("使用邀请码赠送 %s", common.LogQuota(common.QuotaForInvitee)))
+		if err := inviteUser(inviterId); err != nil {
+			common.SysError("failed to increase aff count: " + err.Error())
 		}
-		if common.QuotaForInviter > 0 {
-			//_ = IncreaseUserQuota(inviterId, common.QuotaForInviter)
-			RecordLog(inviterId, LogTypeSystem, fmt.Sprintf("邀请用户赠送 %s", common.LogQuota(common.QuotaForInviter)))
-			_ = inviteUser(inviterId)
+		if common.QuotaForInviter > 0 || common.QuotaForInvitee > 0 {
+			if err := createInviteReward(inviterId, user.Id, source); err != nil {
+				common.SysError("failed to create invite reward: " + err.Error())
+			}
 		}
 	}
 	return nil
 }
 
 func (user *User) Update(updatePassword bool) error {
+	return user.UpdateWithAudit(nil)
+}
+
+// UpdateWithAudit 与 Update 相同，audit 不为空时与用户信息在同一事务中写入
+func (user *User) UpdateWithAudit(audit *AuditLog) error {
 	newUser := *user
 	DB.First(&user, user.Id)
-	if err := DB.Model(user).Updates(newUser).Error; err != nil {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(newUser).Error; err != nil {
+			return err
+		}
+		if audit != nil {
+			return audit.Insert(tx)
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
@@ -342,21 +363,47 @@ func (user *User) Update(updatePassword bool) error {
 	return updateUserCache(*user)
 }
 
-func (user *User) Edit(updatePassword bool) error {
-	newUser := *user
-	updates := map[string]interface{}{
-		"username":     newUser.Username,
-		"display_name": newUser.DisplayName,
-		"group":        newUser.Group,
-		"quota":        newUser.Quota,
-		"remark":       newUser.Remark,
-		"school":       newUser.School,
-		"college":      newUser.College,
-		"phone":        newUser.Phone,
+// EditUpdates 管理员编辑用户时可修改的字段
+func (user *User) EditUpdates() map[string]interface{} {
+	return map[string]interface{}{
+		"username":     user.Username,
+		"display_name": user.DisplayName,
+		"group":        user.Group,
+		"quota":        user.Quota,
+		"remark":       user.Remark,
+		"school":       user.School,
+		"college":      user.College,
+		"phone":        user.Phone,
 	}
+}
+
+func (user *User) Edit(updatePassword bool) error {
+	return user.EditWithAudit(nil)
+}
+
+// EditWithAudit 与 Edit 相同，audit 不为空时与用户信息在同一事务中写入
+func (user *User) EditWithAudit(audit *AuditLog) error {
+	updates := user.EditUpdates()
 
 	DB.First(&user, user.Id)
-	if err := DB.Model(user).Updates(updates).Error; err != nil {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// 重新读取修改前的额度，计算额度流水
+		var originQuota int
+		if err := tx.Model(&User{}).Select("quota").Where("id = ?", user.Id).Scan(&originQuota).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := recordQuotaLedger(tx, user.Id, QuotaLedgerTypeAdminAdjust, updates["quota"].(int)-originQuota); err != nil {
+			return err
+		}
+		if audit != nil {
+			return audit.Insert(tx)
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
@@ -371,6 +418,9 @@ func (user *User) Delete() error {
 	if err := DB.Delete(user).Error; err != nil {
 		return err
 	}
+	if err := RevokeUserSessions(user.Id, 0); err != nil {
+		common.SysError("failed to revoke user sessions: " + err.Error())
+	}
 
 	// 清除缓存
 	return invalidateUserCache(user.Id)
@@ -394,6 +444,10 @@ func (user *User) ValidateAndFill() (err error) {
 	if phone == "" || verificationCode == "" {
 		return errors.New("手机号或验证码为空")
 	}
+	phone, err = common.NormalizePhone(phone)
+	if err != nil {
+		return err
+	}
 	// find by phone
 	DB.Where("phone = ?", phone).First(user)
 	if user.Id == 0 {
@@ -480,6 +534,10 @@ func IsTelegramIdAlreadyTaken(telegramId string) bool {
 	return DB.Unscoped().Where("telegram_id = ?", telegramId).Find(&User{}).RowsAffected == 1
 }
 
+func IsUsernameAlreadyTaken(username string) bool {
+	return DB.Unscoped().Where("username = ?", username).Find(&User{}).RowsAffected == 1
+}
+
 func IsPhoneAlreadyTaken(phone string) bool {
 	return DB.Unscoped().Where("phone = ?", phone).Find(&User{}).RowsAffected == 1
 }
@@ -551,18 +609,6 @@ func IsAdmin(userId int) bool {
 //	return user.Status == common.UserStatusEnabled, nil
 //}
 
-func ValidateAccessToken(token string) (user *User) {
-	if token == "" {
-		return nil
-	}
-	token = strings.Replace(token, "Bearer ", "", 1)
-	user = &User{}
-	if DB.Where("access_token = ?", token).First(user).RowsAffected == 1 {
-		return user
-	}
-	return nil
-}
-
 // GetUserQuota gets quota from Redis first, falls back to DB if needed
 func GetUserQuota(id int, fromDB bool) (quota int, err error) {
 	defer func() {
@@ -818,6 +864,56 @@ func (user *User) FillUserByLinuxDOId() error {
 	return err
 }
 
+// MigrateUserPhones 将未规范化的手机号（如旧的 11 位大陆号码）迁移为 E.164 格式。
+// 无法识别或规范化后与其他用户冲突的号码保持不变并记录日志，可在修正后重启再次迁移
+func MigrateUserPhones() error {
+	var users []User
+	err := DB.Unscoped().Select("id", "phone").
+		Where("phone <> '' AND phone NOT LIKE ?", "+%").
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+	migrated := 0
+	for _, user := range users {
+		phone, err := common.NormalizePhone(user.Phone)
+		if err != nil {
+			common.SysLog(fmt.Sprintf("skip migrating phone of user %d: %s", user.Id, err.Error()))
+			continue
+		}
+		if IsPhoneAlreadyTaken(phone) {
+			common.SysLog(fmt.Sprintf("skip migrating phone of user %d: %s is already taken", user.Id, phone))
+			continue
+		}
+		if err := DB.Unscoped().Model(&User{}).Where("id = ?", user.Id).Update("phone", phone).Error; err != nil {
+			return err
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		common.SysLog(fmt.Sprintf("migrated %d user phones to E.164", migrated))
+	}
+	return nil
+}
+
+// GetUserByPhone 按手机号查找用户，不存在时返回 nil
+func GetUserByPhone(phone string) (*User, error) {
+	var user User
+	err := DB.Where("phone = ?", phone).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UnbindUserOAuth 清空用户的第三方账户字段，column 为 github_id、oidc_id 等绑定字段
+func UnbindUserOAuth(userId int, column string) error {
+	return DB.Model(&User{}).Where("id = ?", userId).Update(column, "").Error
+}
+
 func RootUserExists() bool {
 	var user User
 	err := DB.Where("role = ?", common.RoleRootUser).First(&user).Error
@@ -0,0 +1,218 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// UserSession 用户的登录设备会话。每次登录创建一条记录，刷新令牌只保存哈希，每次刷新都会轮换；
+// 已轮换的旧刷新令牌再次出现时视为泄露，整个会话随之吊销
+type UserSession struct {
+	Id                       int    `json:"id"`
+	UserId                   int    `json:"user_id" gorm:"index"`
+	DeviceName               string `json:"device_name" gorm:"type:varchar(128)"`
+	UserAgent                string `json:"user_agent" gorm:"type:varchar(512)"`
+	Ip                       string `json:"ip" gorm:"type:varchar(64)"`
+	RefreshTokenHash         string `json:"-" gorm:"type:varchar(64);uniqueIndex"`
+	PreviousRefreshTokenHash string `json:"-" gorm:"type:varchar(64);index"`
+	CreatedAt                int64  `json:"created_at" gorm:"bigint"`
+	LastUsedAt               int64  `json:"last_used_at" gorm:"bigint"`
+	ExpiresAt                int64  `json:"expires_at" gorm:"bigint;index"`
+	RevokedAt                int64  `json:"revoked_at" gorm:"bigint;default:0"`
+	SudoExpiresAt            int64  `json:"-" gorm:"bigint;default:0"`
+}
+
+// SystemAccessTokenDeviceName 个人设置中生成的系统访问令牌对应的会话，每个用户只保留一个
+const SystemAccessTokenDeviceName = "系统访问令牌"
+
+var ErrUserSessionInvalid = errors.New("refresh token 无效或已过期")
+
+func getUserSessionRevokedKey(sessionId int) string {
+	return fmt.Sprintf("user_session_revoked:%d", sessionId)
+}
+
+func hashRefreshToken(refreshToken string) string {
+	return common.GenerateHMAC(refreshToken)
+}
+
+func generateRefreshToken() (string, error) {
+	return common.GenerateRandomCharsKey(64)
+}
+
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == 0 && s.ExpiresAt > common.GetTimestamp()
+}
+
+// CreateUserSession 为用户创建新的设备会话，返回会话与明文刷新令牌
+func CreateUserSession(userId int, deviceName string, userAgent string, ip string) (*UserSession, string, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := common.GetTimestamp()
+	session := &UserSession{
+		UserId:           userId,
+		DeviceName:       common.GetStringIfEmpty(deviceName, "unknown"),
+		UserAgent:        userAgent,
+		Ip:               ip,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now + int64(common.RefreshTokenExpireSeconds),
+	}
+	if err := DB.Create(session).Error; err != nil {
+		return nil, "", err
+	}
+	return session, refreshToken, nil
+}
+
+// RotateUserSession 校验刷新令牌并轮换为新的刷新令牌，返回会话与新的明文刷新令牌
+func RotateUserSession(refreshToken string, ip string) (*UserSession, string, error) {
+	if refreshToken == "" {
+		return nil, "", ErrUserSessionInvalid
+	}
+	tokenHash := hashRefreshToken(refreshToken)
+	var session UserSession
+	err := DB.Where("refresh_token_hash = ?", tokenHash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 已轮换的旧令牌被再次使用，吊销该会话
+		if DB.Where("previous_refresh_token_hash = ?", tokenHash).First(&session).Error == nil {
+			if err := RevokeUserSession(session.UserId, session.Id); err != nil {
+				common.SysError("failed to revoke reused session: " + err.Error())
+			}
+		}
+		return nil, "", ErrUserSessionInvalid
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if !session.IsActive() {
+		return nil, "", ErrUserSessionInvalid
+	}
+	newRefreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := common.GetTimestamp()
+	// 以旧哈希为条件更新，避免并发刷新时同一令牌被轮换两次
+	result := DB.Model(&UserSession{}).
+		Where("id = ? AND refresh_token_hash = ?", session.Id, tokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":          hashRefreshToken(newRefreshToken),
+			"previous_refresh_token_hash": tokenHash,
+			"last_used_at":                now,
+			"ip":                          ip,
+		})
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, "", ErrUserSessionInvalid
+	}
+	session.LastUsedAt = now
+	session.Ip = ip
+	return &session, newRefreshToken, nil
+}
+
+// GetActiveUserSessions 返回用户当前有效的设备会话
+func GetActiveUserSessions(userId int) ([]*UserSession, error) {
+	var sessions []*UserSession
+	err := DB.Where("user_id = ? AND revoked_at = 0 AND expires_at > ?", userId, common.GetTimestamp()).
+		Order("last_used_at desc").Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeUserSession 吊销用户的指定会话
+func RevokeUserSession(userId int, sessionId int) error {
+	result := DB.Model(&UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at = 0", sessionId, userId).
+		Update("revoked_at", common.GetTimestamp())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("会话不存在或已吊销")
+	}
+	markUserSessionsRevoked(sessionId)
+	return nil
+}
+
+// RevokeUserSessions 吊销用户除 exceptSessionId 外的全部会话，exceptSessionId 为 0 时全部吊销
+func RevokeUserSessions(userId int, exceptSessionId int) error {
+	return revokeUserSessions(DB.Where("user_id = ? AND revoked_at = 0 AND id <> ?", userId, exceptSessionId))
+}
+
+// RevokeUserSessionsByDeviceName 吊销用户指定设备名称的全部会话
+func RevokeUserSessionsByDeviceName(userId int, deviceName string) error {
+	return revokeUserSessions(DB.Where("user_id = ? AND revoked_at = 0 AND device_name = ?", userId, deviceName))
+}
+
+func revokeUserSessions(query *gorm.DB) error {
+	var sessionIds []int
+	err := query.Model(&UserSession{}).Pluck("id", &sessionIds).Error
+	if err != nil || len(sessionIds) == 0 {
+		return err
+	}
+	err = DB.Model(&UserSession{}).
+		Where("id IN ?", sessionIds).
+		Update("revoked_at", common.GetTimestamp()).Error
+	if err != nil {
+		return err
+	}
+	markUserSessionsRevoked(sessionIds...)
+	return nil
+}
+
+// GrantUserSessionSudo 会话通过二次验证后，在 UserSudoDurationSeconds 内可执行敏感操作
+func GrantUserSessionSudo(sessionId int) (int64, error) {
+	expiresAt := common.GetTimestamp() + UserSudoDurationSeconds
+	err := DB.Model(&UserSession{}).Where("id = ?", sessionId).Update("sudo_expires_at", expiresAt).Error
+	return expiresAt, err
+}
+
+// IsUserSessionSudo 会话是否处于二次验证有效期内
+func IsUserSessionSudo(sessionId int) bool {
+	var session UserSession
+	if err := DB.Select("id", "sudo_expires_at").First(&session, "id = ?", sessionId).Error; err != nil {
+		return false
+	}
+	return session.SudoExpiresAt > common.GetTimestamp()
+}
+
+// markUserSessionsRevoked 在 Redis 中记录已吊销的会话，使各节点上仍未过期的访问令牌立即失效。
+// 记录只需保留到访问令牌过期为止，系统访问令牌与会话同时过期，因此按会话有效期保留
+func markUserSessionsRevoked(sessionIds ...int) {
+	if !common.RedisEnabled {
+		return
+	}
+	expiration := time.Duration(max(common.AccessTokenExpireSeconds, common.RefreshTokenExpireSeconds)) * time.Second
+	for _, sessionId := range sessionIds {
+		if err := common.RedisSet(getUserSessionRevokedKey(sessionId), "1", expiration); err != nil {
+			common.SysError("failed to mark session revoked: " + err.Error())
+		}
+	}
+}
+
+// IsUserSessionRevoked 判断访问令牌所属的会话是否已吊销。启用 Redis 时查询吊销记录，否则查询数据库
+func IsUserSessionRevoked(sessionId int) bool {
+	if common.RedisEnabled {
+		_, err := common.RedisGet(getUserSessionRevokedKey(sessionId))
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, redis.Nil) {
+			return false
+		}
+		common.SysError("failed to get session revoked mark: " + err.Error())
+	}
+	var session UserSession
+	if err := DB.Select("id", "revoked_at").First(&session, "id = ?", sessionId).Error; err != nil {
+		return true
+	}
+	return session.RevokedAt != 0
+}
@@ -48,6 +48,17 @@ func addNewRecord(type_ int, id int, value int) {
 	}
 }
 
+// GetBatchUpdateQueueSizes 返回各类批量更新队列中待写入的记录数
+func GetBatchUpdateQueueSizes() map[int]int {
+	sizes := make(map[int]int, BatchUpdateTypeCount)
+	for i := 0; i < BatchUpdateTypeCount; i++ {
+		batchUpdateLocks[i].Lock()
+		sizes[i] = len(batchUpdateStores[i])
+		batchUpdateLocks[i].Unlock()
+	}
+	return sizes
+}
+
 func batchUpdate() {
 	// check if there's any data to update
 	hasData := false
@@ -7,6 +7,7 @@ import (
 	"one-api/common"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
+	"one-api/relay/common_handler"
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -59,7 +60,7 @@ func RerankHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayI
 		TotalTokens:      aliResponse.Usage.TotalTokens,
 	}
 	rerankResponse := dto.RerankResponse{
-		Results: aliResponse.Output.Results,
+		Results: common_handler.NormalizeRerankResults(info, aliResponse.Output.Results),
 		Usage:   usage,
 	}
 
@@ -5,10 +5,12 @@ import (
 	"io"
 	"net/http"
 	"one-api/dto"
+	"one-api/relay/channel"
 	"one-api/relay/channel/claude"
 	relaycommon "one-api/relay/common"
 	"one-api/setting/model_setting"
 	"one-api/types"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +18,7 @@ import (
 const (
 	RequestModeCompletion = 1
 	RequestModeMessage    = 2
+	RequestModeConverse   = 3
 )
 
 type Adaptor struct {
@@ -23,6 +26,9 @@ type Adaptor struct {
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
+	if a.RequestMode == RequestModeConverse {
+		return channel.ConvertClaudeRequestToOpenAI(a, c, info, request)
+	}
 	c.Set("request_model", request.Model)
 	c.Set("converted_request", request)
 	return request, nil
@@ -39,7 +45,12 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 }
 
 func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
-	a.RequestMode = RequestModeMessage
+	// Claude 模型使用 InvokeModel 的 Messages 格式，其余模型统一使用 Converse API
+	if strings.HasPrefix(info.UpstreamModelName, "claude") || strings.Contains(awsModelID(info.UpstreamModelName), "anthropic.") {
+		a.RequestMode = RequestModeMessage
+	} else {
+		a.RequestMode = RequestModeConverse
+	}
 }
 
 func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
@@ -56,6 +67,16 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 		return nil, errors.New("request is nil")
 	}
 
+	if a.RequestMode == RequestModeConverse {
+		converseReq, err := requestOpenAI2Converse(*request)
+		if err != nil {
+			return nil, err
+		}
+		c.Set("request_model", request.Model)
+		c.Set("converted_request", converseReq)
+		return request, nil
+	}
+
 	var claudeReq *dto.ClaudeRequest
 	var err error
 	claudeReq, err = claude.RequestOpenAI2ClaudeMessage(*request)
@@ -77,8 +98,7 @@ func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.Rela
 }
 
 func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
-	// TODO implement me
-	return nil, errors.New("not implemented")
+	return channel.ConvertResponsesRequestToOpenAI(a, c, info, request)
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
@@ -86,6 +106,28 @@ func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, request
 }
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
+	if info.RelayFormat == relaycommon.RelayFormatOpenAIResponses {
+		return channel.DoResponsesBridgeResponse(c, info, func() (any, *types.NewAPIError) {
+			return a.doResponse(c, resp, info)
+		})
+	}
+	if info.RelayFormat == relaycommon.RelayFormatClaude && a.RequestMode == RequestModeConverse {
+		return channel.DoClaudeBridgeResponse(c, info, func() (any, *types.NewAPIError) {
+			return a.doResponse(c, resp, info)
+		})
+	}
+	return a.doResponse(c, resp, info)
+}
+
+func (a *Adaptor) doResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
+	if a.RequestMode == RequestModeConverse {
+		if info.IsStream {
+			err, usage = awsConverseStreamHandler(c, info)
+		} else {
+			err, usage = awsConverseHandler(c, info)
+		}
+		return
+	}
 	if info.IsStream {
 		err, usage = awsStreamHandler(c, resp, info, a.RequestMode)
 	} else {
@@ -0,0 +1,118 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// AwsKey 渠道密钥，支持 "ak|sk|region" 与 JSON 两种格式。
+// JSON 格式可配置 role_arn 通过 AssumeRole 获取临时凭证，
+// 或配置 web_identity_token_file 通过 AssumeRoleWithWebIdentity 获取（如 EKS IRSA）
+type AwsKey struct {
+	Region               string `json:"region"`
+	AccessKeyId          string `json:"access_key_id,omitempty"`
+	SecretAccessKey      string `json:"secret_access_key,omitempty"`
+	SessionToken         string `json:"session_token,omitempty"`
+	RoleArn              string `json:"role_arn,omitempty"`
+	ExternalId           string `json:"external_id,omitempty"`
+	RoleSessionName      string `json:"role_session_name,omitempty"`
+	WebIdentityTokenFile string `json:"web_identity_token_file,omitempty"`
+}
+
+func parseAwsKey(key string) (*AwsKey, error) {
+	key = strings.TrimSpace(key)
+	if common.IsJsonObject(key) {
+		awsKey := &AwsKey{}
+		if err := common.UnmarshalJsonStr(key, awsKey); err != nil {
+			return nil, fmt.Errorf("invalid aws key: %w", err)
+		}
+		if awsKey.Region == "" {
+			return nil, errors.New("aws region is required")
+		}
+		return awsKey, nil
+	}
+	awsSecret := strings.Split(key, "|")
+	if len(awsSecret) != 3 {
+		return nil, errors.New("invalid aws secret key")
+	}
+	return &AwsKey{
+		AccessKeyId:     awsSecret[0],
+		SecretAccessKey: awsSecret[1],
+		Region:          awsSecret[2],
+	}, nil
+}
+
+func (k *AwsKey) credentialsProvider() (aws.CredentialsProvider, error) {
+	var base aws.CredentialsProvider
+	if k.AccessKeyId != "" && k.SecretAccessKey != "" {
+		base = credentials.NewStaticCredentialsProvider(k.AccessKeyId, k.SecretAccessKey, k.SessionToken)
+	}
+
+	roleArn := k.RoleArn
+	tokenFile := k.WebIdentityTokenFile
+	// 未配置任何凭证时使用环境变量中的 Web Identity 配置
+	if base == nil && roleArn == "" && tokenFile == "" {
+		roleArn = os.Getenv("AWS_ROLE_ARN")
+		tokenFile = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+
+	switch {
+	case roleArn != "" && tokenFile != "":
+		stsClient := sts.New(sts.Options{Region: k.Region})
+		return stscreds.NewWebIdentityRoleProvider(stsClient, roleArn, stscreds.IdentityTokenFile(tokenFile), func(o *stscreds.WebIdentityRoleOptions) {
+			o.RoleSessionName = k.RoleSessionName
+		}), nil
+	case roleArn != "":
+		if base == nil {
+			return nil, errors.New("aws access key is required to assume role")
+		}
+		stsClient := sts.New(sts.Options{Region: k.Region, Credentials: aws.NewCredentialsCache(base)})
+		return stscreds.NewAssumeRoleProvider(stsClient, roleArn, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = k.RoleSessionName
+			if k.ExternalId != "" {
+				o.ExternalID = aws.String(k.ExternalId)
+			}
+		}), nil
+	case base != nil:
+		return base, nil
+	default:
+		return nil, errors.New("aws credentials not configured")
+	}
+}
+
+// 按密钥缓存客户端，使临时凭证在过期前被复用
+var awsClients sync.Map
+
+func newAwsClient(apiKey string) (*bedrockruntime.Client, error) {
+	hash := sha256.Sum256([]byte(apiKey))
+	cacheKey := hex.EncodeToString(hash[:])
+	if client, ok := awsClients.Load(cacheKey); ok {
+		return client.(*bedrockruntime.Client), nil
+	}
+	awsKey, err := parseAwsKey(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := awsKey.credentialsProvider()
+	if err != nil {
+		return nil, err
+	}
+	client := bedrockruntime.New(bedrockruntime.Options{
+		Region:      awsKey.Region,
+		Credentials: aws.NewCredentialsCache(provider),
+	})
+	awsClients.Store(cacheKey, client)
+	return client, nil
+}
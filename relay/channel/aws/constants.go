@@ -13,6 +13,15 @@ var awsModelIDMap = map[string]string{
 	"claude-3-7-sonnet-20250219": "anthropic.claude-3-7-sonnet-20250219-v1:0",
 	"claude-sonnet-4-20250514":   "anthropic.claude-sonnet-4-20250514-v1:0",
 	"claude-opus-4-20250514":     "anthropic.claude-opus-4-20250514-v1:0",
+	// 以下模型通过 Converse API 调用
+	"nova-micro":            "amazon.nova-micro-v1:0",
+	"nova-lite":             "amazon.nova-lite-v1:0",
+	"nova-pro":              "amazon.nova-pro-v1:0",
+	"llama3-3-70b-instruct": "meta.llama3-3-70b-instruct-v1:0",
+	"llama3-1-8b-instruct":  "meta.llama3-1-8b-instruct-v1:0",
+	"llama3-1-70b-instruct": "meta.llama3-1-70b-instruct-v1:0",
+	"mistral-large-2407":    "mistral.mistral-large-2407-v1:0",
+	"deepseek-r1":           "deepseek.r1-v1:0",
 }
 
 var awsModelCanCrossRegionMap = map[string]map[string]bool{
@@ -54,6 +63,33 @@ var awsModelCanCrossRegionMap = map[string]map[string]bool{
 	"anthropic.claude-opus-4-20250514-v1:0": {
 		"us": true,
 	},
+	"amazon.nova-micro-v1:0": {
+		"us": true,
+		"eu": true,
+		"ap": true,
+	},
+	"amazon.nova-lite-v1:0": {
+		"us": true,
+		"eu": true,
+		"ap": true,
+	},
+	"amazon.nova-pro-v1:0": {
+		"us": true,
+		"eu": true,
+		"ap": true,
+	},
+	"meta.llama3-3-70b-instruct-v1:0": {
+		"us": true,
+	},
+	"meta.llama3-1-8b-instruct-v1:0": {
+		"us": true,
+	},
+	"meta.llama3-1-70b-instruct-v1:0": {
+		"us": true,
+	},
+	"deepseek.r1-v1:0": {
+		"us": true,
+	},
 }
 
 var awsRegionCrossModelPrefixMap = map[string]string{
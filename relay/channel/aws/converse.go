@@ -0,0 +1,444 @@
+package aws
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/types"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	bedrockruntimeTypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/gin-gonic/gin"
+)
+
+// ConverseRequest Converse 与 ConverseStream 共用的请求参数
+type ConverseRequest struct {
+	Messages        []bedrockruntimeTypes.Message
+	System          []bedrockruntimeTypes.SystemContentBlock
+	InferenceConfig *bedrockruntimeTypes.InferenceConfiguration
+	ToolConfig      *bedrockruntimeTypes.ToolConfiguration
+}
+
+func converseImageBlock(url string) (*bedrockruntimeTypes.ContentBlockMemberImage, error) {
+	var mimeType, base64Data string
+	if strings.HasPrefix(url, "http") {
+		fileData, err := service.GetFileBase64FromUrl(url)
+		if err != nil {
+			return nil, err
+		}
+		mimeType, base64Data = fileData.MimeType, fileData.Base64Data
+	} else {
+		_, format, data, err := service.DecodeBase64ImageData(url)
+		if err != nil {
+			return nil, err
+		}
+		mimeType, base64Data = "image/"+format, data
+	}
+	imageBytes, err := base64.StdEncoding.DecodeString(base64Data)
+	if err != nil {
+		return nil, err
+	}
+	var format bedrockruntimeTypes.ImageFormat
+	switch strings.TrimPrefix(mimeType, "image/") {
+	case "png":
+		format = bedrockruntimeTypes.ImageFormatPng
+	case "jpeg", "jpg":
+		format = bedrockruntimeTypes.ImageFormatJpeg
+	case "gif":
+		format = bedrockruntimeTypes.ImageFormatGif
+	case "webp":
+		format = bedrockruntimeTypes.ImageFormatWebp
+	default:
+		return nil, fmt.Errorf("unsupported image type: %s", mimeType)
+	}
+	return &bedrockruntimeTypes.ContentBlockMemberImage{
+		Value: bedrockruntimeTypes.ImageBlock{
+			Format: format,
+			Source: &bedrockruntimeTypes.ImageSourceMemberBytes{Value: imageBytes},
+		},
+	}, nil
+}
+
+func converseMessageContent(message dto.Message) ([]bedrockruntimeTypes.ContentBlock, error) {
+	blocks := make([]bedrockruntimeTypes.ContentBlock, 0)
+	if message.IsStringContent() {
+		if text := message.StringContent(); text != "" {
+			blocks = append(blocks, &bedrockruntimeTypes.ContentBlockMemberText{Value: text})
+		}
+		return blocks, nil
+	}
+	for _, content := range message.ParseContent() {
+		switch content.Type {
+		case dto.ContentTypeText:
+			if content.Text != "" {
+				blocks = append(blocks, &bedrockruntimeTypes.ContentBlockMemberText{Value: content.Text})
+			}
+		case dto.ContentTypeImageURL:
+			image, err := converseImageBlock(content.GetImageMedia().Url)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, image)
+		}
+	}
+	return blocks, nil
+}
+
+// requestOpenAI2Converse 将 OpenAI 请求转换为 Converse 请求。
+// Converse 要求 user 与 assistant 交替出现，工具结果作为 user 消息发送，相邻同角色消息会被合并
+func requestOpenAI2Converse(request dto.GeneralOpenAIRequest) (*ConverseRequest, error) {
+	converseRequest := &ConverseRequest{}
+	appendMessage := func(role bedrockruntimeTypes.ConversationRole, blocks []bedrockruntimeTypes.ContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(converseRequest.Messages); n > 0 && converseRequest.Messages[n-1].Role == role {
+			converseRequest.Messages[n-1].Content = append(converseRequest.Messages[n-1].Content, blocks...)
+			return
+		}
+		converseRequest.Messages = append(converseRequest.Messages, bedrockruntimeTypes.Message{
+			Role:    role,
+			Content: blocks,
+		})
+	}
+
+	for _, message := range request.Messages {
+		switch message.Role {
+		case "system", "developer":
+			if text := message.StringContent(); text != "" {
+				converseRequest.System = append(converseRequest.System, &bedrockruntimeTypes.SystemContentBlockMemberText{Value: text})
+			}
+		case "tool":
+			appendMessage(bedrockruntimeTypes.ConversationRoleUser, []bedrockruntimeTypes.ContentBlock{
+				&bedrockruntimeTypes.ContentBlockMemberToolResult{
+					Value: bedrockruntimeTypes.ToolResultBlock{
+						ToolUseId: aws.String(message.ToolCallId),
+						Content: []bedrockruntimeTypes.ToolResultContentBlock{
+							&bedrockruntimeTypes.ToolResultContentBlockMemberText{Value: message.StringContent()},
+						},
+					},
+				},
+			})
+		case "assistant":
+			blocks, err := converseMessageContent(message)
+			if err != nil {
+				return nil, err
+			}
+			for _, toolCall := range message.ParseToolCalls() {
+				var input any = map[string]any{}
+				if toolCall.Function.Arguments != "" {
+					if err := common.UnmarshalJsonStr(toolCall.Function.Arguments, &input); err != nil {
+						return nil, fmt.Errorf("invalid tool call arguments: %w", err)
+					}
+				}
+				blocks = append(blocks, &bedrockruntimeTypes.ContentBlockMemberToolUse{
+					Value: bedrockruntimeTypes.ToolUseBlock{
+						ToolUseId: aws.String(toolCall.ID),
+						Name:      aws.String(toolCall.Function.Name),
+						Input:     document.NewLazyDocument(input),
+					},
+				})
+			}
+			appendMessage(bedrockruntimeTypes.ConversationRoleAssistant, blocks)
+		default:
+			blocks, err := converseMessageContent(message)
+			if err != nil {
+				return nil, err
+			}
+			appendMessage(bedrockruntimeTypes.ConversationRoleUser, blocks)
+		}
+	}
+	if len(converseRequest.Messages) == 0 {
+		return nil, errors.New("messages is empty")
+	}
+
+	inferenceConfig := &bedrockruntimeTypes.InferenceConfiguration{}
+	if maxTokens := request.GetMaxTokens(); maxTokens > 0 {
+		inferenceConfig.MaxTokens = aws.Int32(int32(maxTokens))
+	}
+	if request.MaxCompletionTokens > 0 {
+		inferenceConfig.MaxTokens = aws.Int32(int32(request.MaxCompletionTokens))
+	}
+	if request.Temperature != nil {
+		inferenceConfig.Temperature = aws.Float32(float32(*request.Temperature))
+	}
+	if request.TopP > 0 {
+		inferenceConfig.TopP = aws.Float32(float32(request.TopP))
+	}
+	switch stop := request.Stop.(type) {
+	case string:
+		inferenceConfig.StopSequences = []string{stop}
+	case []any:
+		for _, s := range stop {
+			if str, ok := s.(string); ok {
+				inferenceConfig.StopSequences = append(inferenceConfig.StopSequences, str)
+			}
+		}
+	}
+	converseRequest.InferenceConfig = inferenceConfig
+
+	if len(request.Tools) > 0 && request.ToolChoice != "none" {
+		toolConfig := &bedrockruntimeTypes.ToolConfiguration{}
+		for _, tool := range request.Tools {
+			schema := tool.Function.Parameters
+			if schema == nil {
+				schema = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			spec := bedrockruntimeTypes.ToolSpecification{
+				Name:        aws.String(tool.Function.Name),
+				InputSchema: &bedrockruntimeTypes.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(schema)},
+			}
+			if tool.Function.Description != "" {
+				spec.Description = aws.String(tool.Function.Description)
+			}
+			toolConfig.Tools = append(toolConfig.Tools, &bedrockruntimeTypes.ToolMemberToolSpec{Value: spec})
+		}
+		switch toolChoice := request.ToolChoice.(type) {
+		case string:
+			if toolChoice == "required" {
+				toolConfig.ToolChoice = &bedrockruntimeTypes.ToolChoiceMemberAny{}
+			}
+		case map[string]any:
+			if function, ok := toolChoice["function"].(map[string]any); ok {
+				if name, ok := function["name"].(string); ok {
+					toolConfig.ToolChoice = &bedrockruntimeTypes.ToolChoiceMemberTool{
+						Value: bedrockruntimeTypes.SpecificToolChoice{Name: aws.String(name)},
+					}
+				}
+			}
+		}
+		converseRequest.ToolConfig = toolConfig
+	}
+	return converseRequest, nil
+}
+
+func stopReasonConverse2OpenAI(reason bedrockruntimeTypes.StopReason) string {
+	switch reason {
+	case bedrockruntimeTypes.StopReasonToolUse:
+		return constant.FinishReasonToolCalls
+	case bedrockruntimeTypes.StopReasonMaxTokens:
+		return constant.FinishReasonLength
+	case bedrockruntimeTypes.StopReasonContentFiltered, bedrockruntimeTypes.StopReasonGuardrailIntervened:
+		return constant.FinishReasonContentFilter
+	default:
+		return constant.FinishReasonStop
+	}
+}
+
+func usageConverse2OpenAI(usage *bedrockruntimeTypes.TokenUsage) *dto.Usage {
+	if usage == nil {
+		return &dto.Usage{}
+	}
+	promptTokens := int(aws.ToInt32(usage.InputTokens))
+	completionTokens := int(aws.ToInt32(usage.OutputTokens))
+	return &dto.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
+func getConverseRequest(c *gin.Context) (*ConverseRequest, error) {
+	converseReq, ok := c.Get("converted_request")
+	if !ok {
+		return nil, errors.New("aws converse request not found")
+	}
+	request, ok := converseReq.(*ConverseRequest)
+	if !ok {
+		return nil, errors.New("invalid aws converse request")
+	}
+	return request, nil
+}
+
+func awsConverseHandler(c *gin.Context, info *relaycommon.RelayInfo) (*types.NewAPIError, *dto.Usage) {
+	awsCli, err := newAwsClient(info.ApiKey)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeChannelAwsClientError), nil
+	}
+	request, err := getConverseRequest(c)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeInvalidRequest), nil
+	}
+
+	awsResp, err := awsCli.Converse(c.Request.Context(), &bedrockruntime.ConverseInput{
+		ModelId:         aws.String(resolveAwsModelId(c.GetString("request_model"), awsCli.Options().Region)),
+		Messages:        request.Messages,
+		System:          request.System,
+		InferenceConfig: request.InferenceConfig,
+		ToolConfig:      request.ToolConfig,
+	})
+	if err != nil {
+		return types.NewError(fmt.Errorf("Converse: %w", err), types.ErrorCodeChannelAwsClientError), nil
+	}
+
+	message := dto.Message{Role: "assistant"}
+	var text strings.Builder
+	toolCalls := make([]dto.ToolCallResponse, 0)
+	if output, ok := awsResp.Output.(*bedrockruntimeTypes.ConverseOutputMemberMessage); ok {
+		for _, block := range output.Value.Content {
+			switch v := block.(type) {
+			case *bedrockruntimeTypes.ContentBlockMemberText:
+				text.WriteString(v.Value)
+			case *bedrockruntimeTypes.ContentBlockMemberToolUse:
+				arguments := "{}"
+				if v.Value.Input != nil {
+					if data, err := v.Value.Input.MarshalSmithyDocument(); err == nil {
+						arguments = string(data)
+					}
+				}
+				toolCalls = append(toolCalls, dto.ToolCallResponse{
+					ID:   aws.ToString(v.Value.ToolUseId),
+					Type: "function",
+					Function: dto.FunctionResponse{
+						Name:      aws.ToString(v.Value.Name),
+						Arguments: arguments,
+					},
+				})
+			}
+		}
+	}
+	message.SetStringContent(text.String())
+	if len(toolCalls) > 0 {
+		message.SetToolCalls(toolCalls)
+	}
+	usage := usageConverse2OpenAI(awsResp.Usage)
+	response := &dto.OpenAITextResponse{
+		Id:      helper.GetResponseID(c),
+		Model:   info.UpstreamModelName,
+		Object:  "chat.completion",
+		Created: common.GetTimestamp(),
+		Choices: []dto.OpenAITextResponseChoice{
+			{
+				Index:        0,
+				Message:      message,
+				FinishReason: stopReasonConverse2OpenAI(awsResp.StopReason),
+			},
+		},
+		Usage: *usage,
+	}
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeBadResponseBody), nil
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	_, _ = c.Writer.Write(jsonResponse)
+	return nil, usage
+}
+
+func awsConverseStreamHandler(c *gin.Context, info *relaycommon.RelayInfo) (*types.NewAPIError, *dto.Usage) {
+	awsCli, err := newAwsClient(info.ApiKey)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeChannelAwsClientError), nil
+	}
+	request, err := getConverseRequest(c)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeInvalidRequest), nil
+	}
+
+	awsResp, err := awsCli.ConverseStream(c.Request.Context(), &bedrockruntime.ConverseStreamInput{
+		ModelId:         aws.String(resolveAwsModelId(c.GetString("request_model"), awsCli.Options().Region)),
+		Messages:        request.Messages,
+		System:          request.System,
+		InferenceConfig: request.InferenceConfig,
+		ToolConfig:      request.ToolConfig,
+	})
+	if err != nil {
+		return types.NewError(fmt.Errorf("ConverseStream: %w", err), types.ErrorCodeChannelAwsClientError), nil
+	}
+	stream := awsResp.GetStream()
+	defer stream.Close()
+
+	helper.SetEventStreamHeaders(c)
+	id := helper.GetResponseID(c)
+	createdTime := common.GetTimestamp()
+	usage := &dto.Usage{}
+	// Converse 的内容块序号到 OpenAI 工具调用序号的映射
+	toolCallIndexes := make(map[int32]int)
+
+	sendDelta := func(delta dto.ChatCompletionsStreamResponseChoiceDelta, finishReason *string) {
+		response := &dto.ChatCompletionsStreamResponse{
+			Id:      id,
+			Object:  "chat.completion.chunk",
+			Created: createdTime,
+			Model:   info.UpstreamModelName,
+			Choices: []dto.ChatCompletionsStreamResponseChoice{
+				{
+					Delta:        delta,
+					FinishReason: finishReason,
+				},
+			},
+		}
+		if err := helper.ObjectData(c, response); err != nil {
+			common.LogError(c, "error rendering stream response: "+err.Error())
+		}
+	}
+
+	for event := range stream.Events() {
+		switch v := event.(type) {
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMessageStart:
+			info.SetFirstResponseTime()
+			sendDelta(dto.ChatCompletionsStreamResponseChoiceDelta{Role: "assistant"}, nil)
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberContentBlockStart:
+			start, ok := v.Value.Start.(*bedrockruntimeTypes.ContentBlockStartMemberToolUse)
+			if !ok {
+				continue
+			}
+			index := len(toolCallIndexes)
+			toolCallIndexes[aws.ToInt32(v.Value.ContentBlockIndex)] = index
+			toolCall := dto.ToolCallResponse{
+				ID:   aws.ToString(start.Value.ToolUseId),
+				Type: "function",
+				Function: dto.FunctionResponse{
+					Name: aws.ToString(start.Value.Name),
+				},
+			}
+			toolCall.SetIndex(index)
+			sendDelta(dto.ChatCompletionsStreamResponseChoiceDelta{ToolCalls: []dto.ToolCallResponse{toolCall}}, nil)
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberContentBlockDelta:
+			switch delta := v.Value.Delta.(type) {
+			case *bedrockruntimeTypes.ContentBlockDeltaMemberText:
+				var choiceDelta dto.ChatCompletionsStreamResponseChoiceDelta
+				choiceDelta.SetContentString(delta.Value)
+				sendDelta(choiceDelta, nil)
+			case *bedrockruntimeTypes.ContentBlockDeltaMemberToolUse:
+				toolCall := dto.ToolCallResponse{
+					Function: dto.FunctionResponse{
+						Arguments: aws.ToString(delta.Value.Input),
+					},
+				}
+				toolCall.SetIndex(toolCallIndexes[aws.ToInt32(v.Value.ContentBlockIndex)])
+				sendDelta(dto.ChatCompletionsStreamResponseChoiceDelta{ToolCalls: []dto.ToolCallResponse{toolCall}}, nil)
+			}
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMessageStop:
+			finishReason := stopReasonConverse2OpenAI(v.Value.StopReason)
+			sendDelta(dto.ChatCompletionsStreamResponseChoiceDelta{}, &finishReason)
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMetadata:
+			usage = usageConverse2OpenAI(v.Value.Usage)
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberContentBlockStop:
+		default:
+			common.LogError(c, fmt.Sprintf("unknown converse stream event: %T", v))
+		}
+	}
+	if err := stream.Err(); err != nil {
+		common.LogError(c, "converse stream error: "+err.Error())
+	}
+
+	if info.ShouldIncludeUsage {
+		response := helper.GenerateFinalUsageResponse(id, createdTime, info.UpstreamModelName, *usage)
+		if err := helper.ObjectData(c, response); err != nil {
+			common.LogError(c, "error rendering final usage response: "+err.Error())
+		}
+	}
+	helper.Done(c)
+	return nil, usage
+}
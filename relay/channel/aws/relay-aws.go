@@ -16,27 +16,10 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	bedrockruntimeTypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 )
 
-func newAwsClient(c *gin.Context, info *relaycommon.RelayInfo) (*bedrockruntime.Client, error) {
-	awsSecret := strings.Split(info.ApiKey, "|")
-	if len(awsSecret) != 3 {
-		return nil, errors.New("invalid aws secret key")
-	}
-	ak := awsSecret[0]
-	sk := awsSecret[1]
-	region := awsSecret[2]
-	client := bedrockruntime.New(bedrockruntime.Options{
-		Region:      region,
-		Credentials: aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(ak, sk, "")),
-	})
-
-	return client, nil
-}
-
 func wrapErr(err error) *dto.OpenAIErrorWithStatusCode {
 	return &dto.OpenAIErrorWithStatusCode{
 		StatusCode: http.StatusInternalServerError,
@@ -76,19 +59,39 @@ func awsModelID(requestModel string) string {
 	return requestModel
 }
 
+// isInferenceProfileId 判断是否已是跨区域推理配置文件 ID（如 us.amazon.nova-pro-v1:0）或 ARN
+func isInferenceProfileId(awsModelId string) bool {
+	if strings.HasPrefix(awsModelId, "arn:") {
+		return true
+	}
+	for _, prefix := range []string{"us.", "eu.", "apac.", "us-gov.", "global."} {
+		if strings.HasPrefix(awsModelId, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAwsModelId 将请求模型转换为 Bedrock 模型 ID，支持跨区域推理时使用对应的推理配置文件
+func resolveAwsModelId(requestModel string, region string) string {
+	awsModelId := awsModelID(requestModel)
+	if isInferenceProfileId(awsModelId) {
+		return awsModelId
+	}
+	regionPrefix := awsRegionPrefix(region)
+	if awsModelCanCrossRegion(awsModelId, regionPrefix) {
+		return awsModelCrossRegion(awsModelId, regionPrefix)
+	}
+	return awsModelId
+}
+
 func awsHandler(c *gin.Context, info *relaycommon.RelayInfo, requestMode int) (*types.NewAPIError, *dto.Usage) {
-	awsCli, err := newAwsClient(c, info)
+	awsCli, err := newAwsClient(info.ApiKey)
 	if err != nil {
 		return types.NewError(err, types.ErrorCodeChannelAwsClientError), nil
 	}
 
-	awsModelId := awsModelID(c.GetString("request_model"))
-
-	awsRegionPrefix := awsRegionPrefix(awsCli.Options().Region)
-	canCrossRegion := awsModelCanCrossRegion(awsModelId, awsRegionPrefix)
-	if canCrossRegion {
-		awsModelId = awsModelCrossRegion(awsModelId, awsRegionPrefix)
-	}
+	awsModelId := resolveAwsModelId(c.GetString("request_model"), awsCli.Options().Region)
 
 	awsReq := &bedrockruntime.InvokeModelInput{
 		ModelId:     aws.String(awsModelId),
@@ -128,18 +131,12 @@ func awsHandler(c *gin.Context, info *relaycommon.RelayInfo, requestMode int) (*
 }
 
 func awsStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, requestMode int) (*types.NewAPIError, *dto.Usage) {
-	awsCli, err := newAwsClient(c, info)
+	awsCli, err := newAwsClient(info.ApiKey)
 	if err != nil {
 		return types.NewError(err, types.ErrorCodeChannelAwsClientError), nil
 	}
 
-	awsModelId := awsModelID(c.GetString("request_model"))
-
-	awsRegionPrefix := awsRegionPrefix(awsCli.Options().Region)
-	canCrossRegion := awsModelCanCrossRegion(awsModelId, awsRegionPrefix)
-	if canCrossRegion {
-		awsModelId = awsModelCrossRegion(awsModelId, awsRegionPrefix)
-	}
+	awsModelId := resolveAwsModelId(c.GetString("request_model"), awsCli.Options().Region)
 
 	awsReq := &bedrockruntime.InvokeModelWithResponseStreamInput{
 		ModelId:     aws.String(awsModelId),
@@ -0,0 +1,72 @@
+package channel
+
+import (
+	"bytes"
+	"errors"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/constant"
+	"one-api/service"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConvertClaudeRequestToOpenAI 将 Claude Messages 请求转换为 OpenAI Chat Completions 请求，
+// 再交给渠道自身的 ConvertOpenAIRequest 处理，供兼容 OpenAI 请求格式的渠道实现 ConvertClaudeRequest
+func ConvertClaudeRequestToOpenAI(a Adaptor, c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	aiRequest, err := service.ClaudeToOpenAIRequest(*request, info)
+	if err != nil {
+		return nil, err
+	}
+	if info.SupportStreamOptions && aiRequest.Stream {
+		aiRequest.StreamOptions = &dto.StreamOptions{
+			IncludeUsage: true,
+		}
+	}
+	// 转换后按对话补全请求处理，渠道据此选择上游地址
+	info.RelayMode = constant.RelayModeChatCompletions
+	info.RequestURLPath = "/v1/chat/completions"
+	return a.ConvertOpenAIRequest(c, info, aiRequest)
+}
+
+// DoClaudeBridgeResponse 用于响应处理器直接输出 OpenAI 格式的渠道，将输出实时转换为 Claude 格式。
+// 已支持 RelayFormatClaude 的 OpenAI 处理器（openai.OaiStreamHandler 等）无需使用。
+func DoClaudeBridgeResponse(c *gin.Context, info *relaycommon.RelayInfo, doResponse func() (any, *types.NewAPIError)) (any, *types.NewAPIError) {
+	return doOutputBridgeResponse(c, info, &claudeOutputConverter{info: info}, doResponse)
+}
+
+type claudeOutputConverter struct {
+	info *relaycommon.RelayInfo
+}
+
+func (o *claudeOutputConverter) StreamChunk(chunk *dto.ChatCompletionsStreamResponse) []byte {
+	o.info.SendResponseCount++
+	return o.encode(service.StreamResponseOpenAI2Claude(chunk, o.info))
+}
+
+// StreamFinish 以空分片结束流。每个分片到达时即转换，不再保留最后一个分片：
+// 分片中的 finish_reason 已记录在 info.FinishReason，结束块、带用量与 stop_reason 的 message_delta
+// 及 message_stop 只依赖 ClaudeConvertInfo 的状态，与最后一个分片合并转换时的输出相同
+func (o *claudeOutputConverter) StreamFinish(usage *dto.Usage) []byte {
+	o.info.SendResponseCount++
+	o.info.ClaudeConvertInfo.Done = true
+	o.info.ClaudeConvertInfo.Usage = usage
+	return o.encode(service.StreamResponseOpenAI2Claude(&dto.ChatCompletionsStreamResponse{}, o.info))
+}
+
+func (o *claudeOutputConverter) Response(response *dto.OpenAITextResponse) ([]byte, error) {
+	return common.Marshal(service.ResponseOpenAI2Claude(response, o.info))
+}
+
+func (o *claudeOutputConverter) encode(claudeResponses []*dto.ClaudeResponse) []byte {
+	var buf bytes.Buffer
+	for _, resp := range claudeResponses {
+		sseEvent(&buf, resp.Type, resp)
+	}
+	return buf.Bytes()
+}
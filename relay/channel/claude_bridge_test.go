@@ -0,0 +1,250 @@
+package channel_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"one-api/common"
+	"one-api/dto"
+	"one-api/relay/channel"
+	"one-api/relay/channel/cohere"
+	"one-api/relay/channel/deepseek"
+	"one-api/relay/channel/ollama"
+	relaycommon "one-api/relay/common"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+var update = flag.Bool("update", false, "重新生成 testdata 中的期望结果")
+
+const claudeBridgeTestdata = "testdata/claude_bridge"
+
+// closeNotifyRecorder 为 httptest.ResponseRecorder 补充 gin 流式输出所需的 CloseNotify
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r closeNotifyRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func newClaudeBridgeContext(t *testing.T, stream bool) (*gin.Context, *closeNotifyRecorder, *relaycommon.RelayInfo, *dto.ClaudeRequest) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	recorder := &closeNotifyRecorder{httptest.NewRecorder()}
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	c.Set(common.RequestIdKey, "test")
+
+	var request dto.ClaudeRequest
+	data, err := os.ReadFile(filepath.Join(claudeBridgeTestdata, "request.claude.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	request.Stream = stream
+
+	info := relaycommon.GenRelayInfoClaude(c)
+	info.IsStream = stream
+	info.OriginModelName = request.Model
+	info.UpstreamModelName = request.Model
+	info.PromptTokens = 12
+	return c, recorder, info, &request
+}
+
+// assertGolden 按 JSON 语义比较 got 与期望文件，-update 时改为写入期望文件
+func assertGolden(t *testing.T, name string, got any) {
+	t.Helper()
+	path := filepath.Join(claudeBridgeTestdata, name)
+	gotBytes, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(path, append(gotBytes, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	wantBytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotValue, wantValue any
+	if err := json.Unmarshal(gotBytes, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(wantBytes, &wantValue); err != nil {
+		t.Fatalf("invalid fixture %s: %v", path, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("%s mismatch\n got: %s\nwant: %s", name, gotBytes, bytes.TrimSpace(wantBytes))
+	}
+}
+
+type sseEvent struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// parseClaudeOutput 将流式输出解析为事件列表，非流式输出解析为 JSON。
+// 上游未提供工具调用 ID 时由网关随机生成，比较前统一替换
+func parseClaudeOutput(t *testing.T, body []byte, stream bool) any {
+	t.Helper()
+	if !stream {
+		var response any
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatalf("invalid response %q: %v", body, err)
+		}
+		return normalizeToolIds(response)
+	}
+	var events []sseEvent
+	var event sseEvent
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.Data); err != nil {
+				t.Fatalf("invalid event data %q: %v", line, err)
+			}
+			event.Data = normalizeToolIds(event.Data)
+			events = append(events, event)
+			event = sseEvent{}
+		case strings.HasPrefix(line, ":"):
+			events = append(events, sseEvent{Event: line})
+		}
+	}
+	return events
+}
+
+func normalizeToolIds(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for key, item := range value {
+			if id, ok := item.(string); ok && key == "id" && strings.HasPrefix(id, "call_") {
+				value[key] = "call_*"
+				continue
+			}
+			value[key] = normalizeToolIds(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = normalizeToolIds(item)
+		}
+	}
+	return v
+}
+
+// bridgeAdaptor 为需要测试的桥接渠道
+type bridgeAdaptor interface {
+	ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error)
+	DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (any, *types.NewAPIError)
+}
+
+func TestConvertClaudeRequestToOpenAI(t *testing.T) {
+	tests := []struct {
+		name    string
+		adaptor bridgeAdaptor
+		stream  bool
+		golden  string
+	}{
+		{"deepseek", &deepseek.Adaptor{}, true, "deepseek.request.json"},
+		{"ollama", &ollama.Adaptor{}, true, "ollama.request.json"},
+		{"cohere", &cohere.Adaptor{}, false, "cohere.request.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, info, request := newClaudeBridgeContext(t, tt.stream)
+			info.SupportStreamOptions = true
+			converted, err := tt.adaptor.ConvertClaudeRequest(c, info, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.RequestURLPath != "/v1/chat/completions" {
+				t.Errorf("RequestURLPath = %q, want /v1/chat/completions", info.RequestURLPath)
+			}
+			assertGolden(t, tt.golden, converted)
+		})
+	}
+}
+
+func TestDoClaudeBridgeResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		adaptor  bridgeAdaptor
+		stream   bool
+		upstream string
+	}{
+		{"ollama_stream", &ollama.Adaptor{}, true, "ollama_stream.ndjson"},
+		{"ollama_stream_tool_calls", &ollama.Adaptor{}, true, "ollama_stream_tool_calls.ndjson"},
+		{"ollama", &ollama.Adaptor{}, false, "ollama.json"},
+		{"cohere_stream", &cohere.Adaptor{}, true, "cohere_stream.ndjson"},
+		{"cohere", &cohere.Adaptor{}, false, "cohere.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, recorder, info, request := newClaudeBridgeContext(t, tt.stream)
+			if _, err := tt.adaptor.ConvertClaudeRequest(c, info, request); err != nil {
+				t.Fatal(err)
+			}
+			body, err := os.ReadFile(filepath.Join(claudeBridgeTestdata, tt.upstream))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(bytes.NewReader(body)),
+			}
+			if _, apiErr := tt.adaptor.DoResponse(c, resp, info); apiErr != nil {
+				t.Fatal(apiErr)
+			}
+			if info.RelayFormat != relaycommon.RelayFormatClaude {
+				t.Errorf("RelayFormat = %v, want restored to claude", info.RelayFormat)
+			}
+			assertGolden(t, tt.name+".claude.json", parseClaudeOutput(t, recorder.Body.Bytes(), tt.stream))
+		})
+	}
+}
+
+// TestClaudeBridgeWriterPartialWrites 处理器按任意长度分段写出 SSE 时，输出应与整体写出一致，
+// 心跳注释行原样转发
+func TestClaudeBridgeWriterPartialWrites(t *testing.T) {
+	upstream, err := os.ReadFile(filepath.Join(claudeBridgeTestdata, "openai_stream.sse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{len(upstream), 1, 7, 64} {
+		c, recorder, info, _ := newClaudeBridgeContext(t, true)
+		_, apiErr := channel.DoClaudeBridgeResponse(c, info, func() (any, *types.NewAPIError) {
+			for start := 0; start < len(upstream); start += size {
+				end := min(start+size, len(upstream))
+				_, _ = c.Writer.Write(upstream[start:end])
+			}
+			return &dto.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}, nil
+		})
+		if apiErr != nil {
+			t.Fatal(apiErr)
+		}
+		assertGolden(t, "openai_stream.claude.json", parseClaudeOutput(t, recorder.Body.Bytes(), true))
+		if *update {
+			break
+		}
+	}
+}
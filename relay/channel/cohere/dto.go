@@ -37,6 +37,7 @@ type CohereRerankRequest struct {
 	Model           string `json:"model"`
 	TopN            int    `json:"top_n"`
 	ReturnDocuments bool   `json:"return_documents"`
+	MaxChunksPerDoc int    `json:"max_chunks_per_doc,omitempty"`
 }
 
 type CohereRerankResponseResult struct {
@@ -8,6 +8,7 @@ import (
 	"one-api/common"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
+	"one-api/relay/common_handler"
 	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/types"
@@ -55,14 +56,16 @@ func requestOpenAI2Cohere(textRequest dto.GeneralOpenAIRequest) *CohereRequest {
 
 func requestConvertRerank2Cohere(rerankRequest dto.RerankRequest) *CohereRerankRequest {
 	if rerankRequest.TopN == 0 {
-		rerankRequest.TopN = 1
+		rerankRequest.TopN = len(rerankRequest.Documents)
 	}
+	// 文档由网关按请求原文回填，无需上游返回
 	cohereReq := CohereRerankRequest{
 		Query:           rerankRequest.Query,
 		Documents:       rerankRequest.Documents,
 		Model:           rerankRequest.Model,
 		TopN:            rerankRequest.TopN,
-		ReturnDocuments: true,
+		ReturnDocuments: false,
+		MaxChunksPerDoc: rerankRequest.GetMaxChunksPerDoc(),
 	}
 	return &cohereReq
 }
@@ -234,7 +237,7 @@ func cohereRerankHandler(c *gin.Context, resp *http.Response, info *relaycommon.
 	}
 
 	var rerankResp dto.RerankResponse
-	rerankResp.Results = cohereResp.Results
+	rerankResp.Results = common_handler.NormalizeRerankResults(info, cohereResp.Results)
 	rerankResp.Usage = usage
 
 	jsonResponse, err := json.Marshal(rerankResp)
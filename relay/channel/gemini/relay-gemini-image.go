@@ -0,0 +1,172 @@
+package gemini
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 将 OpenAI 的 size 转换为 Gemini 的宽高比，无法识别时交给模型自行决定
+func imageSize2AspectRatio(size string) string {
+	switch size {
+	case "1024x1024", "512x512", "256x256":
+		return "1:1"
+	case "1024x1792":
+		return "9:16"
+	case "1792x1024":
+		return "16:9"
+	case "1024x1536":
+		return "2:3"
+	case "1536x1024":
+		return "3:2"
+	}
+	if strings.Contains(size, ":") {
+		return size
+	}
+	return ""
+}
+
+// convertImageRequest2GenerateContent 将 OpenAI 图片生成/编辑请求转换为 generateContent 请求，
+// 编辑时 multipart 中上传的图片作为 inlineData 附在提示词之后
+func convertImageRequest2GenerateContent(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (*GeminiChatRequest, error) {
+	parts := []GeminiPart{
+		{
+			Text: request.Prompt,
+		},
+	}
+	if info.RelayMode == relayconstant.RelayModeImagesEdits {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return nil, err
+		}
+		files := append(form.File["image"], form.File["image[]"]...)
+		if len(files) == 0 {
+			return nil, errors.New("image is required")
+		}
+		for _, fileHeader := range files {
+			file, err := fileHeader.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(file)
+			_ = file.Close()
+			if err != nil {
+				return nil, err
+			}
+			mimeType := fileHeader.Header.Get("Content-Type")
+			if !strings.HasPrefix(mimeType, "image/") {
+				mimeType = http.DetectContentType(data)
+			}
+			parts = append(parts, GeminiPart{
+				InlineData: &GeminiInlineData{
+					MimeType: mimeType,
+					Data:     base64.StdEncoding.EncodeToString(data),
+				},
+			})
+		}
+	}
+
+	geminiRequest := &GeminiChatRequest{
+		Contents: []GeminiChatContent{
+			{
+				Role:  "user",
+				Parts: parts,
+			},
+		},
+		GenerationConfig: GeminiChatGenerationConfig{
+			// 部分模型不支持只输出图片，需要同时声明 TEXT
+			ResponseModalities: []string{"TEXT", "IMAGE"},
+		},
+	}
+	// 预扣费按 n 张计算，请求同样数量的候选结果，最终按实际生成的张数结算
+	if request.N > 1 {
+		geminiRequest.GenerationConfig.CandidateCount = request.N
+	}
+	if aspectRatio := imageSize2AspectRatio(request.Size); aspectRatio != "" {
+		geminiRequest.GenerationConfig.ImageConfig = &GeminiImageConfig{
+			AspectRatio: aspectRatio,
+		}
+	}
+	return geminiRequest, nil
+}
+
+// GeminiGenerateContentImageHandler 将 generateContent 返回的内联图片转换为 OpenAI 图片响应，
+// 实际生成的图片张数写入上下文，供按张计费使用
+func GeminiGenerateContentImageHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	common.CloseResponseBodyGracefully(resp)
+
+	var geminiResponse GeminiChatResponse
+	if err = common.Unmarshal(responseBody, &geminiResponse); err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+
+	openAIResponse := dto.ImageResponse{
+		Created: common.GetTimestamp(),
+		Data:    make([]dto.ImageData, 0),
+	}
+	var text strings.Builder
+	finishReason := ""
+	for _, candidate := range geminiResponse.Candidates {
+		if candidate.FinishReason != nil {
+			finishReason = *candidate.FinishReason
+		}
+		candidateText := ""
+		for _, part := range candidate.Content.Parts {
+			if part.Thought {
+				continue
+			}
+			if part.Text != "" {
+				candidateText += part.Text
+			}
+		}
+		text.WriteString(candidateText)
+		for _, part := range candidate.Content.Parts {
+			if part.InlineData == nil || !strings.HasPrefix(part.InlineData.MimeType, "image/") {
+				continue
+			}
+			openAIResponse.Data = append(openAIResponse.Data, dto.ImageData{
+				B64Json:       part.InlineData.Data,
+				RevisedPrompt: candidateText,
+			})
+		}
+	}
+	if len(openAIResponse.Data) == 0 {
+		message := "no images generated"
+		if finishReason != "" {
+			message = fmt.Sprintf("%s, finish reason: %s", message, finishReason)
+		}
+		if text.Len() > 0 {
+			message = fmt.Sprintf("%s, response: %s", message, text.String())
+		}
+		return nil, types.NewError(errors.New(message), types.ErrorCodeBadResponseBody)
+	}
+
+	jsonResponse, err := common.Marshal(openAIResponse)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	common.IOCopyBytesGracefully(c, resp, jsonResponse)
+
+	common.SetContextKey(c, constant.ContextKeyGeneratedImageCount, len(openAIResponse.Data))
+	usage := &dto.Usage{
+		PromptTokens:     geminiResponse.UsageMetadata.PromptTokenCount,
+		CompletionTokens: geminiResponse.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      geminiResponse.UsageMetadata.TotalTokenCount,
+	}
+	return usage, nil
+}
@@ -0,0 +1,9 @@
+package jina
+
+type JinaRerankRequest struct {
+	Model           string `json:"model"`
+	Query           string `json:"query"`
+	Documents       []any  `json:"documents"`
+	TopN            int    `json:"top_n,omitempty"`
+	ReturnDocuments bool   `json:"return_documents"`
+}
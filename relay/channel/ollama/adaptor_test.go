@@ -0,0 +1,406 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const fakeOllamaKey = "ollama-key"
+
+// fakeOllama 模拟 Ollama 的 /api/chat、/api/embed、/api/tags 与 /api/pull 接口，记录收到的请求
+type fakeOllama struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	chat   *OllamaChatRequest
+	embed  *OllamaEmbeddingRequest
+	pulls  []string
+}
+
+func newFakeOllama(t *testing.T) *fakeOllama {
+	t.Helper()
+	f := &fakeOllama{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chat", f.handleChat)
+	mux.HandleFunc("POST /api/embed", f.handleEmbed)
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"models":[{"name":"llama3.1:8b","model":"llama3.1:8b"},{"name":"","model":"nomic-embed-text:latest"}]}`))
+	})
+	mux.HandleFunc("POST /api/pull", f.handlePull)
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+fakeOllamaKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.server.Close)
+
+	if service.GetHttpClient() == nil {
+		service.InitHttpClient()
+	}
+	return f
+}
+
+func (f *fakeOllama) handleChat(w http.ResponseWriter, r *http.Request) {
+	var request OllamaChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.chat = &request
+	f.mu.Unlock()
+	if request.Model == "missing" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, `{"error":"model \"%s\" not found, try pulling it first"}`, request.Model)
+		return
+	}
+
+	var lines []string
+	if len(request.Tools) > 0 {
+		lines = []string{
+			`{"model":"llama3.1","message":{"role":"assistant","content":"","thinking":"Need the weather."},"done":false}`,
+			`{"model":"llama3.1","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"location":"Paris"}}}]},"done":false}`,
+			`{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":120,"eval_count":20}`,
+		}
+	} else {
+		lines = []string{
+			`{"model":"llama3.1","message":{"role":"assistant","content":"Hello"},"done":false}`,
+			`{"model":"llama3.1","message":{"role":"assistant","content":" there"},"done":false}`,
+			`{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":10,"eval_count":2}`,
+		}
+	}
+	if !request.Stream {
+		_, _ = w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"Hello there","thinking":"Greeting."},"done":true,"done_reason":"stop","prompt_eval_count":10,"eval_count":3}`))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for _, line := range lines {
+		_, _ = w.Write([]byte(line + "\n"))
+		w.(http.Flusher).Flush()
+	}
+}
+
+func (f *fakeOllama) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var request OllamaEmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.embed = &request
+	f.mu.Unlock()
+	embeddings := make([][]float64, len(request.Input))
+	for i := range request.Input {
+		embeddings[i] = []float64{float64(i), 0.5}
+	}
+	_ = json.NewEncoder(w).Encode(OllamaEmbeddingResponse{
+		Model:           request.Model,
+		Embedding:       embeddings,
+		PromptEvalCount: 7,
+	})
+}
+
+func (f *fakeOllama) handlePull(w http.ResponseWriter, r *http.Request) {
+	var request OllamaPullRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.pulls = append(f.pulls, request.Model)
+	f.mu.Unlock()
+	if request.Model == "missing" {
+		_, _ = w.Write([]byte(`{"status":"pulling manifest"}` + "\n" + `{"error":"pull model manifest: file does not exist"}` + "\n"))
+		return
+	}
+	_, _ = w.Write([]byte(strings.Join([]string{
+		`{"status":"pulling manifest"}`,
+		`{"status":"pulling 8eeb52dfb3bb","digest":"sha256:8eeb52dfb3bb","total":4661211424,"completed":2330605712}`,
+		`{"status":"verifying sha256 digest"}`,
+		`{"status":"success"}`,
+	}, "\n") + "\n"))
+}
+
+// relayOllama 按 relay 的调用顺序转换请求、请求假服务并处理响应，返回写给客户端的内容
+func relayOllama(t *testing.T, f *fakeOllama, info *relaycommon.RelayInfo, convert func(a *Adaptor, c *gin.Context) (any, error)) (*httptest.ResponseRecorder, any) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Request.Header.Set("Content-Type", "application/json")
+	info.BaseUrl = f.server.URL
+	info.ApiKey = fakeOllamaKey
+	info.StartTime = time.Unix(1751356800, 0)
+
+	a := &Adaptor{}
+	converted, err := convert(a, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := a.DoRequest(c, info, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, apiErr := a.DoResponse(c, resp.(*http.Response), info)
+	if apiErr != nil {
+		t.Fatal(apiErr)
+	}
+	return recorder, usage
+}
+
+func parseChatChunks(t *testing.T, body []byte) []dto.ChatCompletionsStreamResponse {
+	t.Helper()
+	var chunks []dto.ChatCompletionsStreamResponse
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk dto.ChatCompletionsStreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func TestOllamaChatStreamWithToolsAndImages(t *testing.T) {
+	f := newFakeOllama(t)
+	request := &dto.GeneralOpenAIRequest{}
+	err := json.Unmarshal([]byte(`{
+		"model": "llama3.1",
+		"stream": true,
+		"max_tokens": 64,
+		"stop": ["END"],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}],
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "Weather for this place?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}}
+			]},
+			{"role": "assistant", "content": "", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"location\":\"Paris\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "18°C"}
+		]
+	}`), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &relaycommon.RelayInfo{
+		RelayMode:          relayconstant.RelayModeChatCompletions,
+		IsStream:           true,
+		ShouldIncludeUsage: true,
+		UpstreamModelName:  "llama3.1",
+	}
+	recorder, usage := relayOllama(t, f, info, func(a *Adaptor, c *gin.Context) (any, error) {
+		return a.ConvertOpenAIRequest(c, info, request)
+	})
+
+	sent := f.chat
+	if !sent.Stream || sent.Options.NumPredict != 64 || len(sent.Options.Stop) != 1 || sent.Options.Stop[0] != "END" {
+		t.Fatalf("ollama request = %+v, options = %+v", sent, sent.Options)
+	}
+	if len(sent.Messages) != 3 || sent.Messages[0].Content != "Weather for this place?" ||
+		len(sent.Messages[0].Images) != 1 || sent.Messages[0].Images[0] != "iVBORw0KGgo=" {
+		t.Fatalf("user message = %+v", sent.Messages[0])
+	}
+	if len(sent.Messages[1].ToolCalls) != 1 || string(sent.Messages[1].ToolCalls[0].Function.Arguments) != `{"location":"Paris"}` {
+		t.Fatalf("assistant message = %+v", sent.Messages[1])
+	}
+	if sent.Messages[2].ToolName != "get_weather" {
+		t.Fatalf("tool message = %+v, want tool_name get_weather", sent.Messages[2])
+	}
+
+	chunks := parseChatChunks(t, recorder.Body.Bytes())
+	var reasoning string
+	var toolCalls []dto.ToolCallResponse
+	var finishReason string
+	var finalUsage *dto.Usage
+	for _, chunk := range chunks {
+		if chunk.Usage != nil {
+			finalUsage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			reasoning += choice.Delta.GetReasoningContent()
+			toolCalls = append(toolCalls, choice.Delta.ToolCalls...)
+			if choice.FinishReason != nil {
+				finishReason = *choice.FinishReason
+			}
+		}
+	}
+	if reasoning != "Need the weather." {
+		t.Errorf("reasoning = %q", reasoning)
+	}
+	if len(toolCalls) != 1 || toolCalls[0].Function.Name != "get_weather" || toolCalls[0].Function.Arguments != `{"location":"Paris"}` {
+		t.Errorf("tool calls = %+v", toolCalls)
+	}
+	if finishReason != "tool_calls" {
+		t.Errorf("finish_reason = %q, want tool_calls", finishReason)
+	}
+	if finalUsage == nil || finalUsage.PromptTokens != 120 || finalUsage.CompletionTokens != 20 {
+		t.Errorf("usage chunk = %+v", finalUsage)
+	}
+	if u := usage.(*dto.Usage); u.PromptTokens != 120 || u.CompletionTokens != 20 {
+		t.Errorf("usage = %+v", u)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(recorder.Body.String()), "data: [DONE]") {
+		t.Errorf("stream not terminated with [DONE]: %q", recorder.Body.String())
+	}
+}
+
+func TestOllamaChatStreamLength(t *testing.T) {
+	f := newFakeOllama(t)
+	request := &dto.GeneralOpenAIRequest{Model: "llama3.1", Stream: true}
+	request.Messages = []dto.Message{{Role: "user", Content: "Hi"}}
+	info := &relaycommon.RelayInfo{
+		RelayMode:         relayconstant.RelayModeChatCompletions,
+		IsStream:          true,
+		UpstreamModelName: "llama3.1",
+	}
+	recorder, _ := relayOllama(t, f, info, func(a *Adaptor, c *gin.Context) (any, error) {
+		return a.ConvertOpenAIRequest(c, info, request)
+	})
+	var content, finishReason string
+	for _, chunk := range parseChatChunks(t, recorder.Body.Bytes()) {
+		for _, choice := range chunk.Choices {
+			content += choice.Delta.GetContentString()
+			if choice.FinishReason != nil {
+				finishReason = *choice.FinishReason
+			}
+		}
+	}
+	if content != "Hello there" || finishReason != "length" {
+		t.Fatalf("content = %q, finish_reason = %q", content, finishReason)
+	}
+}
+
+func TestOllamaChatNonStream(t *testing.T) {
+	f := newFakeOllama(t)
+	request := &dto.GeneralOpenAIRequest{Model: "llama3.1"}
+	request.Messages = []dto.Message{{Role: "user", Content: "Hi"}}
+	info := &relaycommon.RelayInfo{
+		RelayMode:         relayconstant.RelayModeChatCompletions,
+		UpstreamModelName: "llama3.1",
+	}
+	recorder, usage := relayOllama(t, f, info, func(a *Adaptor, c *gin.Context) (any, error) {
+		return a.ConvertOpenAIRequest(c, info, request)
+	})
+	if f.chat.Stream {
+		t.Fatal("non-stream request sent with stream=true")
+	}
+	var response dto.OpenAITextResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Choices) != 1 || response.Choices[0].Message.StringContent() != "Hello there" ||
+		response.Choices[0].Message.ReasoningContent != "Greeting." || response.Choices[0].FinishReason != "stop" {
+		t.Fatalf("response = %s", recorder.Body.String())
+	}
+	if u := usage.(*dto.Usage); u.PromptTokens != 10 || u.CompletionTokens != 3 || u.TotalTokens != 13 {
+		t.Fatalf("usage = %+v", u)
+	}
+}
+
+func TestOllamaChatUpstreamError(t *testing.T) {
+	f := newFakeOllama(t)
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	info := &relaycommon.RelayInfo{
+		RelayMode:         relayconstant.RelayModeChatCompletions,
+		UpstreamModelName: "missing",
+		BaseUrl:           f.server.URL,
+		ApiKey:            fakeOllamaKey,
+	}
+	a := &Adaptor{}
+	resp, err := a.DoRequest(c, info, strings.NewReader(`{"model":"missing","messages":[],"stream":false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := resp.(*http.Response).StatusCode; status != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", status)
+	}
+}
+
+func TestOllamaEmbeddings(t *testing.T) {
+	f := newFakeOllama(t)
+	info := &relaycommon.RelayInfo{
+		RelayMode:         relayconstant.RelayModeEmbeddings,
+		UpstreamModelName: "nomic-embed-text",
+		PromptTokens:      3,
+	}
+	recorder, usage := relayOllama(t, f, info, func(a *Adaptor, c *gin.Context) (any, error) {
+		return a.ConvertEmbeddingRequest(c, info, dto.EmbeddingRequest{
+			Model: "nomic-embed-text",
+			Input: []any{"first", "second"},
+		})
+	})
+	if f.embed == nil || len(f.embed.Input) != 2 || f.embed.Input[1] != "second" {
+		t.Fatalf("embed request = %+v", f.embed)
+	}
+	var response dto.OpenAIEmbeddingResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data) != 2 || response.Data[1].Index != 1 || response.Data[1].Embedding[0] != 1 {
+		t.Fatalf("embedding response = %s", recorder.Body.String())
+	}
+	if u := usage.(*dto.Usage); u.PromptTokens != 7 {
+		t.Fatalf("usage = %+v, want prompt_eval_count 7", u)
+	}
+}
+
+func TestFetchModels(t *testing.T) {
+	f := newFakeOllama(t)
+	models, err := FetchModels(context.Background(), f.server.URL+"/", fakeOllamaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || models[0] != "llama3.1:8b" || models[1] != "nomic-embed-text:latest" {
+		t.Fatalf("models = %v", models)
+	}
+	if _, err := FetchModels(context.Background(), f.server.URL, "wrong-key"); err == nil {
+		t.Fatal("expected error for unauthorized request")
+	}
+}
+
+func TestPullModel(t *testing.T) {
+	f := newFakeOllama(t)
+	if err := PullModel(context.Background(), f.server.URL, fakeOllamaKey, "llama3.1:8b"); err != nil {
+		t.Fatal(err)
+	}
+	err := PullModel(context.Background(), f.server.URL, fakeOllamaKey, "missing")
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Fatalf("err = %v, want pull error", err)
+	}
+	if err := PullModel(context.Background(), f.server.URL, fakeOllamaKey, ""); err == nil {
+		t.Fatal("expected error for empty model")
+	}
+	if len(f.pulls) != 2 || f.pulls[0] != "llama3.1:8b" {
+		t.Fatalf("pulls = %v", f.pulls)
+	}
+}
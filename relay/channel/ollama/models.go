@@ -0,0 +1,111 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/service"
+	"strings"
+)
+
+func newOllamaRequest(ctx context.Context, method string, url string, key string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	return req, nil
+}
+
+// FetchModels 通过 /api/tags 获取 Ollama 本地已有的模型
+func FetchModels(ctx context.Context, baseURL string, key string) ([]string, error) {
+	req, err := newOllamaRequest(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/tags", key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	var tags OllamaTagsResponse
+	if err = common.Unmarshal(body, &tags); err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		name := m.Name
+		if name == "" {
+			name = m.Model
+		}
+		models = append(models, name)
+	}
+	return models, nil
+}
+
+// PullModel 调用 /api/pull 拉取模型，逐行读取进度直到拉取完成或出错
+func PullModel(ctx context.Context, baseURL string, key string, modelName string) error {
+	if modelName == "" {
+		return errors.New("model is empty")
+	}
+	payload, err := common.Marshal(OllamaPullRequest{
+		Model:  modelName,
+		Stream: true,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := newOllamaRequest(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/api/pull", key, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	// 拉取大模型耗时较长，不使用 RelayTimeout，由 ctx 控制取消
+	client := &http.Client{Transport: service.GetHttpClient().Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var status OllamaPullResponse
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		status = OllamaPullResponse{}
+		if err = common.UnmarshalJsonStr(line, &status); err != nil {
+			return err
+		}
+		if status.Error != "" {
+			return fmt.Errorf("ollama error: %s", status.Error)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if status.Status != "success" {
+		return fmt.Errorf("pull model %s not finished, last status: %s", modelName, status.Status)
+	}
+	return nil
+}
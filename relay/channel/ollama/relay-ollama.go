@@ -1,12 +1,17 @@
 package ollama
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/types"
 	"strings"
@@ -14,60 +19,299 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func requestOpenAI2Ollama(request dto.GeneralOpenAIRequest) (*OllamaRequest, error) {
-	messages := make([]dto.Message, 0, len(request.Messages))
+func requestOpenAI2Ollama(request dto.GeneralOpenAIRequest) (*OllamaChatRequest, error) {
+	messages := make([]OllamaChatMessage, 0, len(request.Messages))
+	// Ollama 的工具结果通过 tool_name 关联，需要根据 tool_call_id 找到对应的函数名
+	toolNames := make(map[string]string)
 	for _, message := range request.Messages {
-		if !message.IsStringContent() {
-			mediaMessages := message.ParseContent()
-			for j, mediaMessage := range mediaMessages {
-				if mediaMessage.Type == dto.ContentTypeImageURL {
-					imageUrl := mediaMessage.GetImageMedia()
-					// check if not base64
-					if strings.HasPrefix(imageUrl.Url, "http") {
-						fileData, err := service.GetFileBase64FromUrl(imageUrl.Url)
-						if err != nil {
-							return nil, err
-						}
-						imageUrl.Url = fmt.Sprintf("data:%s;base64,%s", fileData.MimeType, fileData.Base64Data)
+		ollamaMessage := OllamaChatMessage{
+			Role:     message.Role,
+			Thinking: message.ReasoningContent,
+		}
+		if message.IsStringContent() {
+			ollamaMessage.Content = message.StringContent()
+		} else {
+			var content strings.Builder
+			for _, mediaMessage := range message.ParseContent() {
+				switch mediaMessage.Type {
+				case dto.ContentTypeText:
+					content.WriteString(mediaMessage.Text)
+				case dto.ContentTypeImageURL:
+					image, err := imageUrl2Base64(mediaMessage.GetImageMedia().Url)
+					if err != nil {
+						return nil, err
 					}
-					mediaMessage.ImageUrl = imageUrl
-					mediaMessages[j] = mediaMessage
+					ollamaMessage.Images = append(ollamaMessage.Images, image)
 				}
 			}
-			message.SetMediaContent(mediaMessages)
+			ollamaMessage.Content = content.String()
 		}
-		messages = append(messages, dto.Message{
-			Role:       message.Role,
-			Content:    message.Content,
-			ToolCalls:  message.ToolCalls,
-			ToolCallId: message.ToolCallId,
-		})
+		for _, toolCall := range message.ParseToolCalls() {
+			arguments := json.RawMessage(toolCall.Function.Arguments)
+			if !json.Valid(arguments) {
+				arguments = json.RawMessage("{}")
+			}
+			ollamaMessage.ToolCalls = append(ollamaMessage.ToolCalls, OllamaToolCall{
+				Function: OllamaToolCallFunction{
+					Name:      toolCall.Function.Name,
+					Arguments: arguments,
+				},
+			})
+			toolNames[toolCall.ID] = toolCall.Function.Name
+		}
+		if message.Role == "tool" {
+			ollamaMessage.ToolName = toolNames[message.ToolCallId]
+		}
+		messages = append(messages, ollamaMessage)
 	}
-	str, ok := request.Stop.(string)
-	var Stop []string
-	if ok {
-		Stop = []string{str}
-	} else {
-		Stop, _ = request.Stop.([]string)
-	}
-	return &OllamaRequest{
-		Model:            request.Model,
-		Messages:         messages,
-		Stream:           request.Stream,
+
+	options := &Options{
+		Seed:             int(request.Seed),
 		Temperature:      request.Temperature,
-		Seed:             request.Seed,
-		Topp:             request.TopP,
 		TopK:             request.TopK,
-		Stop:             Stop,
-		Tools:            request.Tools,
-		MaxTokens:        request.MaxTokens,
-		ResponseFormat:   request.ResponseFormat,
+		TopP:             request.TopP,
 		FrequencyPenalty: request.FrequencyPenalty,
 		PresencePenalty:  request.PresencePenalty,
-		Prompt:           request.Prompt,
-		StreamOptions:    request.StreamOptions,
-		Suffix:           request.Suffix,
-	}, nil
+		NumPredict:       request.GetMaxTokens(),
+	}
+	if request.MaxCompletionTokens > 0 {
+		options.NumPredict = int(request.MaxCompletionTokens)
+	}
+	switch stop := request.Stop.(type) {
+	case string:
+		options.Stop = []string{stop}
+	case []any:
+		for _, s := range stop {
+			if str, ok := s.(string); ok {
+				options.Stop = append(options.Stop, str)
+			}
+		}
+	}
+
+	ollamaRequest := &OllamaChatRequest{
+		Model:    request.Model,
+		Messages: messages,
+		Tools:    request.Tools,
+		Options:  options,
+		Stream:   request.Stream,
+	}
+	if request.ResponseFormat != nil {
+		switch request.ResponseFormat.Type {
+		case "json_object":
+			ollamaRequest.Format = "json"
+		case "json_schema":
+			if request.ResponseFormat.JsonSchema != nil {
+				ollamaRequest.Format = request.ResponseFormat.JsonSchema.Schema
+			}
+		}
+	}
+	if request.ReasoningEffort != "" {
+		ollamaRequest.Think = true
+	}
+	return ollamaRequest, nil
+}
+
+// imageUrl2Base64 Ollama 只接受不带 data URI 前缀的 base64 图片
+func imageUrl2Base64(url string) (string, error) {
+	if strings.HasPrefix(url, "http") {
+		fileData, err := service.GetFileBase64FromUrl(url)
+		if err != nil {
+			return "", err
+		}
+		return fileData.Base64Data, nil
+	}
+	if idx := strings.Index(url, ","); strings.HasPrefix(url, "data:") && idx >= 0 {
+		return url[idx+1:], nil
+	}
+	return url, nil
+}
+
+func toolCallsOllama2OpenAI(toolCalls []OllamaToolCall, startIndex int) []dto.ToolCallResponse {
+	result := make([]dto.ToolCallResponse, 0, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		arguments := string(toolCall.Function.Arguments)
+		if arguments == "" || arguments == "null" {
+			arguments = "{}"
+		}
+		openaiToolCall := dto.ToolCallResponse{
+			ID:   fmt.Sprintf("call_%s", common.GetUUID()),
+			Type: "function",
+			Function: dto.FunctionResponse{
+				Name:      toolCall.Function.Name,
+				Arguments: arguments,
+			},
+		}
+		openaiToolCall.SetIndex(startIndex + i)
+		result = append(result, openaiToolCall)
+	}
+	return result
+}
+
+func finishReasonOllama2OpenAI(doneReason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return constant.FinishReasonToolCalls
+	}
+	if doneReason == "length" {
+		return constant.FinishReasonLength
+	}
+	return constant.FinishReasonStop
+}
+
+func ollamaUsage(response *OllamaChatResponse, responseText string, info *relaycommon.RelayInfo) *dto.Usage {
+	if response.PromptEvalCount == 0 && response.EvalCount == 0 {
+		return service.ResponseText2Usage(responseText, info.UpstreamModelName, info.PromptTokens)
+	}
+	return &dto.Usage{
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+		TotalTokens:      response.PromptEvalCount + response.EvalCount,
+	}
+}
+
+func ollamaChatStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	defer common.CloseResponseBodyGracefully(resp)
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, helper.InitialScannerBufferSize), helper.MaxScannerBufferSize)
+	scanner.Split(bufio.ScanLines)
+
+	id := helper.GetResponseID(c)
+	createdTime := info.StartTime.Unix()
+	var responseText strings.Builder
+	var usage *dto.Usage
+	toolCallCount := 0
+	started := false
+
+	for scanner.Scan() {
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+		var ollamaResponse OllamaChatResponse
+		if err := common.UnmarshalJsonStr(data, &ollamaResponse); err != nil {
+			common.LogError(c, "error unmarshalling stream response: "+err.Error())
+			continue
+		}
+		if ollamaResponse.Error != "" {
+			if !started {
+				return nil, types.NewError(fmt.Errorf("ollama error: %s", ollamaResponse.Error), types.ErrorCodeBadResponseBody)
+			}
+			common.LogError(c, "ollama stream error: "+ollamaResponse.Error)
+			break
+		}
+		if !started {
+			started = true
+			info.SetFirstResponseTime()
+			helper.SetEventStreamHeaders(c)
+		}
+
+		delta := dto.ChatCompletionsStreamResponseChoiceDelta{Role: "assistant"}
+		hasDelta := false
+		if ollamaResponse.Message.Content != "" {
+			delta.SetContentString(ollamaResponse.Message.Content)
+			responseText.WriteString(ollamaResponse.Message.Content)
+			hasDelta = true
+		}
+		if ollamaResponse.Message.Thinking != "" {
+			delta.SetReasoningContent(ollamaResponse.Message.Thinking)
+			responseText.WriteString(ollamaResponse.Message.Thinking)
+			hasDelta = true
+		}
+		if len(ollamaResponse.Message.ToolCalls) > 0 {
+			delta.ToolCalls = toolCallsOllama2OpenAI(ollamaResponse.Message.ToolCalls, toolCallCount)
+			toolCallCount += len(ollamaResponse.Message.ToolCalls)
+			hasDelta = true
+		}
+		if hasDelta {
+			response := &dto.ChatCompletionsStreamResponse{
+				Id:      id,
+				Object:  "chat.completion.chunk",
+				Created: createdTime,
+				Model:   info.UpstreamModelName,
+				Choices: []dto.ChatCompletionsStreamResponseChoice{{Delta: delta}},
+			}
+			if err := helper.ObjectData(c, response); err != nil {
+				common.LogError(c, "error rendering stream response: "+err.Error())
+			}
+		}
+
+		if ollamaResponse.Done {
+			usage = ollamaUsage(&ollamaResponse, responseText.String(), info)
+			finishReason := finishReasonOllama2OpenAI(ollamaResponse.DoneReason, toolCallCount > 0)
+			stopResponse := helper.GenerateStopResponse(id, createdTime, info.UpstreamModelName, finishReason)
+			if err := helper.ObjectData(c, stopResponse); err != nil {
+				common.LogError(c, "error rendering stop response: "+err.Error())
+			}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		common.LogError(c, "error scanning stream response: "+err.Error())
+	}
+	if !started {
+		return nil, types.NewError(errors.New("empty response from ollama"), types.ErrorCodeBadResponse)
+	}
+	if usage == nil {
+		usage = service.ResponseText2Usage(responseText.String(), info.UpstreamModelName, info.PromptTokens)
+	}
+	if info.ShouldIncludeUsage {
+		response := helper.GenerateFinalUsageResponse(id, createdTime, info.UpstreamModelName, *usage)
+		if err := helper.ObjectData(c, response); err != nil {
+			common.LogError(c, "error rendering final usage response: "+err.Error())
+		}
+	}
+	helper.Done(c)
+	return usage, nil
+}
+
+func ollamaChatHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	common.CloseResponseBodyGracefully(resp)
+	var ollamaResponse OllamaChatResponse
+	if err = common.Unmarshal(responseBody, &ollamaResponse); err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	if ollamaResponse.Error != "" {
+		return nil, types.NewError(fmt.Errorf("ollama error: %s", ollamaResponse.Error), types.ErrorCodeBadResponseBody)
+	}
+
+	message := dto.Message{
+		Role:             "assistant",
+		ReasoningContent: ollamaResponse.Message.Thinking,
+	}
+	message.SetStringContent(ollamaResponse.Message.Content)
+	toolCalls := toolCallsOllama2OpenAI(ollamaResponse.Message.ToolCalls, 0)
+	if len(toolCalls) > 0 {
+		// 非流式响应中的工具调用不带 index
+		for i := range toolCalls {
+			toolCalls[i].Index = nil
+		}
+		message.SetToolCalls(toolCalls)
+	}
+	usage := ollamaUsage(&ollamaResponse, ollamaResponse.Message.Content+ollamaResponse.Message.Thinking, info)
+	response := &dto.OpenAITextResponse{
+		Id:      helper.GetResponseID(c),
+		Model:   info.UpstreamModelName,
+		Object:  "chat.completion",
+		Created: info.StartTime.Unix(),
+		Choices: []dto.OpenAITextResponseChoice{
+			{
+				Index:        0,
+				Message:      message,
+				FinishReason: finishReasonOllama2OpenAI(ollamaResponse.DoneReason, len(toolCalls) > 0),
+			},
+		},
+		Usage: *usage,
+	}
+	jsonResponse, err := common.Marshal(response)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	common.IOCopyBytesGracefully(c, resp, jsonResponse)
+	return usage, nil
 }
 
 func requestOpenAI2Embeddings(request dto.EmbeddingRequest) *OllamaEmbeddingRequest {
@@ -98,16 +342,23 @@ func ollamaEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *h
 	if ollamaEmbeddingResponse.Error != "" {
 		return nil, types.NewError(fmt.Errorf("ollama error: %s", ollamaEmbeddingResponse.Error), types.ErrorCodeBadResponseBody)
 	}
-	flattenedEmbeddings := flattenEmbeddings(ollamaEmbeddingResponse.Embedding)
-	data := make([]dto.OpenAIEmbeddingResponseItem, 0, 1)
-	data = append(data, dto.OpenAIEmbeddingResponseItem{
-		Embedding: flattenedEmbeddings,
-		Object:    "embedding",
-	})
+	// 每个输入对应一个向量
+	data := make([]dto.OpenAIEmbeddingResponseItem, 0, len(ollamaEmbeddingResponse.Embedding))
+	for i, embedding := range ollamaEmbeddingResponse.Embedding {
+		data = append(data, dto.OpenAIEmbeddingResponseItem{
+			Embedding: embedding,
+			Object:    "embedding",
+			Index:     i,
+		})
+	}
+	promptTokens := info.PromptTokens
+	if ollamaEmbeddingResponse.PromptEvalCount > 0 {
+		promptTokens = ollamaEmbeddingResponse.PromptEvalCount
+	}
 	usage := &dto.Usage{
-		TotalTokens:      info.PromptTokens,
+		TotalTokens:      promptTokens,
 		CompletionTokens: 0,
-		PromptTokens:     info.PromptTokens,
+		PromptTokens:     promptTokens,
 	}
 	embeddingResponse := &dto.OpenAIEmbeddingResponse{
 		Object: "list",
@@ -122,11 +373,3 @@ func ollamaEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *h
 	common.IOCopyBytesGracefully(c, resp, doResponseBody)
 	return usage, nil
 }
-
-func flattenEmbeddings(embeddings [][]float64) []float64 {
-	flattened := []float64{}
-	for _, row := range embeddings {
-		flattened = append(flattened, row...)
-	}
-	return flattened
-}
@@ -0,0 +1,120 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/types"
+	"sort"
+	"strings"
+)
+
+// Azure /openai/v1 统一路径的版本标识，此时模型（部署名）放在请求体中而非路径中
+const (
+	azureApiVersionV1      = "v1"
+	azureApiVersionPreview = "preview"
+)
+
+type azureDeployment struct {
+	Name       string
+	ApiVersion string
+}
+
+// IsUnifiedPath 是否使用 /openai/v1 统一路径
+func (d azureDeployment) IsUnifiedPath() bool {
+	return d.ApiVersion == azureApiVersionV1 || d.ApiVersion == azureApiVersionPreview
+}
+
+// getAzureDeployment 返回模型对应的部署名与 API 版本。
+// 渠道设置中的部署映射优先，其次为请求或渠道配置的版本，最后为默认版本
+func getAzureDeployment(info *relaycommon.RelayInfo) azureDeployment {
+	deployment := azureDeployment{
+		Name:       info.UpstreamModelName,
+		ApiVersion: info.ApiVersion,
+	}
+	// 2025年5月10日后创建的渠道不移除.
+	if info.ChannelCreateTime < constant.AzureNoRemoveDotTime {
+		deployment.Name = strings.Replace(deployment.Name, ".", "", -1)
+	}
+	for _, modelName := range []string{info.UpstreamModelName, info.OriginModelName} {
+		if mapped, ok := info.ChannelSetting.AzureDeployments[modelName]; ok {
+			if mapped.Deployment != "" {
+				deployment.Name = mapped.Deployment
+			}
+			if mapped.ApiVersion != "" {
+				deployment.ApiVersion = mapped.ApiVersion
+			}
+			break
+		}
+	}
+	if deployment.ApiVersion == "" {
+		deployment.ApiVersion = constant.AzureDefaultAPIVersion
+	}
+	return deployment
+}
+
+func getAzureRequestURL(info *relaycommon.RelayInfo) string {
+	deployment := getAzureDeployment(info)
+	// https://learn.microsoft.com/en-us/azure/cognitive-services/openai/chatgpt-quickstart?pivots=rest-api&tabs=command-line#rest-api
+	task := strings.TrimPrefix(strings.Split(info.RequestURLPath, "?")[0], "/v1/")
+
+	// responses API 仅提供统一路径
+	if info.RelayMode == relayconstant.RelayModeResponses {
+		return fmt.Sprintf("/openai/v1/responses?api-version=%s", azureApiVersionPreview)
+	}
+	if info.RelayMode == relayconstant.RelayModeRealtime {
+		return fmt.Sprintf("/openai/realtime?deployment=%s&api-version=%s", deployment.Name, deployment.ApiVersion)
+	}
+	// https://learn.microsoft.com/en-us/azure/ai-foundry/openai/api-version-lifecycle
+	if deployment.IsUnifiedPath() {
+		requestURL := fmt.Sprintf("/openai/v1/%s", task)
+		if deployment.ApiVersion == azureApiVersionPreview {
+			requestURL += "?api-version=" + azureApiVersionPreview
+		}
+		return requestURL
+	}
+	// https://github.com/songquanpeng/one-api/issues/67
+	return fmt.Sprintf("/openai/deployments/%s/%s?api-version=%s", deployment.Name, task, deployment.ApiVersion)
+}
+
+// azureRequestModel 统一路径下请求体中的模型需替换为部署名
+func azureRequestModel(info *relaycommon.RelayInfo, model string) string {
+	if info.ChannelType != constant.ChannelTypeAzure {
+		return model
+	}
+	deployment := getAzureDeployment(info)
+	if deployment.IsUnifiedPath() || info.RelayMode == relayconstant.RelayModeResponses {
+		return deployment.Name
+	}
+	return model
+}
+
+// azureContentFilterError 提示词被 Azure 内容过滤拦截时返回 content_filter 错误
+func azureContentFilterError(results []dto.PromptFilterResult) *types.NewAPIError {
+	categories := make([]string, 0)
+	for _, result := range results {
+		for category, filterResult := range result.ContentFilterResults {
+			if !filterResult.Filtered {
+				continue
+			}
+			if filterResult.Severity != "" {
+				category = fmt.Sprintf("%s(%s)", category, filterResult.Severity)
+			}
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	sort.Strings(categories)
+	message := "prompt was filtered by Azure content management policy: " + strings.Join(categories, ", ")
+	return types.WithOpenAIError(types.OpenAIError{
+		Message: message,
+		Type:    "invalid_request_error",
+		Param:   "prompt",
+		Code:    string(types.ErrorCodeContentFiltered),
+	}, http.StatusBadRequest)
+}
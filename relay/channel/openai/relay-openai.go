@@ -197,6 +197,11 @@ func OpenaiHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Respo
 	if simpleResponse.Error != nil && simpleResponse.Error.Type != "" {
 		return nil, types.WithOpenAIError(*simpleResponse.Error, resp.StatusCode)
 	}
+	if info.ChannelType == constant.ChannelTypeAzure {
+		if filterErr := azureContentFilterError(simpleResponse.PromptFilterResults); filterErr != nil {
+			return nil, filterErr
+		}
+	}
 
 	forceFormat := false
 	if info.ChannelSetting.ForceFormat {
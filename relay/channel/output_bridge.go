@@ -0,0 +1,184 @@
+package channel
+
+import (
+	"bytes"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/types"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// outputConverter 将渠道处理器写出的 OpenAI Chat Completions 输出转换为客户端请求的格式
+type outputConverter interface {
+	// StreamChunk 转换一个流式分片，返回需要写出的 SSE 数据
+	StreamChunk(chunk *dto.ChatCompletionsStreamResponse) []byte
+	// StreamFinish 在处理器返回后调用，usage 为处理器统计的最终用量
+	StreamFinish(usage *dto.Usage) []byte
+	// Response 转换非流式响应
+	Response(response *dto.OpenAITextResponse) ([]byte, error)
+}
+
+// doOutputBridgeResponse 执行 doResponse 期间替换 c.Writer，并临时将 RelayFormat 设为 OpenAI，
+// 使处理器按 OpenAI 格式输出，再由 converter 实时转换。
+func doOutputBridgeResponse(c *gin.Context, info *relaycommon.RelayInfo, converter outputConverter, doResponse func() (any, *types.NewAPIError)) (usage any, err *types.NewAPIError) {
+	writer := &outputBridgeWriter{
+		ResponseWriter: c.Writer,
+		converter:      converter,
+		stream:         info.IsStream,
+		status:         http.StatusOK,
+	}
+	relayFormat := info.RelayFormat
+	c.Writer = writer
+	info.RelayFormat = relaycommon.RelayFormatOpenAI
+	defer func() {
+		c.Writer = writer.ResponseWriter
+		info.RelayFormat = relayFormat
+	}()
+
+	usage, err = doResponse()
+	if err != nil {
+		return nil, err
+	}
+	oaiUsage, _ := usage.(*dto.Usage)
+	if writer.stream {
+		writer.writeEvents(converter.StreamFinish(oaiUsage))
+	} else if convertErr := writer.finishNonStream(); convertErr != nil {
+		return nil, types.NewError(convertErr, types.ErrorCodeBadResponseBody)
+	}
+	return usage, nil
+}
+
+// outputBridgeWriter 截获处理器写出的 OpenAI 响应。
+// 流式响应按行解析 SSE 并逐个分片立即转换，结束事件所需的状态由 converter 自行保存，
+// 不必为等待最终用量而延迟输出最后一个分片；非流式响应缓存后整体转换。
+type outputBridgeWriter struct {
+	gin.ResponseWriter
+	converter outputConverter
+	stream    bool
+	status    int
+	buf       bytes.Buffer
+}
+
+func (w *outputBridgeWriter) WriteHeader(code int) {
+	w.status = code
+	if w.stream {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *outputBridgeWriter) WriteHeaderNow() {
+	if w.stream {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *outputBridgeWriter) Write(data []byte) (int, error) {
+	w.buf.Write(data)
+	if w.stream {
+		w.processLines()
+	}
+	return len(data), nil
+}
+
+func (w *outputBridgeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *outputBridgeWriter) Flush() {
+	if w.stream {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *outputBridgeWriter) Written() bool {
+	if w.stream {
+		return w.ResponseWriter.Written()
+	}
+	return w.buf.Len() > 0
+}
+
+func (w *outputBridgeWriter) Status() int {
+	return w.status
+}
+
+func (w *outputBridgeWriter) Size() int {
+	if w.stream {
+		return w.ResponseWriter.Size()
+	}
+	return w.buf.Len()
+}
+
+// processLines 处理缓冲区中完整的 SSE 行，未以换行结尾的部分留待下次写入
+func (w *outputBridgeWriter) processLines() {
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// 不完整的行放回缓冲区
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "" || data == "[DONE]" {
+				continue
+			}
+			var chunk dto.ChatCompletionsStreamResponse
+			if err := common.UnmarshalJsonStr(data, &chunk); err != nil {
+				common.SysError("error unmarshalling stream response: " + err.Error())
+				continue
+			}
+			w.writeEvents(w.converter.StreamChunk(&chunk))
+		case strings.HasPrefix(line, ":"):
+			// 心跳等注释行原样转发
+			w.writeEvents([]byte(line + "\n\n"))
+		}
+	}
+}
+
+func (w *outputBridgeWriter) writeEvents(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	_, _ = w.ResponseWriter.Write(data)
+	w.ResponseWriter.Flush()
+}
+
+func (w *outputBridgeWriter) finishNonStream() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var openAIResponse dto.OpenAITextResponse
+	if err := common.Unmarshal(w.buf.Bytes(), &openAIResponse); err != nil {
+		return err
+	}
+	responseBody, err := w.converter.Response(&openAIResponse)
+	if err != nil {
+		return err
+	}
+	w.ResponseWriter.Header().Set("Content-Type", "application/json")
+	w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(responseBody)))
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err = w.ResponseWriter.Write(responseBody)
+	return err
+}
+
+// sseEvent 按 "event: <type>\ndata: <json>\n\n" 格式编码事件
+func sseEvent(buf *bytes.Buffer, eventType string, data any) {
+	jsonData, err := common.Marshal(data)
+	if err != nil {
+		common.SysError("error marshalling stream response: " + err.Error())
+		return
+	}
+	buf.WriteString("event: " + eventType + "\n")
+	buf.WriteString("data: ")
+	buf.Write(jsonData)
+	buf.WriteString("\n\n")
+}
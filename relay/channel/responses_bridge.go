@@ -0,0 +1,79 @@
+package channel
+
+import (
+	"bytes"
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/constant"
+	"one-api/service"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConvertResponsesRequestToOpenAI 将 Responses API 请求转换为 Chat Completions 请求，
+// 再交给渠道自身的 ConvertOpenAIRequest 处理，供原生不支持 Responses API 的渠道实现 ConvertOpenAIResponsesRequest
+func ConvertResponsesRequestToOpenAI(a Adaptor, c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
+	aiRequest, err := service.ResponsesRequestToOpenAIRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	info.RelayMode = constant.RelayModeChatCompletions
+	info.RequestURLPath = "/v1/chat/completions"
+	return a.ConvertOpenAIRequest(c, info, aiRequest)
+}
+
+// DoResponsesBridgeResponse 将渠道处理器输出的 OpenAI Chat Completions 响应实时转换为 Responses API 格式
+func DoResponsesBridgeResponse(c *gin.Context, info *relaycommon.RelayInfo, doResponse func() (any, *types.NewAPIError)) (any, *types.NewAPIError) {
+	converter := &responsesOutputConverter{
+		info:      info,
+		id:        fmt.Sprintf("resp_%s", c.GetString(common.RequestIdKey)),
+		createdAt: int(info.StartTime.Unix()),
+	}
+	converter.stream = service.NewResponsesStreamConverter(converter.id, info.UpstreamModelName, converter.createdAt)
+	return doOutputBridgeResponse(c, info, converter, doResponse)
+}
+
+type responsesOutputConverter struct {
+	info      *relaycommon.RelayInfo
+	id        string
+	createdAt int
+	started   bool
+	stream    *service.ResponsesStreamConverter
+}
+
+func (o *responsesOutputConverter) StreamChunk(chunk *dto.ChatCompletionsStreamResponse) []byte {
+	var events []*dto.ResponsesStreamResponse
+	if !o.started {
+		o.started = true
+		events = append(events, o.stream.Start()...)
+	}
+	return o.encode(append(events, o.stream.Convert(chunk)...))
+}
+
+func (o *responsesOutputConverter) StreamFinish(usage *dto.Usage) []byte {
+	var events []*dto.ResponsesStreamResponse
+	if !o.started {
+		o.started = true
+		events = append(events, o.stream.Start()...)
+	}
+	events = append(events, o.stream.Finish(usage)...)
+	o.info.ResponsesUsageInfo.Response = o.stream.Response()
+	return o.encode(events)
+}
+
+func (o *responsesOutputConverter) Response(response *dto.OpenAITextResponse) ([]byte, error) {
+	responsesResponse := service.ResponseOpenAI2Responses(response, o.id, o.createdAt)
+	o.info.ResponsesUsageInfo.Response = responsesResponse
+	return common.Marshal(responsesResponse)
+}
+
+func (o *responsesOutputConverter) encode(events []*dto.ResponsesStreamResponse) []byte {
+	var buf bytes.Buffer
+	for _, event := range events {
+		sseEvent(&buf, event.Type, event)
+	}
+	return buf.Bytes()
+}
@@ -11,6 +11,16 @@ type SFMeta struct {
 	Tokens SFTokens `json:"tokens"`
 }
 
+type SFRerankRequest struct {
+	Model           string `json:"model"`
+	Query           string `json:"query"`
+	Documents       []any  `json:"documents"`
+	TopN            int    `json:"top_n,omitempty"`
+	ReturnDocuments bool   `json:"return_documents"`
+	MaxChunksPerDoc int    `json:"max_chunks_per_doc,omitempty"`
+	OverlapTokens   int    `json:"overlap_tokens,omitempty"`
+}
+
 type SFRerankResponse struct {
 	Results []dto.RerankResponseResult `json:"results"`
 	Meta    SFMeta                     `json:"meta"`
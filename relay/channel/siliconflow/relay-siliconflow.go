@@ -7,6 +7,7 @@ import (
 	"one-api/common"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
+	"one-api/relay/common_handler"
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -29,7 +30,7 @@ func siliconflowRerankHandler(c *gin.Context, info *relaycommon.RelayInfo, resp
 		TotalTokens:      siliconflowResp.Meta.Tokens.InputTokens + siliconflowResp.Meta.Tokens.OutputTokens,
 	}
 	rerankResp := &dto.RerankResponse{
-		Results: siliconflowResp.Results,
+		Results: common_handler.NormalizeRerankResults(info, siliconflowResp.Results),
 		Usage:   *usage,
 	}
 
@@ -10,6 +10,9 @@ var ModelList = []string{
 	//"gemini-1.5-pro-001", "gemini-1.5-flash-001", "gemini-pro", "gemini-pro-vision",
 
 	"meta/llama3-405b-instruct-maas",
+	"meta/llama-3.3-70b-instruct-maas",
+	"meta/llama-4-maverick-17b-128e-instruct-maas",
+	"meta/llama-4-scout-17b-16e-instruct-maas",
 }
 
 var ChannelName = "vertex-ai"
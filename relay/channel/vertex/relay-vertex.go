@@ -1,19 +1,58 @@
 package vertex
 
-import "one-api/common"
+import (
+	"one-api/common"
+	"slices"
+	"strings"
+)
 
+// 合作伙伴模型只在部分区域提供，按模型名前缀匹配，第一个区域为默认选择
+var partnerModelRegions = []struct {
+	prefix  string
+	regions []string
+}{
+	{"claude-opus-4", []string{"us-east5", "europe-west1", "asia-east1", "global"}},
+	{"claude-sonnet-4", []string{"us-east5", "europe-west1", "asia-east1", "global"}},
+	{"claude-3-7-sonnet", []string{"us-east5", "europe-west1", "global"}},
+	{"claude-3-5-sonnet", []string{"us-east5", "europe-west1", "asia-southeast1"}},
+	{"claude-3-5-haiku", []string{"us-east5"}},
+	{"claude-3-opus", []string{"us-east5"}},
+	{"claude-3-haiku", []string{"us-east5", "europe-west1", "asia-southeast1"}},
+	{"claude-3-sonnet", []string{"us-east5"}},
+	{"llama-4", []string{"us-east5"}},
+	{"llama-3.3", []string{"us-central1"}},
+	{"llama-3.2", []string{"us-central1"}},
+	{"llama-3.1", []string{"us-central1"}},
+	{"llama3", []string{"us-central1"}},
+}
+
+func getPartnerModelRegions(modelName string) []string {
+	modelName = strings.TrimPrefix(modelName, "meta/")
+	for _, item := range partnerModelRegions {
+		if strings.HasPrefix(modelName, item.prefix) {
+			return item.regions
+		}
+	}
+	return nil
+}
+
+// GetModelRegion 返回模型使用的区域。渠道中为模型单独配置的区域优先；
+// 否则使用默认区域，若合作伙伴模型在默认区域不可用，则改用该模型支持的区域
 func GetModelRegion(other string, localModelName string) string {
+	region := other
 	// if other is json string
 	if common.IsJsonObject(other) {
 		m, err := common.StrToMap(other)
 		if err != nil {
 			return other // return original if parsing fails
 		}
-		if m[localModelName] != nil {
-			return m[localModelName].(string)
-		} else {
-			return m["default"].(string)
+		if r, ok := m[localModelName].(string); ok && r != "" {
+			return r
 		}
+		region, _ = m["default"].(string)
+	}
+	if regions := getPartnerModelRegions(localModelName); len(regions) > 0 && !slices.Contains(regions, region) {
+		return regions[0]
 	}
-	return other
+	return region
 }
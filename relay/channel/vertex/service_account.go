@@ -5,17 +5,17 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
-	"errors"
-	"github.com/bytedance/gopkg/cache/asynccache"
-	"github.com/golang-jwt/jwt"
+	"fmt"
 	"net/http"
 	"net/url"
 	relaycommon "one-api/relay/common"
 	"one-api/service"
 	"strings"
-
-	"fmt"
+	"sync"
 	"time"
+
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/sync/singleflight"
 )
 
 type Credentials struct {
@@ -26,34 +26,49 @@ type Credentials struct {
 	ClientID     string `json:"client_id"`
 }
 
-var Cache = asynccache.NewAsyncCache(asynccache.Options{
-	RefreshDuration: time.Minute * 35,
-	EnableExpire:    true,
-	ExpireDuration:  time.Minute * 30,
-	Fetcher: func(key string) (interface{}, error) {
-		return nil, errors.New("not found")
-	},
-})
+// 提前刷新的时间，避免令牌在请求过程中过期
+const accessTokenRefreshMargin = 5 * time.Minute
+
+type cachedAccessToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+var (
+	// 按服务账号缓存访问令牌，同一渠道的多个密钥互不影响
+	accessTokenCache sync.Map
+	accessTokenGroup singleflight.Group
+)
+
+func (c Credentials) cacheKey() string {
+	return c.ClientEmail + "/" + c.PrivateKeyID
+}
 
 func getAccessToken(a *Adaptor, info *relaycommon.RelayInfo) (string, error) {
-	cacheKey := fmt.Sprintf("access-token-%d", info.ChannelId)
-	val, err := Cache.Get(cacheKey)
-	if err == nil {
-		return val.(string), nil
+	cacheKey := a.AccountCredentials.cacheKey()
+	if val, ok := accessTokenCache.Load(cacheKey); ok {
+		cached := val.(*cachedAccessToken)
+		if time.Now().Add(accessTokenRefreshMargin).Before(cached.expiresAt) {
+			return cached.token, nil
+		}
 	}
 
-	signedJWT, err := createSignedJWT(a.AccountCredentials.ClientEmail, a.AccountCredentials.PrivateKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to create signed JWT: %w", err)
-	}
-	newToken, err := exchangeJwtForAccessToken(signedJWT, info)
-	if err != nil {
-		return "", fmt.Errorf("failed to exchange JWT for access token: %w", err)
-	}
-	if err := Cache.SetDefault(cacheKey, newToken); err {
+	val, err, _ := accessTokenGroup.Do(cacheKey, func() (interface{}, error) {
+		signedJWT, err := createSignedJWT(a.AccountCredentials.ClientEmail, a.AccountCredentials.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create signed JWT: %w", err)
+		}
+		newToken, err := exchangeJwtForAccessToken(signedJWT, info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to exchange JWT for access token: %w", err)
+		}
+		accessTokenCache.Store(cacheKey, newToken)
 		return newToken, nil
+	})
+	if err != nil {
+		return "", err
 	}
-	return newToken, nil
+	return val.(*cachedAccessToken).token, nil
 }
 
 func createSignedJWT(email, privateKeyPEM string) (string, error) {
@@ -97,7 +112,7 @@ func createSignedJWT(email, privateKeyPEM string) (string, error) {
 	return signedToken, nil
 }
 
-func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (string, error) {
+func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (*cachedAccessToken, error) {
 
 	authURL := "https://www.googleapis.com/oauth2/v4/token"
 	data := url.Values{}
@@ -109,7 +124,7 @@ func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (s
 	if info.ChannelSetting.Proxy != "" {
 		client, err = service.NewProxyHttpClient(info.ChannelSetting.Proxy)
 		if err != nil {
-			return "", fmt.Errorf("new proxy http client failed: %w", err)
+			return nil, fmt.Errorf("new proxy http client failed: %w", err)
 		}
 	} else {
 		client = service.GetHttpClient()
@@ -117,18 +132,27 @@ func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (s
 
 	resp, err := client.PostForm(authURL, data)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var result map[string]interface{}
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return nil, err
 	}
-
-	if accessToken, ok := result["access_token"].(string); ok {
-		return accessToken, nil
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("failed to get access token: %s %s", result.Error, result.ErrorDescription)
 	}
-
-	return "", fmt.Errorf("failed to get access token: %v", result)
+	if result.ExpiresIn <= 0 {
+		result.ExpiresIn = 3600
+	}
+	return &cachedAccessToken{
+		token:     result.AccessToken,
+		expiresAt: time.Now().Add(time.Duration(result.ExpiresIn) * time.Second),
+	}, nil
 }
@@ -28,6 +28,7 @@ const (
 type ClaudeConvertInfo struct {
 	LastMessagesType string
 	Index            int
+	ToolCallIndex    int // 当前内容块对应的 OpenAI 工具调用序号
 	Usage            *dto.Usage
 	FinishReason     string
 	Done             bool
@@ -47,6 +48,7 @@ const (
 type RerankerInfo struct {
 	Documents       []any
 	ReturnDocuments bool
+	TopN            int
 }
 
 type BuildInToolInfo struct {
@@ -57,6 +59,7 @@ type BuildInToolInfo struct {
 
 type ResponsesUsageInfo struct {
 	BuiltInTools map[string]*BuildInToolInfo
+	Response     *dto.OpenAIResponsesResponse // 最终的响应对象，用于本地保存
 }
 
 type RelayInfo struct {
@@ -68,6 +71,7 @@ type RelayInfo struct {
 	UsingGroup        string // 使用的分组
 	UserGroup         string // 用户所在分组
 	TokenUnlimited    bool
+	OrganizationId    int // 令牌所属组织，非 0 时从组织额度扣费
 	StartTime         time.Time
 	FirstResponseTime time.Time
 	isFirstResponse   bool
@@ -152,6 +156,7 @@ func GenRelayInfoRerank(c *gin.Context, req *dto.RerankRequest) *RelayInfo {
 	info.RerankerInfo = &RerankerInfo{
 		Documents:       req.Documents,
 		ReturnDocuments: req.GetReturnDocuments(),
+		TopN:            req.TopN,
 	}
 	return info
 }
@@ -241,6 +246,7 @@ func GenRelayInfo(c *gin.Context) *RelayInfo {
 		UsingGroup:        common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
 		UserGroup:         common.GetContextKeyString(c, constant.ContextKeyUserGroup),
 		TokenUnlimited:    tokenUnlimited,
+		OrganizationId:    common.GetContextKeyInt(c, constant.ContextKeyTokenOrganizationId),
 		StartTime:         startTime,
 		FirstResponseTime: startTime.Add(-time.Second),
 		OriginModelName:   common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
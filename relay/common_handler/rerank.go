@@ -9,6 +9,7 @@ import (
 	"one-api/relay/channel/xinference"
 	relaycommon "one-api/relay/common"
 	"one-api/types"
+	"sort"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,26 +32,10 @@ func RerankHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Respo
 		}
 		jinaRespResults := make([]dto.RerankResponseResult, len(xinRerankResponse.Results))
 		for i, result := range xinRerankResponse.Results {
-			respResult := dto.RerankResponseResult{
+			jinaRespResults[i] = dto.RerankResponseResult{
 				Index:          result.Index,
 				RelevanceScore: result.RelevanceScore,
 			}
-			if info.ReturnDocuments {
-				var document any
-				if result.Document != nil {
-					if doc, ok := result.Document.(string); ok {
-						if doc == "" {
-							document = info.Documents[result.Index]
-						} else {
-							document = doc
-						}
-					} else {
-						document = result.Document
-					}
-				}
-				respResult.Document = document
-			}
-			jinaRespResults[i] = respResult
 		}
 		jinaResp = dto.RerankResponse{
 			Results: jinaRespResults,
@@ -66,8 +51,37 @@ func RerankHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Respo
 		}
 		jinaResp.Usage.PromptTokens = jinaResp.Usage.TotalTokens
 	}
+	jinaResp.Results = NormalizeRerankResults(info, jinaResp.Results)
 
 	c.Writer.Header().Set("Content-Type", "application/json")
 	c.JSON(http.StatusOK, jinaResp)
 	return &jinaResp.Usage, nil
 }
+
+// NormalizeRerankResults 统一各渠道的重排序结果：按相关性降序排列并截取 top_n，
+// return_documents 为 true 时以请求中的原文档填充 document，否则省略
+func NormalizeRerankResults(info *relaycommon.RelayInfo, results []dto.RerankResponseResult) []dto.RerankResponseResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelevanceScore > results[j].RelevanceScore
+	})
+	if info.RerankerInfo == nil {
+		return results
+	}
+	if info.TopN > 0 && len(results) > info.TopN {
+		results = results[:info.TopN]
+	}
+	for i := range results {
+		results[i].Document = nil
+		if info.ReturnDocuments && results[i].Index >= 0 && results[i].Index < len(info.Documents) {
+			results[i].Document = rerankDocument(info.Documents[results[i].Index])
+		}
+	}
+	return results
+}
+
+func rerankDocument(document any) any {
+	if text, ok := document.(string); ok {
+		return dto.RerankDocument{Text: text}
+	}
+	return document
+}
@@ -10,7 +10,6 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/dto"
-	"one-api/model"
 	relaycommon "one-api/relay/common"
 	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
@@ -122,9 +121,23 @@ func ImageHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		return types.NewError(err, types.ErrorCodeChannelModelMappedError)
 	}
 
-	priceData, err := helper.ModelPriceHelper(c, relayInfo, len(imageRequest.Prompt), 0)
-	if err != nil {
-		return types.NewError(err, types.ErrorCodeModelPriceError)
+	// Gemini 通过 generateContent 生成的图片按实际返回张数计费
+	perImageBilling := relayInfo.ApiType == constant.APITypeGemini && !strings.HasPrefix(relayInfo.UpstreamModelName, "imagen")
+	var perImagePrice float64
+	var priceData helper.PriceData
+	if perImageBilling {
+		perCallPriceData := helper.ModelPriceHelperPerCall(c, relayInfo)
+		perImagePrice = perCallPriceData.ModelPrice
+		priceData = helper.PriceData{
+			ModelPrice:     perCallPriceData.ModelPrice,
+			UsePrice:       true,
+			GroupRatioInfo: perCallPriceData.GroupRatioInfo,
+		}
+	} else {
+		priceData, err = helper.ModelPriceHelper(c, relayInfo, len(imageRequest.Prompt), 0)
+		if err != nil {
+			return types.NewError(err, types.ErrorCodeModelPriceError)
+		}
 	}
 	var preConsumedQuota int
 	var quota int
@@ -170,7 +183,7 @@ func ImageHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		// reset model price
 		priceData.ModelPrice *= sizeRatio * qualityRatio * float64(imageRequest.N)
 		quota = int(priceData.ModelPrice * priceData.GroupRatioInfo.GroupRatio * common.QuotaPerUnit)
-		userQuota, err = model.GetUserQuota(relayInfo.UserId, false)
+		userQuota, err = service.GetBillingQuota(relayInfo)
 		if err != nil {
 			return types.NewError(err, types.ErrorCodeQueryDataError)
 		}
@@ -242,6 +255,11 @@ func ImageHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 	}
 
 	logContent := fmt.Sprintf("大小 %s, 品质 %s", imageRequest.Size, quality)
+	if perImageBilling {
+		imageCount := common.GetContextKeyInt(c, constant.ContextKeyGeneratedImageCount)
+		priceData.ModelPrice = perImagePrice * float64(imageCount)
+		logContent = fmt.Sprintf("生成图片 %d 张，单价 %.6f", imageCount, perImagePrice)
+	}
 	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, logContent)
 	return nil
 }
@@ -0,0 +1,31 @@
+package relay
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newInternalRequestContext 创建网关内部子请求的上下文，继承当前请求的用户、令牌与渠道信息，响应写入返回的 recorder
+func newInternalRequestContext(c *gin.Context, path string, contentType string, body []byte) (*gin.Context, *httptest.ResponseRecorder, error) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	ctx.Request = req
+	for key, value := range c.Keys {
+		// 请求体与 prompt_tokens 属于父请求。TextHelper 等处理器在上下文中已有 prompt_tokens 时直接沿用，
+		// 继承后子请求会按父请求的 token 数计费，因此两者都由子请求按自身请求体重新计算
+		if key == common.KeyRequestBody || key == "prompt_tokens" {
+			continue
+		}
+		ctx.Set(key, value)
+	}
+	return ctx, recorder, nil
+}
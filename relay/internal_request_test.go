@@ -0,0 +1,57 @@
+package relay
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"one-api/common"
+	"one-api/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewInternalRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	parentCtx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/realtime", nil).WithContext(parentCtx)
+	c.Set(common.KeyRequestBody, []byte(`{"model":"parent"}`))
+	c.Set("prompt_tokens", 42)
+	common.SetContextKey(c, constant.ContextKeyUserId, 7)
+	common.SetContextKey(c, constant.ContextKeyTokenKey, "token")
+
+	ctx, recorder, err := newInternalRequestContext(c, "/v1/chat/completions", "application/json", []byte(`{"model":"child"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Request.Method != http.MethodPost || ctx.Request.URL.Path != "/v1/chat/completions" || ctx.Request.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("request = %s %s %q", ctx.Request.Method, ctx.Request.URL.Path, ctx.Request.Header.Get("Content-Type"))
+	}
+	// 子请求按自身请求体重新计算 token 数
+	if _, exists := ctx.Get("prompt_tokens"); exists {
+		t.Fatal("prompt_tokens inherited from parent request")
+	}
+	body, err := common.GetRequestBody(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"model":"child"}` {
+		t.Fatalf("body = %s, want the sub-request body", body)
+	}
+	if common.GetContextKeyInt(ctx, constant.ContextKeyUserId) != 7 || common.GetContextKeyString(ctx, constant.ContextKeyTokenKey) != "token" {
+		t.Fatalf("user and token not inherited: %v", ctx.Keys)
+	}
+
+	ctx.String(http.StatusOK, "ok")
+	if data, _ := io.ReadAll(recorder.Body); string(data) != "ok" {
+		t.Fatalf("recorder body = %q", data)
+	}
+	// 客户端断开时子请求随之取消
+	cancel()
+	if ctx.Request.Context().Err() == nil {
+		t.Fatal("sub-request context not cancelled with parent request")
+	}
+}
@@ -0,0 +1,638 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/middleware"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/types"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultRealtimeTranscriptionModel = "whisper-1"
+	defaultRealtimeSpeechModel        = "tts-1"
+	defaultRealtimeVoice              = "alloy"
+	// pcm16 为 24kHz、16 位、单声道
+	realtimePcm16SampleRate = 24000
+	// 单个 response.audio.delta 事件携带的音频字节数
+	realtimeAudioChunkSize = 32 * 1024
+)
+
+// useRealtimePipeline 渠道配置了 realtime_pipeline 或没有原生 realtime 接口时，使用 语音识别 → 对话 → 语音合成 处理会话
+func useRealtimePipeline(info *relaycommon.RelayInfo) bool {
+	return info.ChannelSetting.RealtimePipeline != nil || info.ApiType != constant.APITypeOpenAI
+}
+
+// realtimePipeline 以 OpenAI realtime 协议与客户端通信，每个阶段作为普通请求经现有渠道处理。
+// 不支持服务端 VAD，客户端需通过 input_audio_buffer.commit 与 response.create 驱动对话
+type realtimePipeline struct {
+	c       *gin.Context
+	info    *relaycommon.RelayInfo
+	setting dto.RealtimePipelineSetting
+	session dto.RealtimeSession
+
+	audioBuffer bytes.Buffer
+	messages    []dto.Message
+	// pendingUsage 为尚未结算的用量，每次 response.done 时结算
+	pendingUsage *dto.RealtimeUsage
+	sumUsage     *dto.RealtimeUsage
+}
+
+func newRealtimePipeline(c *gin.Context, info *relaycommon.RelayInfo) *realtimePipeline {
+	setting := dto.RealtimePipelineSetting{}
+	if info.ChannelSetting.RealtimePipeline != nil {
+		setting = *info.ChannelSetting.RealtimePipeline
+	}
+	if setting.TranscriptionModel == "" {
+		setting.TranscriptionModel = defaultRealtimeTranscriptionModel
+	}
+	if setting.ChatModel == "" {
+		setting.ChatModel = info.OriginModelName
+	}
+	if setting.SpeechModel == "" {
+		setting.SpeechModel = defaultRealtimeSpeechModel
+	}
+	if setting.Voice == "" {
+		setting.Voice = defaultRealtimeVoice
+	}
+	return &realtimePipeline{
+		c:       c,
+		info:    info,
+		setting: setting,
+		session: dto.RealtimeSession{
+			Modalities:        []string{"text", "audio"},
+			Voice:             setting.Voice,
+			InputAudioFormat:  info.InputAudioFormat,
+			OutputAudioFormat: info.OutputAudioFormat,
+			InputAudioTranscription: dto.InputAudioTranscription{
+				Model: setting.TranscriptionModel,
+			},
+			ToolChoice: "auto",
+		},
+		pendingUsage: &dto.RealtimeUsage{},
+		sumUsage:     &dto.RealtimeUsage{},
+	}
+}
+
+// RealtimePipelineHandler 处理整个 realtime 会话，返回会话累计用量
+func RealtimePipelineHandler(c *gin.Context, info *relaycommon.RelayInfo) (*types.NewAPIError, *dto.RealtimeUsage) {
+	if info == nil || info.ClientWs == nil {
+		return types.NewError(errors.New("invalid websocket connection"), types.ErrorCodeBadResponse), nil
+	}
+	info.IsStream = true
+	p := newRealtimePipeline(c, info)
+
+	p.send(&dto.RealtimeEvent{
+		Type:    dto.RealtimeEventTypeSessionCreated,
+		Session: &p.session,
+	})
+	for {
+		_, message, err := info.ClientWs.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				common.LogError(c, "realtime pipeline read error: "+err.Error())
+			}
+			break
+		}
+		event := &dto.RealtimeEvent{}
+		if err = common.Unmarshal(message, event); err != nil {
+			p.sendError(fmt.Errorf("invalid event: %w", err), "invalid_request_error")
+			continue
+		}
+		if err = p.handleEvent(event); err != nil {
+			common.LogError(c, "realtime pipeline error: "+err.Error())
+			p.sendError(err, "server_error")
+			if errors.Is(err, errRealtimeQuotaExceeded) {
+				break
+			}
+		}
+	}
+
+	// 会话结束时结算剩余用量
+	if p.pendingUsage.TotalTokens != 0 {
+		_ = p.consumeUsage()
+	}
+	return nil, p.sumUsage
+}
+
+var errRealtimeQuotaExceeded = errors.New("realtime quota exceeded")
+
+func (p *realtimePipeline) handleEvent(event *dto.RealtimeEvent) error {
+	switch event.Type {
+	case dto.RealtimeEventTypeSessionUpdate:
+		p.updateSession(event.Session)
+		p.send(&dto.RealtimeEvent{
+			Type:    dto.RealtimeEventTypeSessionUpdated,
+			Session: &p.session,
+		})
+	case dto.RealtimeEventInputAudioBufferAppend:
+		audio, err := base64.StdEncoding.DecodeString(event.Audio)
+		if err != nil {
+			return fmt.Errorf("invalid audio: %w", err)
+		}
+		audioTokens, err := service.CountAudioTokenInput(event.Audio, p.session.InputAudioFormat)
+		if err != nil {
+			return err
+		}
+		p.audioBuffer.Write(audio)
+		p.pendingUsage.InputTokens += audioTokens
+		p.pendingUsage.TotalTokens += audioTokens
+		p.pendingUsage.InputTokenDetails.AudioTokens += audioTokens
+	case dto.RealtimeEventInputAudioBufferClear:
+		p.audioBuffer.Reset()
+		p.send(&dto.RealtimeEvent{Type: dto.RealtimeEventInputAudioBufferCleared})
+	case dto.RealtimeEventInputAudioBufferCommit:
+		return p.commitAudio()
+	case dto.RealtimeEventTypeConversationCreate:
+		if event.Item == nil {
+			return errors.New("item is required")
+		}
+		item := *event.Item
+		if item.Id == "" {
+			item.Id = "item_" + common.GetRandomString(16)
+		}
+		p.appendItem(item)
+		p.send(&dto.RealtimeEvent{
+			Type: dto.RealtimeEventConversationItemCreated,
+			Item: &item,
+		})
+	case dto.RealtimeEventTypeResponseCreate:
+		return p.createResponse()
+	case dto.RealtimeEventTypeResponseCancel:
+		// 回复是同步生成的，收到取消时已没有进行中的回复
+	default:
+		return fmt.Errorf("unsupported event type: %s", event.Type)
+	}
+	return nil
+}
+
+func (p *realtimePipeline) updateSession(session *dto.RealtimeSession) {
+	if session == nil {
+		return
+	}
+	if len(session.Modalities) > 0 {
+		p.session.Modalities = session.Modalities
+	}
+	if session.Instructions != "" {
+		p.session.Instructions = session.Instructions
+	}
+	if session.Voice != "" {
+		p.session.Voice = session.Voice
+	}
+	if session.Tools != nil {
+		p.session.Tools = session.Tools
+		p.info.RealtimeTools = session.Tools
+	}
+	if session.ToolChoice != "" {
+		p.session.ToolChoice = session.ToolChoice
+	}
+	if session.Temperature != 0 {
+		p.session.Temperature = session.Temperature
+	}
+	if session.InputAudioTranscription.Model != "" {
+		p.session.InputAudioTranscription.Model = session.InputAudioTranscription.Model
+	}
+	// 音频格式固定为 pcm16，服务端 VAD 不可用
+	if session.InputAudioFormat != "" && session.InputAudioFormat != "pcm16" ||
+		session.OutputAudioFormat != "" && session.OutputAudioFormat != "pcm16" {
+		p.sendError(errors.New("only pcm16 audio format is supported"), "invalid_request_error")
+	}
+	p.session.TurnDetection = nil
+}
+
+func (p *realtimePipeline) appendItem(item dto.RealtimeItem) {
+	switch item.Type {
+	case "function_call":
+		name := ""
+		if item.Name != nil {
+			name = *item.Name
+		}
+		message := dto.Message{Role: "assistant"}
+		message.SetToolCalls([]dto.ToolCallRequest{
+			{
+				ID:   item.CallId,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      name,
+					Arguments: item.Arguments,
+				},
+			},
+		})
+		p.messages = append(p.messages, message)
+	case "function_call_output":
+		message := dto.Message{
+			Role:       "tool",
+			ToolCallId: item.CallId,
+		}
+		message.SetStringContent(item.Output)
+		p.messages = append(p.messages, message)
+	default:
+		var text strings.Builder
+		for _, content := range item.Content {
+			switch content.Type {
+			case "input_text", "text":
+				text.WriteString(content.Text)
+			case "input_audio", "audio":
+				text.WriteString(content.Transcript)
+			}
+		}
+		role := item.Role
+		if role == "" {
+			role = "user"
+		}
+		message := dto.Message{Role: role}
+		message.SetStringContent(text.String())
+		p.messages = append(p.messages, message)
+	}
+}
+
+// commitAudio 识别缓冲区中的音频，作为用户消息加入对话
+func (p *realtimePipeline) commitAudio() error {
+	if p.audioBuffer.Len() == 0 {
+		return errors.New("input audio buffer is empty")
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("model", p.session.InputAudioTranscription.Model)
+	_ = writer.WriteField("response_format", "json")
+	part, err := writer.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return err
+	}
+	if _, err = part.Write(pcm16ToWav(p.audioBuffer.Bytes(), realtimePcm16SampleRate)); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	p.audioBuffer.Reset()
+
+	responseBody, _, err := p.doRequest("/v1/audio/transcriptions", p.session.InputAudioTranscription.Model, writer.FormDataContentType(), body.Bytes())
+	if err != nil {
+		return err
+	}
+	var transcription dto.AudioResponse
+	if err = common.Unmarshal(responseBody, &transcription); err != nil {
+		return err
+	}
+
+	item := dto.RealtimeItem{
+		Id:     "item_" + common.GetRandomString(16),
+		Type:   "message",
+		Status: "completed",
+		Role:   "user",
+		Content: []dto.RealtimeContent{
+			{
+				Type:       "input_audio",
+				Transcript: transcription.Text,
+			},
+		},
+	}
+	p.appendItem(item)
+	p.send(&dto.RealtimeEvent{
+		Type:   dto.RealtimeEventInputAudioBufferCommitted,
+		ItemId: item.Id,
+	})
+	p.send(&dto.RealtimeEvent{
+		Type: dto.RealtimeEventConversationItemCreated,
+		Item: &item,
+	})
+	p.send(&dto.RealtimeEvent{
+		Type:       dto.RealtimeEventInputAudioTranscriptionCompleted,
+		ItemId:     item.Id,
+		Transcript: transcription.Text,
+	})
+	return nil
+}
+
+// createResponse 调用对话模型生成回复，需要音频时再调用语音合成
+func (p *realtimePipeline) createResponse() error {
+	chatRequest := dto.GeneralOpenAIRequest{
+		Model:    p.setting.ChatModel,
+		Messages: make([]dto.Message, 0, len(p.messages)+1),
+	}
+	if p.session.Instructions != "" {
+		chatRequest.Messages = append(chatRequest.Messages, dto.Message{
+			Role:    "system",
+			Content: p.session.Instructions,
+		})
+	}
+	chatRequest.Messages = append(chatRequest.Messages, p.messages...)
+	if p.session.Temperature != 0 {
+		chatRequest.Temperature = common.GetPointer(p.session.Temperature)
+	}
+	for _, tool := range p.session.Tools {
+		chatRequest.Tools = append(chatRequest.Tools, dto.ToolCallRequest{
+			Type: "function",
+			Function: dto.FunctionRequest{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	if len(chatRequest.Tools) > 0 && p.session.ToolChoice != "" {
+		chatRequest.ToolChoice = p.session.ToolChoice
+	}
+	requestBody, err := common.Marshal(chatRequest)
+	if err != nil {
+		return err
+	}
+
+	response := &dto.RealtimeResponse{
+		Id:     "resp_" + common.GetRandomString(16),
+		Object: "realtime.response",
+		Status: "in_progress",
+		Output: make([]dto.RealtimeItem, 0),
+	}
+	p.send(&dto.RealtimeEvent{
+		Type:     dto.RealtimeEventTypeResponseCreated,
+		Response: response,
+	})
+
+	responseBody, chatUsage, err := p.doRequest("/v1/chat/completions", p.setting.ChatModel, "application/json", requestBody)
+	if err != nil {
+		return err
+	}
+	p.info.SetFirstResponseTime()
+	var chatResponse dto.OpenAITextResponse
+	if err = common.Unmarshal(responseBody, &chatResponse); err != nil {
+		return err
+	}
+	if len(chatResponse.Choices) == 0 {
+		return errors.New("chat completion returned no choices")
+	}
+	if chatUsage == nil {
+		chatUsage = &chatResponse.Usage
+	}
+	p.pendingUsage.InputTokens += chatUsage.PromptTokens
+	p.pendingUsage.InputTokenDetails.TextTokens += chatUsage.PromptTokens
+	p.pendingUsage.OutputTokens += chatUsage.CompletionTokens
+	p.pendingUsage.OutputTokenDetails.TextTokens += chatUsage.CompletionTokens
+	p.pendingUsage.TotalTokens += chatUsage.PromptTokens + chatUsage.CompletionTokens
+
+	message := chatResponse.Choices[0].Message
+	p.messages = append(p.messages, message)
+	if toolCalls := message.ParseToolCalls(); len(toolCalls) > 0 {
+		for _, toolCall := range toolCalls {
+			item := dto.RealtimeItem{
+				Id:        "item_" + common.GetRandomString(16),
+				Type:      "function_call",
+				Status:    "completed",
+				Name:      common.GetPointer(toolCall.Function.Name),
+				CallId:    toolCall.ID,
+				Arguments: toolCall.Function.Arguments,
+			}
+			p.sendOutputItem(response, item, func() {
+				p.send(&dto.RealtimeEvent{
+					Type:        dto.RealtimeEventResponseFunctionCallArgumentsDone,
+					ResponseId:  response.Id,
+					ItemId:      item.Id,
+					OutputIndex: len(response.Output),
+					CallId:      item.CallId,
+					Name:        toolCall.Function.Name,
+					Arguments:   item.Arguments,
+				})
+			})
+		}
+	} else if text := message.StringContent(); slices.Contains(p.session.Modalities, "audio") {
+		audio, err := p.synthesizeSpeech(text)
+		if err != nil {
+			return err
+		}
+		item := dto.RealtimeItem{
+			Id:      "item_" + common.GetRandomString(16),
+			Type:    "message",
+			Status:  "completed",
+			Role:    "assistant",
+			Content: []dto.RealtimeContent{{Type: "audio", Transcript: text}},
+		}
+		p.sendOutputItem(response, item, func() {
+			base := dto.RealtimeEvent{
+				ResponseId:  response.Id,
+				ItemId:      item.Id,
+				OutputIndex: len(response.Output),
+			}
+			transcriptDelta := base
+			transcriptDelta.Type = dto.RealtimeEventResponseAudioTranscriptionDelta
+			transcriptDelta.Delta = text
+			p.send(&transcriptDelta)
+			for offset := 0; offset < len(audio); offset += realtimeAudioChunkSize {
+				end := min(offset+realtimeAudioChunkSize, len(audio))
+				audioDelta := base
+				audioDelta.Type = dto.RealtimeEventResponseAudioDelta
+				audioDelta.Delta = base64.StdEncoding.EncodeToString(audio[offset:end])
+				p.send(&audioDelta)
+			}
+			audioDone := base
+			audioDone.Type = dto.RealtimeEventResponseAudioDone
+			p.send(&audioDone)
+			transcriptDone := base
+			transcriptDone.Type = dto.RealtimeEventResponseAudioTranscriptionDone
+			transcriptDone.Transcript = text
+			p.send(&transcriptDone)
+		})
+	} else {
+		item := dto.RealtimeItem{
+			Id:      "item_" + common.GetRandomString(16),
+			Type:    "message",
+			Status:  "completed",
+			Role:    "assistant",
+			Content: []dto.RealtimeContent{{Type: "text", Text: text}},
+		}
+		p.sendOutputItem(response, item, func() {
+			p.send(&dto.RealtimeEvent{
+				Type:        dto.RealtimeEventResponseTextDelta,
+				ResponseId:  response.Id,
+				ItemId:      item.Id,
+				OutputIndex: len(response.Output),
+				Delta:       text,
+			})
+			p.send(&dto.RealtimeEvent{
+				Type:        dto.RealtimeEventResponseTextDone,
+				ResponseId:  response.Id,
+				ItemId:      item.Id,
+				OutputIndex: len(response.Output),
+				Text:        text,
+			})
+		})
+	}
+
+	response.Status = "completed"
+	response.Usage = p.pendingUsage
+	consumeErr := p.consumeUsage()
+	p.send(&dto.RealtimeEvent{
+		Type:     dto.RealtimeEventTypeResponseDone,
+		Response: response,
+	})
+	return consumeErr
+}
+
+// synthesizeSpeech 将文本合成为 pcm16 音频，并计入输出音频用量
+func (p *realtimePipeline) synthesizeSpeech(text string) ([]byte, error) {
+	if text == "" {
+		return nil, nil
+	}
+	requestBody, err := common.Marshal(dto.AudioRequest{
+		Model:          p.setting.SpeechModel,
+		Input:          text,
+		Voice:          p.session.Voice,
+		ResponseFormat: "pcm",
+	})
+	if err != nil {
+		return nil, err
+	}
+	audio, _, err := p.doRequest("/v1/audio/speech", p.setting.SpeechModel, "application/json", requestBody)
+	if err != nil {
+		return nil, err
+	}
+	audioTokens, err := service.CountAudioTokenOutput(base64.StdEncoding.EncodeToString(audio), p.session.OutputAudioFormat)
+	if err != nil {
+		return nil, err
+	}
+	p.pendingUsage.OutputTokens += audioTokens
+	p.pendingUsage.OutputTokenDetails.AudioTokens += audioTokens
+	p.pendingUsage.TotalTokens += audioTokens
+	return audio, nil
+}
+
+func (p *realtimePipeline) sendOutputItem(response *dto.RealtimeResponse, item dto.RealtimeItem, sendContent func()) {
+	p.send(&dto.RealtimeEvent{
+		Type:        dto.RealtimeEventResponseOutputItemAdded,
+		ResponseId:  response.Id,
+		OutputIndex: len(response.Output),
+		Item:        &item,
+	})
+	sendContent()
+	p.send(&dto.RealtimeEvent{
+		Type:        dto.RealtimeEventResponseOutputItemDone,
+		ResponseId:  response.Id,
+		OutputIndex: len(response.Output),
+		Item:        &item,
+	})
+	response.Output = append(response.Output, item)
+}
+
+// consumeUsage 按 realtime 模型结算未结算的用量
+func (p *realtimePipeline) consumeUsage() error {
+	usage := p.pendingUsage
+	p.pendingUsage = &dto.RealtimeUsage{}
+	p.sumUsage.TotalTokens += usage.TotalTokens
+	p.sumUsage.InputTokens += usage.InputTokens
+	p.sumUsage.OutputTokens += usage.OutputTokens
+	p.sumUsage.InputTokenDetails.TextTokens += usage.InputTokenDetails.TextTokens
+	p.sumUsage.InputTokenDetails.AudioTokens += usage.InputTokenDetails.AudioTokens
+	p.sumUsage.OutputTokenDetails.TextTokens += usage.OutputTokenDetails.TextTokens
+	p.sumUsage.OutputTokenDetails.AudioTokens += usage.OutputTokenDetails.AudioTokens
+	if err := service.PreWssConsumeQuota(p.c, p.info, usage); err != nil {
+		return fmt.Errorf("%w: %s", errRealtimeQuotaExceeded, err.Error())
+	}
+	return nil
+}
+
+// 子请求不继承的渠道上下文，由 SetupContextForSelectedChannel 按新渠道重新设置
+var realtimePipelineSkipKeys = []string{
+	string(constant.ContextKeyChannelOrganization),
+	string(constant.ContextKeyChannelIsMultiKey),
+	string(constant.ContextKeyChannelMultiKeyIndex),
+	"api_version",
+	"region",
+	"plugin",
+	"bot_id",
+}
+
+// doRequest 通过现有渠道发起子请求，返回响应体与用量。子请求不单独计费
+func (p *realtimePipeline) doRequest(path string, modelName string, contentType string, body []byte) ([]byte, *dto.Usage, error) {
+	ctx, recorder, err := newInternalRequestContext(p.c, path, contentType, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, key := range realtimePipelineSkipKeys {
+		delete(ctx.Keys, key)
+	}
+	common.SetContextKey(ctx, constant.ContextKeyRealtimePipeline, true)
+	// 与 Distribute 中间件一致，音频处理器从已解析的表单中读取 model 与文件
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		if err = ctx.Request.ParseMultipartForm(32 << 20); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	group := p.c.GetString("group")
+	channel, selectGroup, err := model.CacheGetRandomSatisfiedChannel(ctx, group, modelName, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取分组 %s 下模型 %s 的可用渠道失败: %s", selectGroup, modelName, err.Error())
+	}
+	if channel == nil {
+		return nil, nil, fmt.Errorf("分组 %s 下模型 %s 无可用渠道", selectGroup, modelName)
+	}
+	if newAPIError := middleware.SetupContextForSelectedChannel(ctx, channel, modelName); newAPIError != nil {
+		return nil, nil, newAPIError
+	}
+
+	var newAPIError *types.NewAPIError
+	switch relayconstant.Path2RelayMode(path) {
+	case relayconstant.RelayModeAudioSpeech, relayconstant.RelayModeAudioTranscription:
+		newAPIError = AudioHelper(ctx)
+	default:
+		newAPIError = TextHelper(ctx)
+	}
+	if newAPIError != nil {
+		return nil, nil, fmt.Errorf("%s: %w", modelName, newAPIError)
+	}
+	usage, _ := common.GetContextKeyType[*dto.Usage](ctx, constant.ContextKeyRealtimePipelineUsage)
+	return recorder.Body.Bytes(), usage, nil
+}
+
+func (p *realtimePipeline) send(event *dto.RealtimeEvent) {
+	event.EventId = "event_" + common.GetRandomString(16)
+	if err := helper.WssObject(p.c, p.info.ClientWs, event); err != nil {
+		common.LogError(p.c, "error writing to client: "+err.Error())
+	}
+}
+
+func (p *realtimePipeline) sendError(err error, errorType string) {
+	p.send(&dto.RealtimeEvent{
+		Type: dto.RealtimeEventTypeError,
+		Error: &types.OpenAIError{
+			Message: err.Error(),
+			Type:    errorType,
+		},
+	})
+}
+
+// pcm16ToWav 为原始 pcm16 单声道音频添加 WAV 文件头
+func pcm16ToWav(pcm []byte, sampleRate int) []byte {
+	var buf bytes.Buffer
+	buf.Grow(44 + len(pcm))
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1)) // 单声道
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(2))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
@@ -0,0 +1,416 @@
+package relay
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/middleware"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"one-api/setting/ratio_setting"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	realtimeTestUpstreamKey = "sk-upstream"
+	realtimeTestTokenKey    = "realtimetestkey"
+	realtimeTestUserQuota   = 100000000
+	// 1 秒的 pcm16 音频
+	realtimeTestAudioBytes = realtimePcm16SampleRate * 2
+)
+
+// fakeRealtimeUpstream 模拟 OpenAI 的语音识别、对话与语音合成接口，记录收到的请求
+type fakeRealtimeUpstream struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	paths  []string
+	chats  []dto.GeneralOpenAIRequest
+	speech []dto.AudioRequest
+	// transcriptionAudio 为语音识别请求中上传的文件
+	transcriptionAudio []byte
+}
+
+func newFakeRealtimeUpstream(t *testing.T) *fakeRealtimeUpstream {
+	t.Helper()
+	f := &fakeRealtimeUpstream{}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeRealtimeUpstream) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+realtimeTestUpstreamKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"invalid api key","type":"invalid_request_error"}}`))
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.URL.Path)
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/v1/audio/transcriptions":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.transcriptionAudio, _ = io.ReadAll(file)
+		if r.FormValue("model") != "whisper-1" {
+			http.Error(w, "unexpected model "+r.FormValue("model"), http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"text":"what is the weather in Paris"}`))
+	case "/v1/chat/completions":
+		var request dto.GeneralOpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.chats = append(f.chats, request)
+		last := request.Messages[len(request.Messages)-1]
+		if len(request.Tools) > 0 && last.Role != "tool" {
+			_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_weather","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":30,"completion_tokens":8,"total_tokens":38}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-2","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"It is sunny in Paris."},"finish_reason":"stop"}],"usage":{"prompt_tokens":45,"completion_tokens":6,"total_tokens":51}}`))
+	case "/v1/audio/speech":
+		var request dto.AudioRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.speech = append(f.speech, request)
+		w.Header().Set("Content-Type", "audio/pcm")
+		_, _ = w.Write(make([]byte, realtimeTestAudioBytes))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// installFakeFfprobe 语音识别按 ffprobe 读取的时长计费，测试中以输出 1 秒的脚本代替
+func installFakeFfprobe(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffprobe script requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ffprobe"), []byte("#!/bin/sh\necho 1.000000\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func setupRealtimePipelineTest(t *testing.T, baseUrl string) (*model.User, *model.Token) {
+	t.Helper()
+	t.Setenv("SQL_DSN", "")
+	t.Setenv("LOG_SQL_DSN", "")
+	oldDB, oldLogDB := model.DB, model.LOG_DB
+	oldSQLitePath, oldIsMasterNode, oldRedisEnabled := common.SQLitePath, common.IsMasterNode, common.RedisEnabled
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB = oldDB, oldLogDB
+		common.SQLitePath, common.IsMasterNode, common.RedisEnabled = oldSQLitePath, oldIsMasterNode, oldRedisEnabled
+	})
+	common.SQLitePath = filepath.Join(t.TempDir(), "realtime.db")
+	common.IsMasterNode = true
+	common.RedisEnabled = false
+	if err := model.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.InitLogDB(); err != nil {
+		t.Fatal(err)
+	}
+	installFakeFfprobe(t)
+	ratio_setting.InitRatioSettings()
+	service.InitTokenEncoders()
+	service.InitHttpClient()
+
+	channel := &model.Channel{
+		Type:    constant.ChannelTypeOpenAI,
+		Name:    "realtime-upstream",
+		Key:     realtimeTestUpstreamKey,
+		BaseURL: common.GetPointer(baseUrl),
+		Models:  "whisper-1,tts-1,gpt-4o-mini",
+		Group:   "default",
+		Status:  common.ChannelStatusEnabled,
+	}
+	if err := channel.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{
+		Username: "realtime",
+		Quota:    realtimeTestUserQuota,
+		Group:    "default",
+		Status:   common.UserStatusEnabled,
+	}
+	if err := model.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	token := &model.Token{
+		UserId:       user.Id,
+		Key:          realtimeTestTokenKey,
+		Name:         "realtime",
+		Status:       common.TokenStatusEnabled,
+		RemainQuota:  realtimeTestUserQuota,
+		ExpiredTime:  -1,
+		CreatedTime:  common.GetTimestamp(),
+		AccessedTime: common.GetTimestamp(),
+	}
+	if err := token.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	return user, token
+}
+
+type realtimePipelineResult struct {
+	err   *types.NewAPIError
+	usage *dto.RealtimeUsage
+}
+
+// startRealtimePipeline 启动按鉴权中间件设置好上下文的 realtime 服务端，返回客户端连接与会话结果
+func startRealtimePipeline(t *testing.T, token *model.Token, setting *dto.RealtimePipelineSetting) (*websocket.Conn, <-chan realtimePipelineResult) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	results := make(chan realtimePipelineResult, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer ws.Close()
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = r
+		if err := middleware.SetupContextForToken(c, token); err != nil {
+			t.Error(err)
+			return
+		}
+		common.SetContextKey(c, constant.ContextKeyUserGroup, "default")
+		common.SetContextKey(c, constant.ContextKeyUsingGroup, "default")
+		common.SetContextKey(c, constant.ContextKeyOriginalModel, "gpt-4o-realtime-preview")
+		common.SetContextKey(c, constant.ContextKeyRequestStartTime, time.Now())
+		info := relaycommon.GenRelayInfoWs(c, ws)
+		info.ChannelSetting.RealtimePipeline = setting
+		apiErr, usage := RealtimePipelineHandler(c, info)
+		results <- realtimePipelineResult{err: apiErr, usage: usage}
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client, results
+}
+
+func sendRealtimeEvent(t *testing.T, client *websocket.Conn, event dto.RealtimeEvent) {
+	t.Helper()
+	if err := client.WriteJSON(event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readRealtimeEvents 读取事件直到收到 until 类型的事件，返回期间的全部事件
+func readRealtimeEvents(t *testing.T, client *websocket.Conn, until string) []dto.RealtimeEvent {
+	t.Helper()
+	var events []dto.RealtimeEvent
+	_ = client.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		var event dto.RealtimeEvent
+		if err := client.ReadJSON(&event); err != nil {
+			t.Fatalf("read event after %v: %v", realtimeEventTypes(events), err)
+		}
+		if event.Type == dto.RealtimeEventTypeError {
+			t.Fatalf("error event: %+v", event.Error)
+		}
+		events = append(events, event)
+		if event.Type == until {
+			return events
+		}
+	}
+}
+
+func realtimeEventTypes(events []dto.RealtimeEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func assertRealtimeEventTypes(t *testing.T, events []dto.RealtimeEvent, want []string) {
+	t.Helper()
+	got := realtimeEventTypes(events)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v\nwant %v", got, want)
+	}
+}
+
+// realtimeTestQuota 按 gpt-4o-realtime-preview 的倍率计算一次回复应扣的额度
+func realtimeTestQuota(usage *dto.RealtimeUsage) int {
+	modelName := "gpt-4o-realtime-preview"
+	modelRatio, _, _ := ratio_setting.GetModelRatio(modelName)
+	audioRatio := ratio_setting.GetAudioRatio(modelName)
+	quota := float64(usage.InputTokenDetails.TextTokens) +
+		float64(usage.OutputTokenDetails.TextTokens)*ratio_setting.GetCompletionRatio(modelName) +
+		float64(usage.InputTokenDetails.AudioTokens)*audioRatio +
+		float64(usage.OutputTokenDetails.AudioTokens)*audioRatio*ratio_setting.GetAudioCompletionRatio(modelName)
+	return int(math.Round(quota * modelRatio * ratio_setting.GetGroupRatio("default")))
+}
+
+// TestRealtimePipelineConversation 语音输入经识别、工具调用、对话与语音合成完成一轮对话，按 realtime 模型结算用量
+func TestRealtimePipelineConversation(t *testing.T) {
+	upstream := newFakeRealtimeUpstream(t)
+	user, token := setupRealtimePipelineTest(t, upstream.server.URL)
+	client, results := startRealtimePipeline(t, token, &dto.RealtimePipelineSetting{ChatModel: "gpt-4o-mini"})
+
+	events := readRealtimeEvents(t, client, dto.RealtimeEventTypeSessionCreated)
+	if session := events[0].Session; session == nil || session.InputAudioTranscription.Model != "whisper-1" {
+		t.Fatalf("session.created = %+v", events[0].Session)
+	}
+
+	tools := []dto.RealTimeTool{{Type: "function", Name: "get_weather", Description: "查询天气", Parameters: map[string]any{"type": "object"}}}
+	sendRealtimeEvent(t, client, dto.RealtimeEvent{
+		Type:    dto.RealtimeEventTypeSessionUpdate,
+		Session: &dto.RealtimeSession{Instructions: "You are a weather bot.", Tools: tools},
+	})
+	readRealtimeEvents(t, client, dto.RealtimeEventTypeSessionUpdated)
+
+	// 分两段追加 1 秒音频
+	half := base64.StdEncoding.EncodeToString(make([]byte, realtimeTestAudioBytes/2))
+	sendRealtimeEvent(t, client, dto.RealtimeEvent{Type: dto.RealtimeEventInputAudioBufferAppend, Audio: half})
+	sendRealtimeEvent(t, client, dto.RealtimeEvent{Type: dto.RealtimeEventInputAudioBufferAppend, Audio: half})
+	sendRealtimeEvent(t, client, dto.RealtimeEvent{Type: dto.RealtimeEventInputAudioBufferCommit})
+	events = readRealtimeEvents(t, client, dto.RealtimeEventInputAudioTranscriptionCompleted)
+	assertRealtimeEventTypes(t, events, []string{
+		dto.RealtimeEventInputAudioBufferCommitted,
+		dto.RealtimeEventConversationItemCreated,
+		dto.RealtimeEventInputAudioTranscriptionCompleted,
+	})
+	if events[2].Transcript != "what is the weather in Paris" {
+		t.Fatalf("transcript = %q", events[2].Transcript)
+	}
+	if len(upstream.transcriptionAudio) != 44+realtimeTestAudioBytes || string(upstream.transcriptionAudio[:4]) != "RIFF" {
+		t.Fatalf("uploaded audio is not a wav of the committed buffer (%d bytes)", len(upstream.transcriptionAudio))
+	}
+
+	// 第一轮回复为工具调用
+	sendRealtimeEvent(t, client, dto.RealtimeEvent{Type: dto.RealtimeEventTypeResponseCreate})
+	events = readRealtimeEvents(t, client, dto.RealtimeEventTypeResponseDone)
+	assertRealtimeEventTypes(t, events, []string{
+		dto.RealtimeEventTypeResponseCreated,
+		dto.RealtimeEventResponseOutputItemAdded,
+		dto.RealtimeEventResponseFunctionCallArgumentsDone,
+		dto.RealtimeEventResponseOutputItemDone,
+		dto.RealtimeEventTypeResponseDone,
+	})
+	if call := events[2]; call.CallId != "call_weather" || call.Name != "get_weather" || call.Arguments != `{"city":"Paris"}` {
+		t.Fatalf("function call = %+v", call)
+	}
+	firstUsage := events[4].Response.Usage
+	if firstUsage.InputTokenDetails.AudioTokens == 0 || firstUsage.InputTokenDetails.TextTokens != 30 || firstUsage.OutputTokenDetails.TextTokens != 8 {
+		t.Fatalf("first response usage = %+v", firstUsage)
+	}
+
+	// 返回工具结果后第二轮回复为语音
+	sendRealtimeEvent(t, client, dto.RealtimeEvent{
+		Type: dto.RealtimeEventTypeConversationCreate,
+		Item: &dto.RealtimeItem{Type: "function_call_output", CallId: "call_weather", Output: `{"weather":"sunny"}`},
+	})
+	readRealtimeEvents(t, client, dto.RealtimeEventConversationItemCreated)
+	sendRealtimeEvent(t, client, dto.RealtimeEvent{Type: dto.RealtimeEventTypeResponseCreate})
+	events = readRealtimeEvents(t, client, dto.RealtimeEventTypeResponseDone)
+	assertRealtimeEventTypes(t, events, []string{
+		dto.RealtimeEventTypeResponseCreated,
+		dto.RealtimeEventResponseOutputItemAdded,
+		dto.RealtimeEventResponseAudioTranscriptionDelta,
+		dto.RealtimeEventResponseAudioDelta,
+		dto.RealtimeEventResponseAudioDelta,
+		dto.RealtimeEventResponseAudioDone,
+		dto.RealtimeEventResponseAudioTranscriptionDone,
+		dto.RealtimeEventResponseOutputItemDone,
+		dto.RealtimeEventTypeResponseDone,
+	})
+	var audio []byte
+	for _, event := range events[3:5] {
+		chunk, err := base64.StdEncoding.DecodeString(event.Delta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		audio = append(audio, chunk...)
+	}
+	if len(audio) != realtimeTestAudioBytes {
+		t.Fatalf("audio = %d bytes, want %d", len(audio), realtimeTestAudioBytes)
+	}
+	if events[6].Transcript != "It is sunny in Paris." {
+		t.Fatalf("transcript = %q", events[6].Transcript)
+	}
+	secondUsage := events[8].Response.Usage
+	if secondUsage.InputTokenDetails.TextTokens != 45 || secondUsage.OutputTokenDetails.TextTokens != 6 || secondUsage.OutputTokenDetails.AudioTokens == 0 {
+		t.Fatalf("second response usage = %+v", secondUsage)
+	}
+
+	_ = client.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	var result realtimePipelineResult
+	select {
+	case result = <-results:
+	case <-time.After(10 * time.Second):
+		t.Fatal("pipeline did not finish after client closed")
+	}
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	wantTotal := firstUsage.TotalTokens + secondUsage.TotalTokens
+	if result.usage.TotalTokens != wantTotal || result.usage.InputTokenDetails.TextTokens != 75 || result.usage.OutputTokenDetails.TextTokens != 14 {
+		t.Fatalf("session usage = %+v, want total %d", result.usage, wantTotal)
+	}
+
+	// 子请求携带会话上下文
+	if strings.Join(upstream.paths, ",") != "/v1/audio/transcriptions,/v1/chat/completions,/v1/chat/completions,/v1/audio/speech" {
+		t.Fatalf("upstream paths = %v", upstream.paths)
+	}
+	second := upstream.chats[1]
+	if second.Model != "gpt-4o-mini" || len(second.Messages) != 4 || second.Messages[0].Role != "system" || second.Messages[3].Role != "tool" {
+		t.Fatalf("second chat request = %+v", second)
+	}
+	if speech := upstream.speech[0]; speech.Model != "tts-1" || speech.Voice != "alloy" || speech.ResponseFormat != "pcm" || speech.Input != "It is sunny in Paris." {
+		t.Fatalf("speech request = %+v", speech)
+	}
+
+	// 子请求不单独扣费，会话用量按 realtime 模型结算到用户与令牌
+	wantQuota := realtimeTestQuota(firstUsage) + realtimeTestQuota(secondUsage)
+	userQuota, err := model.GetUserQuota(user.Id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if realtimeTestUserQuota-userQuota != wantQuota {
+		t.Fatalf("consumed user quota = %d, want %d", realtimeTestUserQuota-userQuota, wantQuota)
+	}
+	consumedToken, err := model.GetTokenById(token.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if realtimeTestUserQuota-consumedToken.RemainQuota != wantQuota {
+		t.Fatalf("consumed token quota = %d, want %d", realtimeTestUserQuota-consumedToken.RemainQuota, wantQuota)
+	}
+}
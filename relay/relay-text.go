@@ -279,8 +279,11 @@ func checkRequestSensitive(textRequest *dto.GeneralOpenAIRequest, info *relaycom
 
 // 预扣费并返回用户剩余配额
 func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommon.RelayInfo) (int, int, *types.NewAPIError) {
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := service.GetBillingQuota(relayInfo)
 	if err != nil {
+		if errors.Is(err, model.ErrNotOrganizationMember) || errors.Is(err, model.ErrOrganizationDisabled) {
+			return 0, 0, types.NewErrorWithStatusCode(err, types.ErrorCodeAccessDenied, http.StatusForbidden)
+		}
 		return 0, 0, types.NewError(err, types.ErrorCodeQueryDataError)
 	}
 	if userQuota <= 0 {
@@ -290,6 +293,10 @@ func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommo
 		return 0, 0, types.NewErrorWithStatusCode(fmt.Errorf("pre-consume quota failed, user quota: %s, need quota: %s", common.FormatQuota(userQuota), common.FormatQuota(preConsumedQuota)), types.ErrorCodeInsufficientUserQuota, http.StatusForbidden)
 	}
 	relayInfo.UserQuota = userQuota
+	// realtime 会话内部的子请求不单独预扣费，由会话按实际用量结算
+	if common.GetContextKeyBool(c, constant.ContextKeyRealtimePipeline) {
+		return 0, userQuota, nil
+	}
 	if userQuota > 100*preConsumedQuota {
 		// 用户额度充足，判断令牌额度是否充足
 		if !relayInfo.TokenUnlimited {
@@ -313,8 +320,17 @@ func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommo
 		if err != nil {
 			return 0, 0, types.NewErrorWithStatusCode(err, types.ErrorCodePreConsumeTokenQuotaFailed, http.StatusForbidden)
 		}
-		err = model.DecreaseUserQuota(relayInfo.UserId, preConsumedQuota)
+		err = service.DecreaseBillingQuota(relayInfo, preConsumedQuota)
 		if err != nil {
+			// 付费方扣费失败，退回已预扣的令牌额度
+			if !relayInfo.IsPlayground {
+				if tokenErr := model.IncreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKey, preConsumedQuota); tokenErr != nil {
+					common.SysError("error return pre-consumed token quota: " + tokenErr.Error())
+				}
+			}
+			if errors.Is(err, model.ErrOrganizationQuotaNotEnough) {
+				return 0, 0, types.NewErrorWithStatusCode(err, types.ErrorCodeInsufficientUserQuota, http.StatusForbidden)
+			}
 			return 0, 0, types.NewError(err, types.ErrorCodeUpdateDataError)
 		}
 	}
@@ -336,6 +352,10 @@ func returnPreConsumedQuota(c *gin.Context, relayInfo *relaycommon.RelayInfo, us
 
 func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	usage *dto.Usage, preConsumedQuota int, userQuota int, priceData helper.PriceData, extraContent string) {
+	if common.GetContextKeyBool(ctx, constant.ContextKeyRealtimePipeline) {
+		common.SetContextKey(ctx, constant.ContextKeyRealtimePipelineUsage, usage)
+		return
+	}
 	if usage == nil {
 		usage = &dto.Usage{
 			PromptTokens:     relayInfo.PromptTokens,
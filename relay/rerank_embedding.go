@@ -0,0 +1,124 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/common_handler"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 向量重排序时单个分块的最大字符数
+const embeddingRerankChunkSize = 1024
+
+// rerankDocumentText 提取文档文本，支持字符串与 {"text": "..."} 两种格式
+func rerankDocumentText(document any) string {
+	switch v := document.(type) {
+	case string:
+		return v
+	case map[string]any:
+		if text, ok := v["text"].(string); ok {
+			return text
+		}
+	}
+	data, _ := common.Marshal(document)
+	return string(data)
+}
+
+// splitRerankChunks 按 max_chunks_per_doc 将文档切分为多个分块，未设置时整篇作为一个分块
+func splitRerankChunks(text string, maxChunks int) []string {
+	runes := []rune(text)
+	if maxChunks <= 0 || len(runes) <= embeddingRerankChunkSize {
+		return []string{text}
+	}
+	chunks := make([]string, 0, maxChunks)
+	for start := 0; start < len(runes) && len(chunks) < maxChunks; start += embeddingRerankChunkSize {
+		end := min(start+embeddingRerankChunkSize, len(runes))
+		chunks = append(chunks, string(runes[start:end]))
+	}
+	return chunks
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// embeddingRerankHelper 对不提供原生重排序接口的模型，调用同名向量模型获取查询与文档的向量，
+// 以文档各分块与查询的最大余弦相似度作为相关性得分，计费按向量请求处理
+func embeddingRerankHelper(c *gin.Context, info *relaycommon.RelayInfo, request *dto.RerankRequest) *types.NewAPIError {
+	inputs := []string{request.Query}
+	// 每篇文档在 inputs 中的分块下标区间
+	docRanges := make([][2]int, len(request.Documents))
+	for i, document := range request.Documents {
+		chunks := splitRerankChunks(rerankDocumentText(document), request.GetMaxChunksPerDoc())
+		docRanges[i] = [2]int{len(inputs), len(inputs) + len(chunks)}
+		inputs = append(inputs, chunks...)
+	}
+
+	body, err := common.Marshal(dto.EmbeddingRequest{
+		Model: request.Model,
+		Input: inputs,
+	})
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeConvertRequestFailed)
+	}
+	subCtx, recorder, err := newInternalRequestContext(c, "/v1/embeddings", "application/json", body)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeDoRequestFailed)
+	}
+	if newAPIError := EmbeddingHelper(subCtx); newAPIError != nil {
+		return newAPIError
+	}
+	if recorder.Code != http.StatusOK {
+		return types.NewOpenAIError(fmt.Errorf("embedding request failed: %s", recorder.Body.String()), types.ErrorCodeBadResponse, recorder.Code)
+	}
+
+	var embeddingResponse dto.OpenAIEmbeddingResponse
+	if err := common.Unmarshal(recorder.Body.Bytes(), &embeddingResponse); err != nil {
+		return types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	embeddings := make([][]float64, len(inputs))
+	for _, item := range embeddingResponse.Data {
+		if item.Index >= 0 && item.Index < len(embeddings) {
+			embeddings[item.Index] = item.Embedding
+		}
+	}
+	if embeddings[0] == nil {
+		return types.NewError(errors.New("query embedding is missing"), types.ErrorCodeBadResponseBody)
+	}
+
+	results := make([]dto.RerankResponseResult, len(request.Documents))
+	for i, docRange := range docRanges {
+		score := -1.0
+		for j := docRange[0]; j < docRange[1]; j++ {
+			score = math.Max(score, cosineSimilarity(embeddings[0], embeddings[j]))
+		}
+		results[i] = dto.RerankResponseResult{
+			Index:          i,
+			RelevanceScore: score,
+		}
+	}
+	c.JSON(http.StatusOK, dto.RerankResponse{
+		Results: common_handler.NormalizeRerankResults(info, results),
+		Usage:   embeddingResponse.Usage,
+	})
+	return nil
+}
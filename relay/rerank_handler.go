@@ -9,6 +9,7 @@ import (
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
 	"one-api/service"
+	"one-api/setting/model_setting"
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -41,6 +42,10 @@ func RerankHelper(c *gin.Context, relayMode int) (newAPIError *types.NewAPIError
 		return types.NewError(fmt.Errorf("documents is empty"), types.ErrorCodeInvalidRequest)
 	}
 
+	if model_setting.IsEmbeddingRerankModel(relayInfo.OriginModelName) {
+		return embeddingRerankHelper(c, relayInfo, rerankRequest)
+	}
+
 	err = helper.ModelMappedHelper(c, relayInfo, rerankRequest)
 	if err != nil {
 		return types.NewError(err, types.ErrorCodeChannelModelMappedError)
@@ -56,6 +56,12 @@ func ResponsesHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 
 	relayInfo := relaycommon.GenRelayInfoResponses(c, req)
 
+	originalInput := req.Input
+	previousResponseId := req.PreviousResponseID
+	if err = expandPreviousResponse(req, relayInfo); err != nil {
+		return types.NewError(err, types.ErrorCodeInvalidRequest)
+	}
+
 	if setting.ShouldCheckPromptSensitive() {
 		sensitiveWords, err := checkInputSensitive(req, relayInfo)
 		if err != nil {
@@ -160,6 +166,12 @@ func ResponsesHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		return newAPIError
 	}
 
+	if req.ShouldStore() {
+		if err := storeResponse(relayInfo, originalInput, previousResponseId); err != nil {
+			common.LogError(c, "failed to store response: "+err.Error())
+		}
+	}
+
 	if strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
 		service.PostAudioConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
 	} else {
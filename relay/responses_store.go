@@ -0,0 +1,174 @@
+package relay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+
+	"gorm.io/gorm"
+)
+
+func responsesKeyHash(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// normalizeResponsesInput 将字符串形式的 input 统一为条目数组
+func normalizeResponsesInput(input json.RawMessage) ([]json.RawMessage, error) {
+	var text string
+	if err := common.Unmarshal(input, &text); err == nil {
+		item, err := common.Marshal(map[string]any{
+			"type":    dto.ResponsesItemTypeMessage,
+			"role":    "user",
+			"content": text,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []json.RawMessage{item}, nil
+	}
+	var items []json.RawMessage
+	if err := common.Unmarshal(input, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	return items, nil
+}
+
+// responsesOutputToInput 将输出条目转换为可作为下一轮 input 的条目。
+// 只保留消息与函数调用，去掉条目 id，避免另一个上游因找不到对应条目而报错。
+func responsesOutputToInput(output []dto.ResponsesOutput) ([]json.RawMessage, error) {
+	items := make([]json.RawMessage, 0, len(output))
+	for _, out := range output {
+		var item any
+		switch out.Type {
+		case dto.ResponsesItemTypeMessage:
+			contents := make([]map[string]any, 0, len(out.Content))
+			for _, content := range out.Content {
+				if content.Type != "output_text" {
+					continue
+				}
+				contents = append(contents, map[string]any{
+					"type": "output_text",
+					"text": content.Text,
+				})
+			}
+			item = map[string]any{
+				"type":    dto.ResponsesItemTypeMessage,
+				"role":    "assistant",
+				"content": contents,
+			}
+		case dto.ResponsesItemTypeFunctionCall:
+			item = map[string]any{
+				"type":      dto.ResponsesItemTypeFunctionCall,
+				"call_id":   out.CallId,
+				"name":      out.Name,
+				"arguments": out.Arguments,
+			}
+		default:
+			continue
+		}
+		data, err := common.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, data)
+	}
+	return items, nil
+}
+
+// expandPreviousResponse 当 previous_response_id 指向的响应并非由当前渠道的同一密钥原生保存时，
+// 使用本地保存的对话链将其展开为完整的 input。本地没有记录时原样交给上游处理。
+func expandPreviousResponse(req *dto.OpenAIResponsesRequest, info *relaycommon.RelayInfo) error {
+	if req.PreviousResponseID == "" {
+		return nil
+	}
+	previous, err := model.GetStoredResponse(info.UserId, req.PreviousResponseID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if previous.UpstreamStored && previous.ChannelId == info.ChannelId && previous.KeyHash == responsesKeyHash(info.ApiKey) {
+		return nil
+	}
+	chain, err := model.GetStoredResponseChain(info.UserId, req.PreviousResponseID)
+	if err != nil {
+		return fmt.Errorf("failed to load previous responses: %w", err)
+	}
+
+	items := make([]json.RawMessage, 0)
+	for _, stored := range chain {
+		var input []json.RawMessage
+		if err := common.UnmarshalJsonStr(stored.Input, &input); err != nil {
+			return err
+		}
+		var output []dto.ResponsesOutput
+		if err := common.UnmarshalJsonStr(stored.Output, &output); err != nil {
+			return err
+		}
+		outputItems, err := responsesOutputToInput(output)
+		if err != nil {
+			return err
+		}
+		items = append(items, input...)
+		items = append(items, outputItems...)
+	}
+	current, err := normalizeResponsesInput(req.Input)
+	if err != nil {
+		return err
+	}
+	req.Input, err = common.Marshal(append(items, current...))
+	if err != nil {
+		return err
+	}
+	req.PreviousResponseID = ""
+	return nil
+}
+
+// storeResponse 保存本轮的 input 与最终响应，input 为展开前客户端提交的内容
+func storeResponse(info *relaycommon.RelayInfo, input json.RawMessage, previousResponseId string) error {
+	response := info.ResponsesUsageInfo.Response
+	if response == nil || response.ID == "" {
+		return nil
+	}
+	inputItems, err := normalizeResponsesInput(input)
+	if err != nil {
+		return err
+	}
+	inputData, err := common.Marshal(inputItems)
+	if err != nil {
+		return err
+	}
+	outputData, err := common.Marshal(response.Output)
+	if err != nil {
+		return err
+	}
+	response.PreviousResponseID = previousResponseId
+	response.Store = true
+	responseData, err := common.Marshal(response)
+	if err != nil {
+		return err
+	}
+	stored := &model.StoredResponse{
+		ResponseId: response.ID,
+		UserId:     info.UserId,
+		ChannelId:  info.ChannelId,
+		KeyHash:    responsesKeyHash(info.ApiKey),
+		// 转换为其他格式的渠道会把 RelayMode 改为对话补全，此时上游并未保存该响应
+		UpstreamStored:     info.RelayMode == relayconstant.RelayModeResponses,
+		ModelName:          info.OriginModelName,
+		PreviousResponseId: previousResponseId,
+		Input:              string(inputData),
+		Output:             string(outputData),
+		Response:           string(responseData),
+	}
+	return stored.Insert()
+}
@@ -44,6 +44,17 @@ func WssHelper(c *gin.Context, ws *websocket.Conn) (newAPIError *types.NewAPIErr
 		}
 	}()
 
+	if useRealtimePipeline(relayInfo) {
+		var usage *dto.RealtimeUsage
+		newAPIError, usage = RealtimePipelineHandler(c, relayInfo)
+		if newAPIError != nil {
+			return newAPIError
+		}
+		service.PostWssConsumeQuota(c, relayInfo, relayInfo.UpstreamModelName, usage, preConsumedQuota,
+			userQuota, priceData, "")
+		return nil
+	}
+
 	adaptor := GetAdaptor(relayInfo.ApiType)
 	if adaptor == nil {
 		return types.NewError(fmt.Errorf("invalid api type: %d", relayInfo.ApiType), types.ErrorCodeInvalidApiType)
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"one-api/common"
 	"one-api/controller"
 	"one-api/middleware"
 
@@ -19,7 +20,7 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/status", controller.GetStatus)
 		apiRouter.GET("/uptime/status", controller.GetUptimeKumaStatus)
 		apiRouter.GET("/models", middleware.APIAuth(), controller.DashboardListModels)
-		apiRouter.GET("/status/test", middleware.AdminAuth(), controller.TestStatus)
+		apiRouter.GET("/status/test", middleware.AdminAuth(), middleware.RequirePermission(common.PermissionChannelManage), controller.TestStatus)
 		apiRouter.GET("/notice", controller.GetNotice)
 		apiRouter.GET("/about", controller.GetAbout)
 		//apiRouter.GET("/midjourney", controller.GetMidjourney)
@@ -31,15 +32,20 @@ func SetApiRouter(router *gin.Engine) {
 		//apiRouter.POST("/user/reset", middleware.CriticalRateLimit(), controller.ResetPassword)
 		//apiRouter.POST("/user/verify_reset_code", middleware.CriticalRateLimit(), controller.VerifyResetCode)
 		//apiRouter.POST("/user/reset_password", middleware.CriticalRateLimit(), controller.ResetPasswordWithNewPassword)
-		//apiRouter.GET("/oauth/github", middleware.CriticalRateLimit(), controller.GitHubOAuth)
-		//apiRouter.GET("/oauth/oidc", middleware.CriticalRateLimit(), controller.OidcAuth)
-		//apiRouter.GET("/oauth/linuxdo", middleware.CriticalRateLimit(), controller.LinuxdoOAuth)
-		//apiRouter.GET("/oauth/state", middleware.CriticalRateLimit(), controller.GenerateOAuthCode)
-		//apiRouter.GET("/oauth/wechat", middleware.CriticalRateLimit(), controller.WeChatAuth)
-		//apiRouter.GET("/oauth/wechat/bind", middleware.CriticalRateLimit(), controller.WeChatBind)
+		apiRouter.GET("/oauth/github", middleware.CriticalRateLimit(), controller.GitHubOAuth)
+		apiRouter.GET("/oauth/github/bind", middleware.CriticalRateLimit(), middleware.APIAuth(), controller.GitHubBind)
+		apiRouter.GET("/oauth/oidc", middleware.CriticalRateLimit(), controller.OidcAuth)
+		apiRouter.GET("/oauth/oidc/bind", middleware.CriticalRateLimit(), middleware.APIAuth(), controller.OidcBind)
+		apiRouter.GET("/oauth/linuxdo", middleware.CriticalRateLimit(), controller.LinuxdoOAuth)
+		apiRouter.GET("/oauth/linuxdo/bind", middleware.CriticalRateLimit(), middleware.APIAuth(), controller.LinuxDoBind)
+		apiRouter.GET("/oauth/state", middleware.CriticalRateLimit(), controller.GenerateOAuthCode)
+		apiRouter.GET("/oauth/wechat", middleware.CriticalRateLimit(), controller.WeChatAuth)
+		apiRouter.GET("/oauth/wechat/bind", middleware.CriticalRateLimit(), middleware.APIAuth(), controller.WeChatBind)
 		//apiRouter.GET("/oauth/email/bind", middleware.CriticalRateLimit(), controller.EmailBind)
-		//apiRouter.GET("/oauth/telegram/login", middleware.CriticalRateLimit(), controller.TelegramLogin)
-		//apiRouter.GET("/oauth/telegram/bind", middleware.CriticalRateLimit(), controller.TelegramBind)
+		apiRouter.GET("/oauth/telegram/login", middleware.CriticalRateLimit(), controller.TelegramLogin)
+		// Telegram 登录组件以浏览器跳转方式回调，绑定时使用网页会话中的用户
+		apiRouter.GET("/oauth/telegram/bind", middleware.CriticalRateLimit(), controller.TelegramBind)
+		apiRouter.POST("/oauth/bind_phone", middleware.CriticalRateLimit(), controller.OAuthBindPhone)
 		apiRouter.GET("/ratio_config", middleware.CriticalRateLimit(), controller.GetRatioConfig)
 
 		apiRouter.POST("/stripe/webhook", controller.StripeWebhook)
@@ -50,26 +56,55 @@ func SetApiRouter(router *gin.Engine) {
 			userRoute.POST("/login", middleware.CriticalRateLimit(), middleware.TurnstileCheck(), controller.Login)
 			//userRoute.POST("/tokenlog", middleware.CriticalRateLimit(), controller.TokenLog)
 			userRoute.GET("/logout", controller.Logout)
+			userRoute.POST("/login/2fa", middleware.CriticalRateLimit(), controller.LoginTwoFA)
+			userRoute.POST("/refresh", middleware.CriticalRateLimit(), controller.RefreshAccessToken)
 			userRoute.GET("/epay/notify", controller.EpayNotify)
 			userRoute.GET("/groups", controller.GetUserGroups)
 
+			// 未启用两步验证的管理员需要读取自身信息以进入两步验证设置
+			userRoute.GET("/self", middleware.TwoFAEnrolment(), middleware.APIAuth(), controller.GetSelf)
+
 			selfRoute := userRoute.Group("/")
 			selfRoute.Use(middleware.APIAuth())
 			{
 				selfRoute.GET("/self/groups", controller.GetUserGroups)
-				selfRoute.GET("/self", controller.GetSelf)
+				selfRoute.GET("/self/permissions", controller.GetSelfPermissions)
 				selfRoute.GET("/models", controller.GetUserModels)
 				selfRoute.PUT("/self", controller.UpdateSelf)
-				selfRoute.DELETE("/self", controller.DeleteSelf)
+				selfRoute.DELETE("/self", middleware.SudoAuth(), controller.DeleteSelf)
+				selfRoute.GET("/self/deletion", controller.GetSelfDeletion)
+				selfRoute.DELETE("/self/deletion", controller.CancelSelfDeletion)
+				selfRoute.GET("/self/export", middleware.CriticalRateLimit(), controller.ExportSelfData)
 				selfRoute.GET("/token", controller.GenerateAccessToken)
+				selfRoute.GET("/sessions", controller.GetSelfSessions)
+				selfRoute.DELETE("/sessions", controller.RevokeOtherSelfSessions)
+				selfRoute.DELETE("/sessions/:id", controller.RevokeSelfSession)
+				selfRoute.DELETE("/oauth/:provider", controller.UnbindOAuth)
 				selfRoute.GET("/aff", controller.GetAffCode)
+				selfRoute.GET("/aff/rewards", controller.GetSelfInviteRewards)
 				selfRoute.POST("/topup", middleware.CriticalRateLimit(), controller.TopUp)
 				selfRoute.POST("/pay", middleware.CriticalRateLimit(), controller.RequestEpay)
 				selfRoute.POST("/amount", controller.RequestAmount)
 				selfRoute.POST("/stripe/pay", middleware.CriticalRateLimit(), controller.RequestStripePay)
 				selfRoute.POST("/stripe/amount", controller.RequestStripeAmount)
+				selfRoute.POST("/stripe/subscribe", middleware.CriticalRateLimit(), controller.RequestStripeSubscribe)
+				selfRoute.GET("/stripe/subscriptions", controller.GetStripeSubscriptions)
+				selfRoute.POST("/stripe/portal", middleware.CriticalRateLimit(), controller.RequestStripePortal)
 				selfRoute.POST("/aff_transfer", controller.TransferAffQuota)
 				selfRoute.PUT("/setting", controller.UpdateUserSetting)
+				selfRoute.GET("/self/statements", controller.GetSelfStatement)
+			}
+
+			// 两步验证，同时支持网页会话与 access token，供管理员在网页端启用
+			twoFARoute := userRoute.Group("/2fa")
+			twoFARoute.Use(middleware.TwoFAEnrolment(), middleware.UserAuth())
+			{
+				twoFARoute.GET("/", controller.GetTwoFAStatus)
+				twoFARoute.POST("/setup", controller.SetupTwoFA)
+				twoFARoute.POST("/enable", middleware.CriticalRateLimit(), controller.EnableTwoFA)
+				twoFARoute.POST("/disable", middleware.CriticalRateLimit(), controller.DisableTwoFA)
+				twoFARoute.POST("/recovery_codes", middleware.CriticalRateLimit(), controller.RegenerateTwoFARecoveryCodes)
+				twoFARoute.POST("/sudo", middleware.CriticalRateLimit(), controller.SudoTwoFA)
 			}
 
 			// 聊天会话相关路由
@@ -106,23 +141,73 @@ func SetApiRouter(router *gin.Engine) {
 			adminRoute := userRoute.Group("/")
 			adminRoute.Use(middleware.AdminAuth())
 			{
-				adminRoute.GET("/", controller.GetAllUsers)
-				adminRoute.GET("/search", controller.SearchUsers)
-				adminRoute.GET("/:id", controller.GetUser)
-				adminRoute.POST("/", controller.CreateUser)
-				adminRoute.POST("/manage", controller.ManageUser)
-				adminRoute.PUT("/", controller.UpdateUser)
-				adminRoute.DELETE("/:id", controller.DeleteUser)
+				userRead := middleware.RequirePermission(common.PermissionUserRead, common.PermissionUserManage)
+				userManage := middleware.RequirePermission(common.PermissionUserManage)
+				adminRoute.GET("/", userRead, controller.GetAllUsers)
+				adminRoute.GET("/search", userRead, controller.SearchUsers)
+				adminRoute.GET("/:id", userRead, controller.GetUser)
+				adminRoute.GET("/:id/statements", userRead, controller.GetUserStatement)
+				adminRoute.POST("/", userManage, controller.CreateUser)
+				adminRoute.POST("/manage", userManage, controller.ManageUser)
+				adminRoute.PUT("/", userManage, controller.UpdateUser)
+				adminRoute.DELETE("/:id", userManage, middleware.SudoAuth(), controller.DeleteUser)
+				adminRoute.DELETE("/:id/2fa", userManage, middleware.SudoAuth(), controller.ResetUserTwoFA)
 			}
 		}
 		optionRoute := apiRouter.Group("/option")
 		optionRoute.Use(middleware.RootAuth())
 		{
 			optionRoute.GET("/", controller.GetOptions)
-			optionRoute.PUT("/", controller.UpdateOption)
+			optionRoute.PUT("/", middleware.SudoAuth(), controller.UpdateOption)
 			optionRoute.POST("/rest_model_ratio", controller.ResetModelRatio)
 			optionRoute.POST("/migrate_console_setting", controller.MigrateConsoleSetting) // 用于迁移检测的旧键，下个版本会删除
 		}
+		// 管理员角色，仅超级管理员可管理
+		adminRoleRoute := apiRouter.Group("/role")
+		adminRoleRoute.Use(middleware.RootAuth())
+		{
+			adminRoleRoute.GET("/", controller.GetAdminRoles)
+			adminRoleRoute.POST("/", middleware.SudoAuth(), controller.CreateAdminRole)
+			adminRoleRoute.PUT("/user", middleware.SudoAuth(), controller.SetUserAdminRole)
+			adminRoleRoute.PUT("/:id", middleware.SudoAuth(), controller.UpdateAdminRole)
+			adminRoleRoute.DELETE("/:id", middleware.SudoAuth(), controller.DeleteAdminRole)
+		}
+		organizationRoute := apiRouter.Group("/organization")
+		organizationRoute.Use(middleware.UserAuth())
+		{
+			organizationRoute.GET("/self", controller.GetSelfOrganization)
+			organizationRoute.POST("/", controller.CreateOrganization)
+			organizationRoute.DELETE("/", middleware.SudoAuth(), controller.DeleteOrganization)
+			organizationRoute.POST("/deposit", controller.DepositOrganizationQuota)
+			organizationRoute.POST("/leave", controller.LeaveOrganization)
+			organizationRoute.POST("/invitation/accept", controller.AcceptOrganizationInvitation)
+			organizationRoute.DELETE("/invitation", controller.DeclineOrganizationInvitation)
+			organizationRoute.GET("/members", controller.GetOrganizationMembers)
+			organizationRoute.POST("/members", controller.InviteOrganizationMember)
+			organizationRoute.PUT("/members/:user_id", controller.UpdateOrganizationMember)
+			organizationRoute.DELETE("/members/:user_id", controller.RemoveOrganizationMember)
+			organizationRoute.GET("/usage", controller.GetOrganizationUsage)
+		}
+		organizationAdminRoute := apiRouter.Group("/organization/admin")
+		organizationAdminRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionUserManage))
+		{
+			organizationAdminRoute.GET("/", controller.GetAllOrganizations)
+			organizationAdminRoute.PUT("/", controller.UpdateOrganization)
+		}
+		inviteRewardRoute := apiRouter.Group("/invite_reward")
+		inviteRewardRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionUserManage))
+		{
+			inviteRewardRoute.GET("/", controller.GetInviteRewards)
+			inviteRewardRoute.POST("/:id/approve", controller.ApproveInviteReward)
+			inviteRewardRoute.POST("/:id/reject", controller.RejectInviteReward)
+		}
+		auditLogRoute := apiRouter.Group("/audit_log")
+		auditLogRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionLogManage))
+		{
+			auditLogRoute.GET("/", controller.GetAuditLogs)
+			auditLogRoute.GET("/actions", controller.GetAuditLogActions)
+			auditLogRoute.GET("/export", controller.ExportAuditLogs)
+		}
 		ratioSyncRoute := apiRouter.Group("/ratio_sync")
 		ratioSyncRoute.Use(middleware.RootAuth())
 		{
@@ -130,13 +215,14 @@ func SetApiRouter(router *gin.Engine) {
 			ratioSyncRoute.POST("/fetch", controller.FetchUpstreamRatios)
 		}
 		channelRoute := apiRouter.Group("/channel")
-		channelRoute.Use(middleware.AdminAuth())
+		channelRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionChannelManage))
 		{
 			channelRoute.GET("/", controller.GetAllChannels)
 			channelRoute.GET("/search", controller.SearchChannels)
 			channelRoute.GET("/models", controller.ChannelListModels)
 			channelRoute.GET("/models_enabled", controller.EnabledListModels)
 			channelRoute.GET("/:id", controller.GetChannel)
+			channelRoute.GET("/:id/key", middleware.RequirePermission(common.PermissionChannelKey), middleware.SudoAuth(), controller.GetChannelKey)
 			channelRoute.GET("/test", controller.TestAllChannels)
 			channelRoute.GET("/test/:id", controller.TestChannel)
 			channelRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
@@ -152,6 +238,7 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.POST("/fix", controller.FixChannelsAbilities)
 			channelRoute.GET("/fetch_models/:id", controller.FetchUpstreamModels)
 			channelRoute.POST("/fetch_models", controller.FetchModels)
+			channelRoute.POST("/ollama/pull/:id", controller.PullOllamaModel)
 			channelRoute.POST("/batch/tag", controller.BatchSetChannelTag)
 			channelRoute.GET("/tag/models", controller.GetTagModels)
 			channelRoute.POST("/copy/:id", controller.CopyChannel)
@@ -168,7 +255,7 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.POST("/batch", controller.DeleteTokenBatch)
 		}
 		redemptionRoute := apiRouter.Group("/redemption")
-		redemptionRoute.Use(middleware.AdminAuth())
+		redemptionRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionRedemptionManage))
 		{
 			redemptionRoute.GET("/", controller.GetAllRedemptions)
 			redemptionRoute.GET("/search", controller.SearchRedemptions)
@@ -178,17 +265,30 @@ func SetApiRouter(router *gin.Engine) {
 			redemptionRoute.DELETE("/invalid", controller.DeleteInvalidRedemption)
 			redemptionRoute.DELETE("/:id", controller.DeleteRedemption)
 		}
+		topUpRoute := apiRouter.Group("/topup")
+		topUpRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionTopUpManage))
+		{
+			topUpRoute.GET("/", controller.GetAllTopUps)
+			topUpRoute.POST("/:id/complete", controller.CompleteTopUp)
+		}
+		logRead := middleware.RequirePermission(common.PermissionLogRead, common.PermissionLogManage)
+		logManage := middleware.RequirePermission(common.PermissionLogManage)
 		logRoute := apiRouter.Group("/log")
-		logRoute.GET("/", middleware.AdminAuth(), controller.GetAllLogs)
-		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
-		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/", middleware.AdminAuth(), logRead, controller.GetAllLogs)
+		logRoute.DELETE("/", middleware.AdminAuth(), logManage, controller.DeleteHistoryLogs)
+		logRoute.POST("/archive", middleware.AdminAuth(), logManage, controller.ArchiveHistoryLogs)
+		logRoute.GET("/archive", middleware.AdminAuth(), logRead, controller.GetLogArchives)
+		logRoute.GET("/archive/query", middleware.AdminAuth(), logRead, controller.QueryArchivedLogs)
+		logRoute.GET("/stat", middleware.AdminAuth(), logRead, controller.GetLogsStat)
 		logRoute.GET("/self/stat", middleware.APIAuth(), controller.GetLogsSelfStat)
-		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
+		logRoute.GET("/search", middleware.AdminAuth(), logRead, controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.APIAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.APIAuth(), controller.SearchUserLogs)
+		logRoute.GET("/export", middleware.AdminAuth(), logRead, controller.ExportAllLogs)
+		logRoute.GET("/self/export", middleware.APIAuth(), controller.ExportUserLogs)
 
 		dataRoute := apiRouter.Group("/data")
-		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
+		dataRoute.GET("/", middleware.AdminAuth(), logRead, controller.GetAllQuotaDates)
 		dataRoute.GET("/self", middleware.APIAuth(), controller.GetUserQuotaDates)
 
 		logRoute.Use(middleware.CORS())
@@ -197,18 +297,18 @@ func SetApiRouter(router *gin.Engine) {
 
 		}
 		groupRoute := apiRouter.Group("/group")
-		groupRoute.Use(middleware.AdminAuth())
+		groupRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionChannelManage, common.PermissionUserManage, common.PermissionRedemptionManage))
 		{
 			groupRoute.GET("/", controller.GetGroups)
 		}
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.APIAuth(), controller.GetUserMidjourney)
-		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
+		mjRoute.GET("/", middleware.AdminAuth(), logRead, controller.GetAllMidjourney)
 
 		taskRoute := apiRouter.Group("/task")
 		{
 			taskRoute.GET("/self", middleware.APIAuth(), controller.GetUserTask)
-			taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+			taskRoute.GET("/", middleware.AdminAuth(), logRead, controller.GetAllTask)
 		}
 
 		// 订阅相关路由
@@ -227,7 +327,7 @@ func SetApiRouter(router *gin.Engine) {
 
 		// 订阅文章管理路由（管理员功能）
 		subscriptionArticleRoute := apiRouter.Group("/subscription_articles")
-		subscriptionArticleRoute.Use(middleware.AdminAuth())
+		subscriptionArticleRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionContentManage))
 		{
 			subscriptionArticleRoute.POST("/", controller.CreateSubscriptionArticle) // 创建订阅文章
 		}
@@ -241,7 +341,7 @@ func SetApiRouter(router *gin.Engine) {
 
 		// 系统推荐管理路由（管理员功能）
 		recommendationRoute := apiRouter.Group("/recommendations")
-		recommendationRoute.Use(middleware.AdminAuth())
+		recommendationRoute.Use(middleware.AdminAuth(), middleware.RequirePermission(common.PermissionContentManage))
 		{
 			recommendationRoute.POST("/", controller.CreateSystemRecommendation)      // 创建系统推荐
 			recommendationRoute.PUT("/:id", controller.UpdateSystemRecommendation)    // 更新系统推荐
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"one-api/common/metrics"
+	"one-api/middleware"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetMetricsRouter(router *gin.Engine) {
+	model.RegisterMetrics()
+	router.GET("/metrics", middleware.MetricsAuth(), gin.WrapH(metrics.Handler()))
+}
@@ -33,6 +33,12 @@ func SetRelayRouter(router *gin.Engine) {
 		wsRouter.Use(middleware.Distribute())
 		wsRouter.GET("/realtime", controller.WssRelay)
 	}
+	{
+		// 本地保存的 Responses API 响应，不需要分发渠道
+		responsesRouter := relayV1Router.Group("/responses")
+		responsesRouter.GET("/:id", controller.GetStoredResponse)
+		responsesRouter.DELETE("/:id", controller.DeleteStoredResponse)
+	}
 	{
 		//http router
 		httpRouter := relayV1Router.Group("")
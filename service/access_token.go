@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const accessTokenIssuer = "one-api"
+
+// AccessTokenClaims 登录签发的访问令牌，Subject 为用户 ID，SessionId 为所属设备会话
+type AccessTokenClaims struct {
+	SessionId int `json:"sid"`
+	jwt.StandardClaims
+}
+
+func (c *AccessTokenClaims) UserId() int {
+	id, _ := strconv.Atoi(c.Subject)
+	return id
+}
+
+func accessTokenSecret() []byte {
+	return []byte(common.SessionSecret)
+}
+
+// IsJWTAccessToken 区分 JWT 访问令牌与旧版永久 access token
+func IsJWTAccessToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// GenerateAccessToken 为设备会话签发短期访问令牌，返回令牌与有效期（秒）
+func GenerateAccessToken(userId int, sessionId int) (string, int64, error) {
+	return signAccessToken(userId, sessionId, int64(common.AccessTokenExpireSeconds))
+}
+
+// GenerateSystemAccessToken 为系统访问令牌会话签发与会话同时过期的访问令牌，供脚本调用管理接口
+func GenerateSystemAccessToken(userId int, sessionId int, expiresAt int64) (string, int64, error) {
+	return signAccessToken(userId, sessionId, expiresAt-time.Now().Unix())
+}
+
+func signAccessToken(userId int, sessionId int, expiresIn int64) (string, int64, error) {
+	now := time.Now()
+	claims := AccessTokenClaims{
+		SessionId: sessionId,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.Itoa(userId),
+			Issuer:    accessTokenIssuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Unix() + expiresIn,
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(accessTokenSecret())
+	if err != nil {
+		return "", 0, err
+	}
+	return token, expiresIn, nil
+}
+
+// ParseAccessToken 校验访问令牌的签名与有效期，不检查会话是否已吊销
+func ParseAccessToken(token string) (*AccessTokenClaims, error) {
+	claims := &AccessTokenClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return accessTokenSecret(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !parsed.Valid || claims.Issuer != accessTokenIssuer || claims.UserId() == 0 || claims.SessionId == 0 {
+		return nil, errors.New("invalid access token")
+	}
+	return claims, nil
+}
+
+const (
+	twoFALoginAudience      = "2fa_login"
+	twoFALoginExpireSeconds = 5 * 60
+)
+
+// GenerateTwoFALoginToken 密码校验通过但需要两步验证时签发的临时令牌，仅可用于完成登录
+func GenerateTwoFALoginToken(userId int) (string, int64, error) {
+	now := time.Now()
+	claims := jwt.StandardClaims{
+		Subject:   strconv.Itoa(userId),
+		Issuer:    accessTokenIssuer,
+		Audience:  twoFALoginAudience,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Unix() + twoFALoginExpireSeconds,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(accessTokenSecret())
+	if err != nil {
+		return "", 0, err
+	}
+	return token, twoFALoginExpireSeconds, nil
+}
+
+// ParseTwoFALoginToken 校验两步验证临时令牌，返回用户 ID
+func ParseTwoFALoginToken(token string) (int, error) {
+	claims := &jwt.StandardClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return accessTokenSecret(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	userId, _ := strconv.Atoi(claims.Subject)
+	if !parsed.Valid || claims.Issuer != accessTokenIssuer || !claims.VerifyAudience(twoFALoginAudience, true) || userId == 0 {
+		return 0, errors.New("invalid two-factor login token")
+	}
+	return userId, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"time"
+)
+
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	data, err := common.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// WriteUserDataExport 将用户的个人资料、令牌（已脱敏）、话题、会话、订阅与使用日志打包为 zip 写入 w
+func WriteUserDataExport(ctx context.Context, userId int, w io.Writer) error {
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		return err
+	}
+	user.AccessToken = nil
+	tokens, err := model.GetUserTokensForExport(userId)
+	if err != nil {
+		return err
+	}
+	topics, err := model.GetUserTopicsForExport(userId)
+	if err != nil {
+		return err
+	}
+	sessions, err := model.GetUserChatSessionsForExport(userId)
+	if err != nil {
+		return err
+	}
+	subscriptions, err := model.GetUserSubscriptionsForExport(userId)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"tokens.json", tokens},
+		{"topics.json", topics},
+		{"chat_sessions.json", sessions},
+		{"subscriptions.json", subscriptions},
+	}
+	for _, file := range files {
+		if err = writeZipJSON(zw, file.name, file.data); err != nil {
+			return err
+		}
+	}
+	logFile, err := zw.Create("logs.jsonl")
+	if err != nil {
+		return err
+	}
+	err = model.IterateLogs(ctx, model.LogExportFilter{UserId: userId}, 1000, func(logs []*model.Log) error {
+		for _, log := range logs {
+			data, err := common.Marshal(log)
+			if err != nil {
+				return err
+			}
+			if _, err = logFile.Write(append(data, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ExecuteDueAccountDeletions 执行冷静期已结束的注销申请，返回成功执行的数量
+func ExecuteDueAccountDeletions() (int, error) {
+	deletions, err := model.GetDueAccountDeletions(common.GetTimestamp(), 100)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, deletion := range deletions {
+		if err := deletion.Execute(); err != nil {
+			common.SysError(fmt.Sprintf("failed to delete account of user %d: %s", deletion.UserId, err.Error()))
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+func StartAccountDeletionTask() {
+	for {
+		interval := operation_setting.GetAccountDeletionSetting().IntervalMinutes
+		if interval <= 0 {
+			interval = 60
+		}
+		count, err := ExecuteDueAccountDeletions()
+		if err != nil {
+			common.SysError("failed to execute account deletions: " + err.Error())
+		} else if count > 0 {
+			common.SysLog(fmt.Sprintf("account deletion finished, %d accounts deleted", count))
+		}
+		time.Sleep(time.Duration(interval) * time.Minute)
+	}
+}
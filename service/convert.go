@@ -63,6 +63,7 @@ func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.Re
 		openAITools = append(openAITools, openAITool)
 	}
 	openAIRequest.Tools = openAITools
+	openAIRequest.ToolChoice = toolChoiceClaude2OpenAI(claudeRequest.ToolChoice)
 
 	// Convert messages
 	openAIMessages := make([]dto.Message, 0)
@@ -174,7 +175,7 @@ func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.Re
 				openAIMessage.SetToolCalls(toolCalls)
 			}
 
-			if len(mediaMessages) > 0 && len(toolCalls) == 0 {
+			if len(mediaMessages) > 0 {
 				openAIMessage.SetMediaContent(mediaMessages)
 			}
 		}
@@ -188,6 +189,28 @@ func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.Re
 	return &openAIRequest, nil
 }
 
+// toolChoiceClaude2OpenAI 转换 tool_choice：auto/none 保持不变，any 对应 required，tool 对应指定函数
+func toolChoiceClaude2OpenAI(toolChoice any) any {
+	choice, ok := toolChoice.(map[string]any)
+	if !ok {
+		return nil
+	}
+	switch choice["type"] {
+	case "auto", "none":
+		return choice["type"]
+	case "any":
+		return "required"
+	case "tool":
+		return map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name": choice["name"],
+			},
+		}
+	}
+	return nil
+}
+
 func OpenAIErrorToClaudeError(openAIError *dto.OpenAIErrorWithStatusCode) *dto.ClaudeErrorWithStatusCode {
 	claudeError := dto.ClaudeError{
 		Type:    "new_api_error",
@@ -235,146 +258,117 @@ func StreamResponseOpenAI2Claude(openAIResponse *dto.ChatCompletionsStreamRespon
 			Type:    "message_start",
 			Message: msg,
 		})
-		claudeResponses = append(claudeResponses)
-		//claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-		//	Type: "ping",
-		//})
-		if openAIResponse.IsToolCall() {
-			resp := &dto.ClaudeResponse{
-				Type: "content_block_start",
-				ContentBlock: &dto.ClaudeMediaMessage{
-					Id:   openAIResponse.GetFirstToolCall().ID,
-					Type: "tool_use",
-					Name: openAIResponse.GetFirstToolCall().Function.Name,
-				},
-			}
-			resp.SetIndex(0)
-			claudeResponses = append(claudeResponses, resp)
-		} else {
-			//resp := &dto.ClaudeResponse{
-			//	Type: "content_block_start",
-			//	ContentBlock: &dto.ClaudeMediaMessage{
-			//		Type: "text",
-			//		Text: common.GetPointer[string](""),
-			//	},
-			//}
-			//resp.SetIndex(0)
-			//claudeResponses = append(claudeResponses, resp)
-		}
-		return claudeResponses
 	}
 
-	if len(openAIResponse.Choices) == 0 {
-		// no choices
-		// TODO: handle this case
-		return claudeResponses
-	} else {
+	// 首个分片与最后一个分片也可能携带内容，需与普通分片一样处理
+	if len(openAIResponse.Choices) > 0 {
 		chosenChoice := openAIResponse.Choices[0]
+		claudeResponses = append(claudeResponses, streamDeltaOpenAI2Claude(&chosenChoice.Delta, info)...)
 		if chosenChoice.FinishReason != nil && *chosenChoice.FinishReason != "" {
-			// should be done
 			info.FinishReason = *chosenChoice.FinishReason
-			return claudeResponses
 		}
-		if info.Done {
+	}
+
+	if info.Done {
+		if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeNone {
 			claudeResponses = append(claudeResponses, generateStopBlock(info.ClaudeConvertInfo.Index))
-			oaiUsage := info.ClaudeConvertInfo.Usage
-			if oaiUsage != nil {
-				claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-					Type: "message_delta",
-					Usage: &dto.ClaudeUsage{
-						InputTokens:              oaiUsage.PromptTokens,
-						OutputTokens:             oaiUsage.CompletionTokens,
-						CacheCreationInputTokens: oaiUsage.PromptTokensDetails.CachedCreationTokens,
-						CacheReadInputTokens:     oaiUsage.PromptTokensDetails.CachedTokens,
-					},
-					Delta: &dto.ClaudeMediaMessage{
-						StopReason: common.GetPointer[string](stopReasonOpenAI2Claude(info.FinishReason)),
-					},
-				})
-			}
+		}
+		oaiUsage := info.ClaudeConvertInfo.Usage
+		if oaiUsage != nil {
 			claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-				Type: "message_stop",
+				Type: "message_delta",
+				Usage: &dto.ClaudeUsage{
+					InputTokens:              oaiUsage.PromptTokens,
+					OutputTokens:             oaiUsage.CompletionTokens,
+					CacheCreationInputTokens: oaiUsage.PromptTokensDetails.CachedCreationTokens,
+					CacheReadInputTokens:     oaiUsage.PromptTokensDetails.CachedTokens,
+				},
+				Delta: &dto.ClaudeMediaMessage{
+					StopReason: common.GetPointer[string](stopReasonOpenAI2Claude(info.FinishReason)),
+				},
+			})
+		}
+		claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
+			Type: "message_stop",
+		})
+	}
+
+	return claudeResponses
+}
+
+// streamDeltaOpenAI2Claude 将一个 OpenAI 流式增量转换为 Claude 内容块事件，
+// 内容类型切换（思考、文本、不同的工具调用）时关闭上一个内容块并开启新的内容块
+func streamDeltaOpenAI2Claude(delta *dto.ChatCompletionsStreamResponseChoiceDelta, info *relaycommon.RelayInfo) []*dto.ClaudeResponse {
+	var claudeResponses []*dto.ClaudeResponse
+	convertInfo := info.ClaudeConvertInfo
+	startBlock := func(messageType string, contentBlock *dto.ClaudeMediaMessage) {
+		if convertInfo.LastMessagesType != relaycommon.LastMessageTypeNone {
+			claudeResponses = append(claudeResponses, generateStopBlock(convertInfo.Index))
+			convertInfo.Index++
+		}
+		convertInfo.LastMessagesType = messageType
+		claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
+			Index:        common.GetPointer[int](convertInfo.Index),
+			Type:         "content_block_start",
+			ContentBlock: contentBlock,
+		})
+	}
+	appendDelta := func(d *dto.ClaudeMediaMessage) {
+		claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
+			Index: common.GetPointer[int](convertInfo.Index),
+			Type:  "content_block_delta",
+			Delta: d,
+		})
+	}
+
+	if reasoning := delta.GetReasoningContent(); reasoning != "" {
+		if convertInfo.LastMessagesType != relaycommon.LastMessageTypeThinking {
+			startBlock(relaycommon.LastMessageTypeThinking, &dto.ClaudeMediaMessage{
+				Type:     "thinking",
+				Thinking: "",
+			})
+		}
+		appendDelta(&dto.ClaudeMediaMessage{
+			Type:     "thinking_delta",
+			Thinking: reasoning,
+		})
+	}
+
+	if textContent := delta.GetContentString(); textContent != "" {
+		if convertInfo.LastMessagesType != relaycommon.LastMessageTypeText {
+			startBlock(relaycommon.LastMessageTypeText, &dto.ClaudeMediaMessage{
+				Type: "text",
+				Text: common.GetPointer[string](""),
 			})
-		} else {
-			var claudeResponse dto.ClaudeResponse
-			var isEmpty bool
-			claudeResponse.Type = "content_block_delta"
-			if len(chosenChoice.Delta.ToolCalls) > 0 {
-				if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeTools {
-					claudeResponses = append(claudeResponses, generateStopBlock(info.ClaudeConvertInfo.Index))
-					info.ClaudeConvertInfo.Index++
-					claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-						Index: &info.ClaudeConvertInfo.Index,
-						Type:  "content_block_start",
-						ContentBlock: &dto.ClaudeMediaMessage{
-							Id:    openAIResponse.GetFirstToolCall().ID,
-							Type:  "tool_use",
-							Name:  openAIResponse.GetFirstToolCall().Function.Name,
-							Input: map[string]interface{}{},
-						},
-					})
-				}
-				info.ClaudeConvertInfo.LastMessagesType = relaycommon.LastMessageTypeTools
-				// tools delta
-				claudeResponse.Delta = &dto.ClaudeMediaMessage{
-					Type:        "input_json_delta",
-					PartialJson: &chosenChoice.Delta.ToolCalls[0].Function.Arguments,
-				}
-			} else {
-				reasoning := chosenChoice.Delta.GetReasoningContent()
-				textContent := chosenChoice.Delta.GetContentString()
-				if reasoning != "" || textContent != "" {
-					if reasoning != "" {
-						if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeThinking {
-							//info.ClaudeConvertInfo.Index++
-							claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-								Index: &info.ClaudeConvertInfo.Index,
-								Type:  "content_block_start",
-								ContentBlock: &dto.ClaudeMediaMessage{
-									Type:     "thinking",
-									Thinking: "",
-								},
-							})
-						}
-						info.ClaudeConvertInfo.LastMessagesType = relaycommon.LastMessageTypeThinking
-						// text delta
-						claudeResponse.Delta = &dto.ClaudeMediaMessage{
-							Type:     "thinking_delta",
-							Thinking: reasoning,
-						}
-					} else {
-						if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeText {
-							if info.LastMessagesType == relaycommon.LastMessageTypeThinking || info.LastMessagesType == relaycommon.LastMessageTypeTools {
-								claudeResponses = append(claudeResponses, generateStopBlock(info.ClaudeConvertInfo.Index))
-								info.ClaudeConvertInfo.Index++
-							}
-							claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-								Index: &info.ClaudeConvertInfo.Index,
-								Type:  "content_block_start",
-								ContentBlock: &dto.ClaudeMediaMessage{
-									Type: "text",
-									Text: common.GetPointer[string](""),
-								},
-							})
-						}
-						info.ClaudeConvertInfo.LastMessagesType = relaycommon.LastMessageTypeText
-						// text delta
-						claudeResponse.Delta = &dto.ClaudeMediaMessage{
-							Type: "text_delta",
-							Text: common.GetPointer[string](textContent),
-						}
-					}
-				} else {
-					isEmpty = true
-				}
-			}
-			claudeResponse.Index = &info.ClaudeConvertInfo.Index
-			if !isEmpty {
-				claudeResponses = append(claudeResponses, &claudeResponse)
-			}
 		}
+		appendDelta(&dto.ClaudeMediaMessage{
+			Type: "text_delta",
+			Text: common.GetPointer[string](textContent),
+		})
 	}
 
+	for i := range delta.ToolCalls {
+		toolCall := &delta.ToolCalls[i]
+		toolCallIndex := 0
+		if toolCall.Index != nil {
+			toolCallIndex = *toolCall.Index
+		}
+		if convertInfo.LastMessagesType != relaycommon.LastMessageTypeTools || toolCallIndex != convertInfo.ToolCallIndex {
+			convertInfo.ToolCallIndex = toolCallIndex
+			startBlock(relaycommon.LastMessageTypeTools, &dto.ClaudeMediaMessage{
+				Id:    toolCall.ID,
+				Type:  "tool_use",
+				Name:  toolCall.Function.Name,
+				Input: map[string]interface{}{},
+			})
+		}
+		if toolCall.Function.Arguments != "" {
+			appendDelta(&dto.ClaudeMediaMessage{
+				Type:        "input_json_delta",
+				PartialJson: common.GetPointer[string](toolCall.Function.Arguments),
+			})
+		}
+	}
 	return claudeResponses
 }
 
@@ -389,28 +383,48 @@ func ResponseOpenAI2Claude(openAIResponse *dto.OpenAITextResponse, info *relayco
 	}
 	for _, choice := range openAIResponse.Choices {
 		stopReason = stopReasonOpenAI2Claude(choice.FinishReason)
-		claudeContent := dto.ClaudeMediaMessage{}
-		if choice.FinishReason == "tool_calls" {
-			claudeContent.Type = "tool_use"
-			claudeContent.Id = choice.Message.ToolCallId
-			claudeContent.Name = choice.Message.ParseToolCalls()[0].Function.Name
+		reasoning := choice.Message.ReasoningContent
+		if reasoning == "" {
+			reasoning = choice.Message.Reasoning
+		}
+		if reasoning != "" {
+			contents = append(contents, dto.ClaudeMediaMessage{
+				Type:     "thinking",
+				Thinking: reasoning,
+			})
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			claudeContent := dto.ClaudeMediaMessage{Type: "text"}
+			claudeContent.SetText(text)
+			contents = append(contents, claudeContent)
+		}
+		for _, toolCall := range choice.Message.ParseToolCalls() {
+			claudeContent := dto.ClaudeMediaMessage{
+				Type: "tool_use",
+				Id:   toolCall.ID,
+				Name: toolCall.Function.Name,
+			}
 			var mapParams map[string]interface{}
-			if err := json.Unmarshal([]byte(choice.Message.ParseToolCalls()[0].Function.Arguments), &mapParams); err == nil {
+			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &mapParams); err == nil {
 				claudeContent.Input = mapParams
 			} else {
-				claudeContent.Input = choice.Message.ParseToolCalls()[0].Function.Arguments
+				claudeContent.Input = toolCall.Function.Arguments
 			}
-		} else {
-			claudeContent.Type = "text"
-			claudeContent.SetText(choice.Message.StringContent())
+			contents = append(contents, claudeContent)
 		}
+	}
+	if len(contents) == 0 {
+		claudeContent := dto.ClaudeMediaMessage{Type: "text"}
+		claudeContent.SetText("")
 		contents = append(contents, claudeContent)
 	}
 	claudeResponse.Content = contents
 	claudeResponse.StopReason = stopReason
 	claudeResponse.Usage = &dto.ClaudeUsage{
-		InputTokens:  openAIResponse.PromptTokens,
-		OutputTokens: openAIResponse.CompletionTokens,
+		InputTokens:              openAIResponse.PromptTokens,
+		OutputTokens:             openAIResponse.CompletionTokens,
+		CacheCreationInputTokens: openAIResponse.PromptTokensDetails.CachedCreationTokens,
+		CacheReadInputTokens:     openAIResponse.PromptTokensDetails.CachedTokens,
 	}
 
 	return claudeResponse
@@ -422,7 +436,7 @@ func stopReasonOpenAI2Claude(reason string) string {
 		return "end_turn"
 	case "stop_sequence":
 		return "stop_sequence"
-	case "max_tokens":
+	case "max_tokens", "length":
 		return "max_tokens"
 	case "tool_calls":
 		return "tool_use"
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+)
+
+var update = flag.Bool("update", false, "重新生成 testdata 中的期望结果")
+
+// goldenCases 返回 dir 下以 inputSuffix 结尾的录制样本，键为样本名
+func goldenCases(t *testing.T, dir string, inputSuffix string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+inputSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no fixtures in %s", dir)
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), inputSuffix))
+	}
+	return names
+}
+
+// assertGoldenJSON 按 JSON 语义比较 got 与期望文件，-update 时改为写入期望文件
+func assertGoldenJSON(t *testing.T, path string, got any) {
+	t.Helper()
+	gotBytes, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(path, append(gotBytes, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	wantBytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotValue, wantValue any
+	if err := json.Unmarshal(gotBytes, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(wantBytes, &wantValue); err != nil {
+		t.Fatalf("invalid fixture %s: %v", path, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("%s mismatch\n got: %s\nwant: %s", filepath.Base(path), gotBytes, bytes.TrimSpace(wantBytes))
+	}
+}
+
+func readFixture(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("invalid fixture %s: %v", path, err)
+	}
+}
+
+func newClaudeRelayInfo() *relaycommon.RelayInfo {
+	return &relaycommon.RelayInfo{
+		RelayFormat:       relaycommon.RelayFormatClaude,
+		OriginModelName:   "test-model",
+		UpstreamModelName: "test-model",
+		PromptTokens:      12,
+		ClaudeConvertInfo: &relaycommon.ClaudeConvertInfo{
+			LastMessagesType: relaycommon.LastMessageTypeNone,
+		},
+	}
+}
+
+func TestClaudeToOpenAIRequest(t *testing.T) {
+	dir := filepath.Join("testdata", "claude_to_openai")
+	for _, name := range goldenCases(t, dir, ".claude.json") {
+		t.Run(name, func(t *testing.T) {
+			var request dto.ClaudeRequest
+			readFixture(t, filepath.Join(dir, name+".claude.json"), &request)
+			openAIRequest, err := ClaudeToOpenAIRequest(request, newClaudeRelayInfo())
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGoldenJSON(t, filepath.Join(dir, name+".openai.json"), openAIRequest)
+		})
+	}
+}
+
+func TestResponseOpenAI2Claude(t *testing.T) {
+	dir := filepath.Join("testdata", "openai_to_claude")
+	for _, name := range goldenCases(t, dir, ".openai.json") {
+		t.Run(name, func(t *testing.T) {
+			var response dto.OpenAITextResponse
+			readFixture(t, filepath.Join(dir, name+".openai.json"), &response)
+			claudeResponse := ResponseOpenAI2Claude(&response, newClaudeRelayInfo())
+			assertGoldenJSON(t, filepath.Join(dir, name+".claude.json"), claudeResponse)
+		})
+	}
+}
+
+// TestStreamResponseOpenAI2Claude 录制的上游分片为 JSON 数组，最后一项之后按处理器返回的用量结束流
+func TestStreamResponseOpenAI2Claude(t *testing.T) {
+	dir := filepath.Join("testdata", "openai_to_claude_stream")
+	for _, name := range goldenCases(t, dir, ".openai.json") {
+		t.Run(name, func(t *testing.T) {
+			var recorded struct {
+				Chunks []dto.ChatCompletionsStreamResponse `json:"chunks"`
+				Usage  *dto.Usage                          `json:"usage"`
+			}
+			readFixture(t, filepath.Join(dir, name+".openai.json"), &recorded)
+			info := newClaudeRelayInfo()
+			var events []*dto.ClaudeResponse
+			for i := range recorded.Chunks {
+				info.SendResponseCount++
+				events = append(events, StreamResponseOpenAI2Claude(&recorded.Chunks[i], info)...)
+			}
+			info.SendResponseCount++
+			info.ClaudeConvertInfo.Done = true
+			info.ClaudeConvertInfo.Usage = recorded.Usage
+			events = append(events, StreamResponseOpenAI2Claude(&dto.ChatCompletionsStreamResponse{}, info)...)
+			assertGoldenJSON(t, filepath.Join(dir, name+".claude.json"), events)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"time"
+)
+
+// VestInviteRewards 发放被邀请用户已完成充值或消耗达到门槛的奖励，返回发放数量
+func VestInviteRewards() (int, error) {
+	setting := operation_setting.GetInviteRewardSetting()
+	rewards, err := model.GetVestableInviteRewards(setting.UsageThreshold, 100)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, reward := range rewards {
+		if err := reward.Vest(); err != nil {
+			common.SysError(fmt.Sprintf("failed to vest invite reward %d: %s", reward.Id, err.Error()))
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+func StartInviteRewardTask() {
+	for {
+		interval := operation_setting.GetInviteRewardSetting().IntervalMinutes
+		if interval <= 0 {
+			interval = 10
+		}
+		count, err := VestInviteRewards()
+		if err != nil {
+			common.SysError("failed to vest invite rewards: " + err.Error())
+		} else if count > 0 {
+			common.SysLog(fmt.Sprintf("invite reward vesting finished, %d rewards vested", count))
+		}
+		time.Sleep(time.Duration(interval) * time.Minute)
+	}
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// LogArchiveStorage 归档文件存储，local 为本地磁盘，s3 为 S3 兼容对象存储
+type LogArchiveStorage interface {
+	Name() string
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+type localArchiveStorage struct {
+	dir string
+}
+
+func (s *localArchiveStorage) Name() string {
+	return operation_setting.LogArchiveStorageLocal
+}
+
+func (s *localArchiveStorage) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免留下不完整的归档文件
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (s *localArchiveStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.dir, filepath.FromSlash(key)))
+}
+
+type s3ArchiveStorage struct {
+	endpoint    string
+	region      string
+	bucket      string
+	credentials aws.Credentials
+}
+
+func (s *s3ArchiveStorage) Name() string {
+	return operation_setting.LogArchiveStorageS3
+}
+
+// objectURL 使用 path-style 地址，兼容 MinIO 等 S3 兼容存储
+func (s *s3ArchiveStorage) objectURL(key string) string {
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.endpoint, "/"), url.PathEscape(s.bucket), key)
+}
+
+func (s *s3ArchiveStorage) do(ctx context.Context, method string, key string, data []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	payloadHash := hex.EncodeToString(hash[:])
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+	err = v4.NewSigner().SignHTTP(ctx, s.credentials, req, payloadHash, "s3", s.region, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s failed: status %d, %s", method, key, resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+func (s *s3ArchiveStorage) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *s3ArchiveStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func getLogArchiveStorage(name string) (LogArchiveStorage, error) {
+	archiveSetting := operation_setting.GetLogArchiveSetting()
+	switch name {
+	case operation_setting.LogArchiveStorageLocal, "":
+		if archiveSetting.LocalDir == "" {
+			return nil, errors.New("未配置日志归档目录")
+		}
+		return &localArchiveStorage{dir: archiveSetting.LocalDir}, nil
+	case operation_setting.LogArchiveStorageS3:
+		if archiveSetting.S3Endpoint == "" || archiveSetting.S3Bucket == "" {
+			return nil, errors.New("未配置 S3 归档存储")
+		}
+		return &s3ArchiveStorage{
+			endpoint: archiveSetting.S3Endpoint,
+			region:   archiveSetting.S3Region,
+			bucket:   archiveSetting.S3Bucket,
+			credentials: aws.Credentials{
+				AccessKeyID:     archiveSetting.S3AccessKeyId,
+				SecretAccessKey: archiveSetting.S3SecretAccessKey,
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的日志归档存储: %s", name)
+	}
+}
+
+var logArchiveLock sync.Mutex
+
+// ArchiveOldLogs 将早于 targetTimestamp 的日志按批写入压缩的 JSONL 文件，上传成功后写入清单并删除原日志
+func ArchiveOldLogs(ctx context.Context, targetTimestamp int64) (total int, err error) {
+	if !logArchiveLock.TryLock() {
+		return 0, errors.New("日志归档任务正在执行")
+	}
+	defer logArchiveLock.Unlock()
+
+	archiveSetting := operation_setting.GetLogArchiveSetting()
+	storage, err := getLogArchiveStorage(archiveSetting.Storage)
+	if err != nil {
+		return 0, err
+	}
+	rowsPerFile := archiveSetting.RowsPerFile
+	if rowsPerFile <= 0 {
+		rowsPerFile = 50000
+	}
+	const pageSize = 1000
+
+	lastId := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		archive := &model.LogArchive{Storage: storage.Name()}
+		for archive.Count < rowsPerFile {
+			logs, err := model.GetLogsToArchive(ctx, targetTimestamp, lastId, min(pageSize, rowsPerFile-archive.Count))
+			if err != nil {
+				return total, err
+			}
+			for _, log := range logs {
+				data, err := common.Marshal(log)
+				if err != nil {
+					return total, err
+				}
+				if _, err = gz.Write(append(data, '\n')); err != nil {
+					return total, err
+				}
+				if archive.Count == 0 || log.CreatedAt < archive.StartTime {
+					archive.StartTime = log.CreatedAt
+				}
+				if log.CreatedAt > archive.EndTime {
+					archive.EndTime = log.CreatedAt
+				}
+				if archive.StartId == 0 {
+					archive.StartId = log.Id
+				}
+				archive.EndId = log.Id
+				archive.Count++
+			}
+			if len(logs) > 0 {
+				lastId = logs[len(logs)-1].Id
+			}
+			if len(logs) < pageSize {
+				break
+			}
+		}
+		if err := gz.Close(); err != nil {
+			return total, err
+		}
+		if archive.Count == 0 {
+			return total, nil
+		}
+
+		archive.Path = fmt.Sprintf("logs/%s/logs-%d-%d.jsonl.gz",
+			time.Unix(archive.StartTime, 0).Format("2006/01"), archive.StartId, archive.EndId)
+		archive.Size = int64(buf.Len())
+		archive.CreatedAt = common.GetTimestamp()
+		if err := storage.Put(ctx, archive.Path, buf.Bytes()); err != nil {
+			return total, err
+		}
+		if err := model.CommitLogArchive(archive, targetTimestamp); err != nil {
+			return total, err
+		}
+		total += archive.Count
+		common.SysLog(fmt.Sprintf("archived %d logs to %s:%s", archive.Count, archive.Storage, archive.Path))
+		if archive.Count < rowsPerFile {
+			return total, nil
+		}
+	}
+}
+
+// ReadLogArchive 逐行读取归档文件中的日志并交给 fn 处理
+func ReadLogArchive(ctx context.Context, archive *model.LogArchive, fn func(log *model.Log) error) error {
+	storage, err := getLogArchiveStorage(archive.Storage)
+	if err != nil {
+		return err
+	}
+	reader, err := storage.Get(ctx, archive.Path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var log model.Log
+		if err := common.Unmarshal(scanner.Bytes(), &log); err != nil {
+			return err
+		}
+		if err := fn(&log); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// StartLogArchiveTask 定时归档超过保留天数的日志
+func StartLogArchiveTask() {
+	for {
+		archiveSetting := operation_setting.GetLogArchiveSetting()
+		interval := archiveSetting.IntervalMinutes
+		if interval <= 0 {
+			interval = 60
+		}
+		if archiveSetting.Enabled && archiveSetting.RetentionDays > 0 {
+			targetTimestamp := time.Now().AddDate(0, 0, -archiveSetting.RetentionDays).Unix()
+			count, err := ArchiveOldLogs(context.Background(), targetTimestamp)
+			if err != nil {
+				common.SysError("failed to archive logs: " + err.Error())
+			} else if count > 0 {
+				common.SysLog(fmt.Sprintf("log archive finished, %d logs archived", count))
+			}
+		}
+		time.Sleep(time.Duration(interval) * time.Minute)
+	}
+}
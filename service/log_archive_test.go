@@ -0,0 +1,318 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"one-api/model"
+	"one-api/setting/operation_setting"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	s3TestAccessKeyId     = "minioadmin"
+	s3TestSecretAccessKey = "minioadmin-secret"
+	s3TestBucket          = "log-archive"
+)
+
+// s3StandIn 仿照 MinIO 的最小 S3 兼容服务：path-style 地址，校验 SigV4 签名与载荷哈希，对象保存在内存中
+type s3StandIn struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newS3StandIn(t *testing.T) *s3StandIn {
+	t.Helper()
+	s := &s3StandIn{objects: make(map[string][]byte)}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *s3StandIn) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := verifySigV4(r, body); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<Error><Code>SignatureDoesNotMatch</Code><Message>" + err.Error() + "</Message></Error>"))
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s3TestBucket {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("<Error><Code>NoSuchBucket</Code></Error>"))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = body
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("<Error><Code>NoSuchKey</Code></Error>"))
+			return
+		}
+		_, _ = w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *s3StandIn) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// verifySigV4 按请求中声明的签名头重新计算签名，与 Authorization 比较
+func verifySigV4(r *http.Request, body []byte) error {
+	hash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(hash[:])
+	if r.Header.Get("x-amz-content-sha256") != payloadHash {
+		return errors.New("payload hash mismatch")
+	}
+	authorization := r.Header.Get("Authorization")
+	_, signedHeaders, ok := strings.Cut(authorization, "SignedHeaders=")
+	if !ok || !strings.Contains(authorization, "Credential="+s3TestAccessKeyId+"/") {
+		return errors.New("missing credential")
+	}
+	signedHeaders, _, _ = strings.Cut(signedHeaders, ",")
+	signTime, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(signedHeaders, ";") {
+		if name != "host" && name != "content-length" {
+			req.Header.Set(name, r.Header.Get(name))
+		}
+	}
+	credentials := aws.Credentials{AccessKeyID: s3TestAccessKeyId, SecretAccessKey: s3TestSecretAccessKey}
+	if err := v4.NewSigner().SignHTTP(context.Background(), credentials, req, payloadHash, "s3", "us-east-1", signTime); err != nil {
+		return err
+	}
+	if req.Header.Get("Authorization") != authorization {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func setupLogArchiveTest(t *testing.T, setting operation_setting.LogArchiveSetting) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "logs.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.Log{}, &model.LogArchive{}); err != nil {
+		t.Fatal(err)
+	}
+	oldLogDB := model.LOG_DB
+	model.LOG_DB = db
+	archiveSetting := operation_setting.GetLogArchiveSetting()
+	oldSetting := *archiveSetting
+	*archiveSetting = setting
+	oldHttpClient := httpClient
+	httpClient = &http.Client{}
+	t.Cleanup(func() {
+		model.LOG_DB = oldLogDB
+		*archiveSetting = oldSetting
+		httpClient = oldHttpClient
+	})
+}
+
+// insertArchiveTestLogs 写入 5 条早于 cutoff 的日志与 2 条较新的日志
+func insertArchiveTestLogs(t *testing.T, cutoff int64) {
+	t.Helper()
+	for i := 0; i < 7; i++ {
+		createdAt := cutoff - int64(5-i)*3600
+		if i >= 5 {
+			createdAt = cutoff + int64(i)*3600
+		}
+		log := &model.Log{
+			UserId:    1,
+			CreatedAt: createdAt,
+			Type:      model.LogTypeConsume,
+			Username:  "archiver",
+			ModelName: "gpt-4o",
+			Quota:     100 + i,
+		}
+		if err := model.LOG_DB.Create(log).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertArchivedLogs(t *testing.T, cutoff int64) {
+	t.Helper()
+	var remaining []*model.Log
+	if err := model.LOG_DB.Order("id").Find(&remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("remaining logs = %d, want 2", len(remaining))
+	}
+	for _, log := range remaining {
+		if log.CreatedAt < cutoff {
+			t.Fatalf("log %d older than cutoff was not archived", log.Id)
+		}
+	}
+
+	archives, err := model.GetLogArchives(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RowsPerFile 为 2，5 条日志分为 3 个文件
+	if len(archives) != 3 {
+		t.Fatalf("archives = %d, want 3", len(archives))
+	}
+	var archived []*model.Log
+	for _, archive := range archives {
+		err := ReadLogArchive(context.Background(), archive, func(log *model.Log) error {
+			if log.Id < archive.StartId || log.Id > archive.EndId || log.CreatedAt < archive.StartTime || log.CreatedAt > archive.EndTime {
+				t.Errorf("log %d outside manifest range %+v", log.Id, archive)
+			}
+			archived = append(archived, log)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(archived) != 5 {
+		t.Fatalf("archived logs = %d, want 5", len(archived))
+	}
+	for i, log := range archived {
+		if log.Id != i+1 || log.Quota != 100+i || log.ModelName != "gpt-4o" {
+			t.Fatalf("archived log %d = %+v", i, log)
+		}
+	}
+
+	// 按时间范围查询清单只返回有交集的归档
+	lastArchive := archives[len(archives)-1]
+	ranged, err := model.GetLogArchives(lastArchive.StartTime, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranged) != 1 || ranged[0].Id != lastArchive.Id {
+		t.Fatalf("ranged archives = %+v, want only %d", ranged, lastArchive.Id)
+	}
+}
+
+func TestArchiveOldLogsToS3(t *testing.T) {
+	s3 := newS3StandIn(t)
+	setupLogArchiveTest(t, operation_setting.LogArchiveSetting{
+		RowsPerFile:       2,
+		Storage:           operation_setting.LogArchiveStorageS3,
+		S3Endpoint:        s3.server.URL,
+		S3Region:          "us-east-1",
+		S3Bucket:          s3TestBucket,
+		S3AccessKeyId:     s3TestAccessKeyId,
+		S3SecretAccessKey: s3TestSecretAccessKey,
+	})
+	cutoff := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Unix()
+	insertArchiveTestLogs(t, cutoff)
+
+	total, err := ArchiveOldLogs(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 {
+		t.Fatalf("archived = %d, want 5", total)
+	}
+	if keys := s3.keys(); len(keys) != 3 {
+		t.Fatalf("uploaded objects = %v, want 3", keys)
+	}
+	assertArchivedLogs(t, cutoff)
+
+	// 再次执行没有可归档的日志
+	total, err = ArchiveOldLogs(context.Background(), cutoff)
+	if err != nil || total != 0 {
+		t.Fatalf("second run archived %d, err %v", total, err)
+	}
+}
+
+func TestArchiveOldLogsKeepsLogsWhenUploadFails(t *testing.T) {
+	s3 := newS3StandIn(t)
+	setupLogArchiveTest(t, operation_setting.LogArchiveSetting{
+		RowsPerFile:       2,
+		Storage:           operation_setting.LogArchiveStorageS3,
+		S3Endpoint:        s3.server.URL,
+		S3Region:          "us-east-1",
+		S3Bucket:          s3TestBucket,
+		S3AccessKeyId:     s3TestAccessKeyId,
+		S3SecretAccessKey: "wrong-secret",
+	})
+	cutoff := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Unix()
+	insertArchiveTestLogs(t, cutoff)
+
+	if _, err := ArchiveOldLogs(context.Background(), cutoff); err == nil {
+		t.Fatal("expected upload to be rejected")
+	}
+	var count int64
+	model.LOG_DB.Model(&model.Log{}).Count(&count)
+	if count != 7 {
+		t.Fatalf("logs = %d, want all 7 kept", count)
+	}
+	archives, _ := model.GetLogArchives(0, 0)
+	if len(archives) != 0 {
+		t.Fatalf("archives = %d, want 0", len(archives))
+	}
+}
+
+func TestArchiveOldLogsToLocalDisk(t *testing.T) {
+	dir := t.TempDir()
+	setupLogArchiveTest(t, operation_setting.LogArchiveSetting{
+		RowsPerFile: 2,
+		Storage:     operation_setting.LogArchiveStorageLocal,
+		LocalDir:    dir,
+	})
+	cutoff := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Unix()
+	insertArchiveTestLogs(t, cutoff)
+
+	total, err := ArchiveOldLogs(context.Background(), cutoff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 {
+		t.Fatalf("archived = %d, want 5", total)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "logs", "*", "*", "*.jsonl.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("archive files = %v, want 3", files)
+	}
+	assertArchivedLogs(t, cutoff)
+}
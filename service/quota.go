@@ -80,7 +80,7 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 	if relayInfo.UsePrice {
 		return nil
 	}
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := GetBillingQuota(relayInfo)
 	if err != nil {
 		return err
 	}
@@ -428,6 +428,29 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	})
 }
 
+// GetBillingQuota 获取本次请求付费方的可用额度：组织令牌为成员可用的组织额度，否则为用户额度
+func GetBillingQuota(relayInfo *relaycommon.RelayInfo) (int, error) {
+	if relayInfo.OrganizationId != 0 {
+		return model.GetOrganizationMemberQuota(relayInfo.OrganizationId, relayInfo.UserId)
+	}
+	return model.GetUserQuota(relayInfo.UserId, false)
+}
+
+// DecreaseBillingQuota 从付费方扣除额度，组织令牌扣组织额度并计入成员已用额度
+func DecreaseBillingQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
+	if relayInfo.OrganizationId != 0 {
+		return model.DecreaseOrganizationQuota(relayInfo.OrganizationId, relayInfo.UserId, quota)
+	}
+	return model.DecreaseUserQuota(relayInfo.UserId, quota)
+}
+
+func IncreaseBillingQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
+	if relayInfo.OrganizationId != 0 {
+		return model.IncreaseOrganizationQuota(relayInfo.OrganizationId, relayInfo.UserId, quota)
+	}
+	return model.IncreaseUserQuota(relayInfo.UserId, quota, false)
+}
+
 func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
@@ -455,9 +478,9 @@ func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQuota int, sendEmail bool) (err error) {
 
 	if quota > 0 {
-		err = model.DecreaseUserQuota(relayInfo.UserId, quota)
+		err = DecreaseBillingQuota(relayInfo, quota)
 	} else {
-		err = model.IncreaseUserQuota(relayInfo.UserId, -quota, false)
+		err = IncreaseBillingQuota(relayInfo, -quota)
 	}
 	if err != nil {
 		return err
@@ -0,0 +1,560 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	"strings"
+)
+
+type responsesInputItem struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	CallId    string          `json:"call_id"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	Output    json.RawMessage `json:"output"`
+}
+
+type responsesInputContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Refusal  string `json:"refusal"`
+	ImageUrl string `json:"image_url"`
+	Detail   string `json:"detail"`
+	FileId   string `json:"file_id"`
+	FileData string `json:"file_data"`
+	Filename string `json:"filename"`
+}
+
+// ResponsesRequestToOpenAIRequest 将 Responses API 请求转换为 Chat Completions 请求，
+// 供不支持 Responses API 的渠道复用各自的 ConvertOpenAIRequest
+func ResponsesRequestToOpenAIRequest(request dto.OpenAIResponsesRequest) (*dto.GeneralOpenAIRequest, error) {
+	openAIRequest := &dto.GeneralOpenAIRequest{
+		Model:     request.Model,
+		Stream:    request.Stream,
+		MaxTokens: request.MaxOutputTokens,
+		TopP:      request.TopP,
+		User:      request.User,
+	}
+	if request.Temperature != 0 {
+		openAIRequest.Temperature = common.GetPointer[float64](request.Temperature)
+	}
+	if request.Stream {
+		openAIRequest.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+	}
+	if request.Reasoning != nil {
+		openAIRequest.ReasoningEffort = request.Reasoning.Effort
+	}
+
+	messages := make([]dto.Message, 0)
+	if len(request.Instructions) > 0 {
+		var instructions string
+		if err := common.Unmarshal(request.Instructions, &instructions); err == nil && instructions != "" {
+			message := dto.Message{Role: "system"}
+			message.SetStringContent(instructions)
+			messages = append(messages, message)
+		}
+	}
+
+	inputMessages, err := responsesInputToMessages(request.Input)
+	if err != nil {
+		return nil, err
+	}
+	openAIRequest.Messages = append(messages, inputMessages...)
+
+	for _, tool := range request.Tools {
+		switch common.Interface2String(tool["type"]) {
+		case "function":
+			openAIRequest.Tools = append(openAIRequest.Tools, dto.ToolCallRequest{
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:        common.Interface2String(tool["name"]),
+					Description: common.Interface2String(tool["description"]),
+					Parameters:  tool["parameters"],
+				},
+			})
+		case dto.BuildInToolWebSearchPreview:
+			openAIRequest.WebSearchOptions = &dto.WebSearchOptions{
+				SearchContextSize: common.Interface2String(tool["search_context_size"]),
+			}
+		}
+	}
+	if len(openAIRequest.Tools) > 0 {
+		openAIRequest.ToolChoice = toolChoiceResponses2OpenAI(request.ToolChoice)
+		if request.ParallelToolCalls {
+			openAIRequest.ParallelTooCalls = common.GetPointer[bool](true)
+		}
+	}
+
+	if len(request.Text) > 0 {
+		var text struct {
+			Format *struct {
+				Type        string `json:"type"`
+				Name        string `json:"name"`
+				Description string `json:"description"`
+				Schema      any    `json:"schema"`
+				Strict      any    `json:"strict"`
+			} `json:"format"`
+		}
+		if err := common.Unmarshal(request.Text, &text); err == nil && text.Format != nil && text.Format.Type != "text" {
+			openAIRequest.ResponseFormat = &dto.ResponseFormat{Type: text.Format.Type}
+			if text.Format.Type == "json_schema" {
+				openAIRequest.ResponseFormat.JsonSchema = &dto.FormatJsonSchema{
+					Name:        text.Format.Name,
+					Description: text.Format.Description,
+					Schema:      text.Format.Schema,
+					Strict:      text.Format.Strict,
+				}
+			}
+		}
+	}
+	return openAIRequest, nil
+}
+
+// responsesInputToMessages 转换 input，连续的 function_call 合并为同一条 assistant 消息
+func responsesInputToMessages(input json.RawMessage) ([]dto.Message, error) {
+	messages := make([]dto.Message, 0)
+	var text string
+	if err := common.Unmarshal(input, &text); err == nil {
+		message := dto.Message{Role: "user"}
+		message.SetStringContent(text)
+		return append(messages, message), nil
+	}
+
+	var items []responsesInputItem
+	if err := common.Unmarshal(input, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	var pendingToolCalls []dto.ToolCallRequest
+	flushToolCalls := func() {
+		if len(pendingToolCalls) == 0 {
+			return
+		}
+		message := dto.Message{Role: "assistant", Content: ""}
+		message.SetToolCalls(pendingToolCalls)
+		messages = append(messages, message)
+		pendingToolCalls = nil
+	}
+	for _, item := range items {
+		switch item.Type {
+		case dto.ResponsesItemTypeFunctionCall:
+			pendingToolCalls = append(pendingToolCalls, dto.ToolCallRequest{
+				ID:   item.CallId,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			})
+		case "function_call_output":
+			flushToolCalls()
+			message := dto.Message{Role: "tool", ToolCallId: item.CallId}
+			var output string
+			if err := common.Unmarshal(item.Output, &output); err != nil {
+				output = string(item.Output)
+			}
+			message.SetStringContent(output)
+			messages = append(messages, message)
+		case dto.ResponsesItemTypeMessage, "":
+			flushToolCalls()
+			message, err := responsesMessageToOpenAI(item)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		default:
+			// reasoning 等其他条目上游无法识别，直接忽略
+		}
+	}
+	flushToolCalls()
+	return messages, nil
+}
+
+func responsesMessageToOpenAI(item responsesInputItem) (dto.Message, error) {
+	role := item.Role
+	if role == "developer" {
+		role = "system"
+	}
+	message := dto.Message{Role: role}
+	var text string
+	if err := common.Unmarshal(item.Content, &text); err == nil {
+		message.SetStringContent(text)
+		return message, nil
+	}
+	var contents []responsesInputContent
+	if err := common.Unmarshal(item.Content, &contents); err != nil {
+		return message, fmt.Errorf("invalid message content: %w", err)
+	}
+	mediaContents := make([]dto.MediaContent, 0, len(contents))
+	for _, content := range contents {
+		switch content.Type {
+		case "input_text", "output_text":
+			mediaContents = append(mediaContents, dto.MediaContent{Type: dto.ContentTypeText, Text: content.Text})
+		case "refusal":
+			mediaContents = append(mediaContents, dto.MediaContent{Type: dto.ContentTypeText, Text: content.Refusal})
+		case "input_image":
+			mediaContents = append(mediaContents, dto.MediaContent{
+				Type: dto.ContentTypeImageURL,
+				ImageUrl: &dto.MessageImageUrl{
+					Url:    content.ImageUrl,
+					Detail: content.Detail,
+				},
+			})
+		case "input_file":
+			mediaContents = append(mediaContents, dto.MediaContent{
+				Type: dto.ContentTypeFile,
+				File: map[string]string{
+					"file_id":   content.FileId,
+					"file_data": content.FileData,
+					"filename":  content.Filename,
+				},
+			})
+		}
+	}
+	message.SetMediaContent(mediaContents)
+	return message, nil
+}
+
+// toolChoiceResponses2OpenAI 转换 tool_choice，{"type":"function","name":"x"} 需改为 Chat Completions 的嵌套格式
+func toolChoiceResponses2OpenAI(toolChoice json.RawMessage) any {
+	if len(toolChoice) == 0 {
+		return nil
+	}
+	var choice string
+	if err := common.Unmarshal(toolChoice, &choice); err == nil {
+		return choice
+	}
+	var function struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	if err := common.Unmarshal(toolChoice, &function); err == nil && function.Type == "function" {
+		return map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name": function.Name,
+			},
+		}
+	}
+	return nil
+}
+
+// UsageOpenAI2Responses 按 Responses API 的字段填充用量
+func UsageOpenAI2Responses(usage *dto.Usage) *dto.Usage {
+	if usage == nil {
+		return nil
+	}
+	responsesUsage := *usage
+	responsesUsage.InputTokens = usage.PromptTokens
+	responsesUsage.OutputTokens = usage.CompletionTokens
+	responsesUsage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	responsesUsage.InputTokensDetails = &dto.InputTokenDetails{
+		CachedTokens: usage.PromptTokensDetails.CachedTokens,
+	}
+	responsesUsage.OutputTokensDetails = &dto.OutputTokenDetails{
+		ReasoningTokens: usage.CompletionTokenDetails.ReasoningTokens,
+	}
+	return &responsesUsage
+}
+
+func newResponsesResponse(id string, model string, createdAt int) *dto.OpenAIResponsesResponse {
+	return &dto.OpenAIResponsesResponse{
+		ID:        id,
+		Object:    "response",
+		CreatedAt: createdAt,
+		Status:    "in_progress",
+		Model:     model,
+		Output:    make([]dto.ResponsesOutput, 0),
+		Tools:     make([]map[string]any, 0),
+	}
+}
+
+func responsesStatus(finishReason string) string {
+	if finishReason == "length" {
+		return "incomplete"
+	}
+	return "completed"
+}
+
+func newResponsesItemId(prefix string) string {
+	return prefix + "_" + common.GetUUID()
+}
+
+// ResponseOpenAI2Responses 将非流式 Chat Completions 响应转换为 Responses API 响应
+func ResponseOpenAI2Responses(openAIResponse *dto.OpenAITextResponse, id string, createdAt int) *dto.OpenAIResponsesResponse {
+	response := newResponsesResponse(id, openAIResponse.Model, createdAt)
+	finishReason := ""
+	for _, choice := range openAIResponse.Choices {
+		finishReason = choice.FinishReason
+		reasoning := choice.Message.ReasoningContent
+		if reasoning == "" {
+			reasoning = choice.Message.Reasoning
+		}
+		if reasoning != "" {
+			response.Output = append(response.Output, dto.ResponsesOutput{
+				Type:    dto.ResponsesItemTypeReasoning,
+				ID:      newResponsesItemId("rs"),
+				Summary: []dto.ResponsesOutputContent{{Type: "summary_text", Text: reasoning}},
+			})
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			response.Output = append(response.Output, dto.ResponsesOutput{
+				Type:    dto.ResponsesItemTypeMessage,
+				ID:      newResponsesItemId("msg"),
+				Status:  "completed",
+				Role:    "assistant",
+				Content: []dto.ResponsesOutputContent{{Type: "output_text", Text: text, Annotations: []interface{}{}}},
+			})
+		}
+		for _, toolCall := range choice.Message.ParseToolCalls() {
+			response.Output = append(response.Output, dto.ResponsesOutput{
+				Type:      dto.ResponsesItemTypeFunctionCall,
+				ID:        newResponsesItemId("fc"),
+				Status:    "completed",
+				CallId:    toolCall.ID,
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			})
+		}
+	}
+	response.Status = responsesStatus(finishReason)
+	response.Usage = UsageOpenAI2Responses(&openAIResponse.Usage)
+	return response
+}
+
+// ResponsesStreamConverter 将 Chat Completions 流式分片转换为 Responses API 流式事件。
+// 同一时刻只有一个输出条目处于进行中，内容类型切换（思考、文本、不同的工具调用）时结束上一个条目。
+type ResponsesStreamConverter struct {
+	response      *dto.OpenAIResponsesResponse
+	sequence      int
+	current       *dto.ResponsesOutput
+	currentText   strings.Builder
+	toolCallIndex int
+	finishReason  string
+}
+
+func NewResponsesStreamConverter(id string, model string, createdAt int) *ResponsesStreamConverter {
+	return &ResponsesStreamConverter{
+		response: newResponsesResponse(id, model, createdAt),
+	}
+}
+
+func (s *ResponsesStreamConverter) event(event *dto.ResponsesStreamResponse) *dto.ResponsesStreamResponse {
+	event.SequenceNumber = s.sequence
+	s.sequence++
+	return event
+}
+
+func (s *ResponsesStreamConverter) outputIndex() *int {
+	return common.GetPointer[int](len(s.response.Output))
+}
+
+// Start 返回 response.created 与 response.in_progress 事件
+func (s *ResponsesStreamConverter) Start() []*dto.ResponsesStreamResponse {
+	created := *s.response
+	inProgress := *s.response
+	return []*dto.ResponsesStreamResponse{
+		s.event(&dto.ResponsesStreamResponse{Type: "response.created", Response: &created}),
+		s.event(&dto.ResponsesStreamResponse{Type: "response.in_progress", Response: &inProgress}),
+	}
+}
+
+func (s *ResponsesStreamConverter) Convert(chunk *dto.ChatCompletionsStreamResponse) []*dto.ResponsesStreamResponse {
+	var events []*dto.ResponsesStreamResponse
+	if chunk.Model != "" {
+		s.response.Model = chunk.Model
+	}
+	if len(chunk.Choices) == 0 {
+		return events
+	}
+	choice := chunk.Choices[0]
+	delta := &choice.Delta
+
+	if reasoning := delta.GetReasoningContent(); reasoning != "" {
+		if s.current == nil || s.current.Type != dto.ResponsesItemTypeReasoning {
+			events = append(events, s.closeCurrent()...)
+			events = append(events, s.openItem(&dto.ResponsesOutput{
+				Type:    dto.ResponsesItemTypeReasoning,
+				ID:      newResponsesItemId("rs"),
+				Summary: []dto.ResponsesOutputContent{},
+			})...)
+			events = append(events, s.event(&dto.ResponsesStreamResponse{
+				Type:         "response.reasoning_summary_part.added",
+				ItemId:       s.current.ID,
+				OutputIndex:  s.outputIndex(),
+				SummaryIndex: common.GetPointer[int](0),
+				Part:         &dto.ResponsesOutputContent{Type: "summary_text"},
+			}))
+		}
+		s.currentText.WriteString(reasoning)
+		events = append(events, s.event(&dto.ResponsesStreamResponse{
+			Type:         "response.reasoning_summary_text.delta",
+			ItemId:       s.current.ID,
+			OutputIndex:  s.outputIndex(),
+			SummaryIndex: common.GetPointer[int](0),
+			Delta:        reasoning,
+		}))
+	}
+
+	if text := delta.GetContentString(); text != "" {
+		if s.current == nil || s.current.Type != dto.ResponsesItemTypeMessage {
+			events = append(events, s.closeCurrent()...)
+			events = append(events, s.openItem(&dto.ResponsesOutput{
+				Type:    dto.ResponsesItemTypeMessage,
+				ID:      newResponsesItemId("msg"),
+				Status:  "in_progress",
+				Role:    "assistant",
+				Content: []dto.ResponsesOutputContent{},
+			})...)
+			events = append(events, s.event(&dto.ResponsesStreamResponse{
+				Type:         "response.content_part.added",
+				ItemId:       s.current.ID,
+				OutputIndex:  s.outputIndex(),
+				ContentIndex: common.GetPointer[int](0),
+				Part:         &dto.ResponsesOutputContent{Type: "output_text", Annotations: []interface{}{}},
+			}))
+		}
+		s.currentText.WriteString(text)
+		events = append(events, s.event(&dto.ResponsesStreamResponse{
+			Type:         "response.output_text.delta",
+			ItemId:       s.current.ID,
+			OutputIndex:  s.outputIndex(),
+			ContentIndex: common.GetPointer[int](0),
+			Delta:        text,
+		}))
+	}
+
+	for i := range delta.ToolCalls {
+		toolCall := &delta.ToolCalls[i]
+		toolCallIndex := 0
+		if toolCall.Index != nil {
+			toolCallIndex = *toolCall.Index
+		}
+		if s.current == nil || s.current.Type != dto.ResponsesItemTypeFunctionCall || toolCallIndex != s.toolCallIndex {
+			events = append(events, s.closeCurrent()...)
+			s.toolCallIndex = toolCallIndex
+			events = append(events, s.openItem(&dto.ResponsesOutput{
+				Type:   dto.ResponsesItemTypeFunctionCall,
+				ID:     newResponsesItemId("fc"),
+				Status: "in_progress",
+				CallId: toolCall.ID,
+				Name:   toolCall.Function.Name,
+			})...)
+		}
+		if toolCall.Function.Arguments != "" {
+			s.currentText.WriteString(toolCall.Function.Arguments)
+			events = append(events, s.event(&dto.ResponsesStreamResponse{
+				Type:        "response.function_call_arguments.delta",
+				ItemId:      s.current.ID,
+				OutputIndex: s.outputIndex(),
+				Delta:       toolCall.Function.Arguments,
+			}))
+		}
+	}
+
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		s.finishReason = *choice.FinishReason
+	}
+	return events
+}
+
+func (s *ResponsesStreamConverter) openItem(item *dto.ResponsesOutput) []*dto.ResponsesStreamResponse {
+	s.current = item
+	s.currentText.Reset()
+	added := *item
+	return []*dto.ResponsesStreamResponse{s.event(&dto.ResponsesStreamResponse{
+		Type:        dto.ResponsesOutputTypeItemAdded,
+		OutputIndex: s.outputIndex(),
+		Item:        &added,
+	})}
+}
+
+// closeCurrent 结束当前条目，发送对应的 done 事件并将其加入最终输出
+func (s *ResponsesStreamConverter) closeCurrent() []*dto.ResponsesStreamResponse {
+	if s.current == nil {
+		return nil
+	}
+	var events []*dto.ResponsesStreamResponse
+	item := s.current
+	text := s.currentText.String()
+	switch item.Type {
+	case dto.ResponsesItemTypeReasoning:
+		part := dto.ResponsesOutputContent{Type: "summary_text", Text: text}
+		item.Summary = []dto.ResponsesOutputContent{part}
+		events = append(events,
+			s.event(&dto.ResponsesStreamResponse{
+				Type:         "response.reasoning_summary_text.done",
+				ItemId:       item.ID,
+				OutputIndex:  s.outputIndex(),
+				SummaryIndex: common.GetPointer[int](0),
+				Text:         common.GetPointer[string](text),
+			}),
+			s.event(&dto.ResponsesStreamResponse{
+				Type:         "response.reasoning_summary_part.done",
+				ItemId:       item.ID,
+				OutputIndex:  s.outputIndex(),
+				SummaryIndex: common.GetPointer[int](0),
+				Part:         &part,
+			}))
+	case dto.ResponsesItemTypeMessage:
+		part := dto.ResponsesOutputContent{Type: "output_text", Text: text, Annotations: []interface{}{}}
+		item.Status = "completed"
+		item.Content = []dto.ResponsesOutputContent{part}
+		events = append(events,
+			s.event(&dto.ResponsesStreamResponse{
+				Type:         "response.output_text.done",
+				ItemId:       item.ID,
+				OutputIndex:  s.outputIndex(),
+				ContentIndex: common.GetPointer[int](0),
+				Text:         common.GetPointer[string](text),
+			}),
+			s.event(&dto.ResponsesStreamResponse{
+				Type:         "response.content_part.done",
+				ItemId:       item.ID,
+				OutputIndex:  s.outputIndex(),
+				ContentIndex: common.GetPointer[int](0),
+				Part:         &part,
+			}))
+	case dto.ResponsesItemTypeFunctionCall:
+		item.Status = "completed"
+		item.Arguments = text
+		events = append(events, s.event(&dto.ResponsesStreamResponse{
+			Type:        "response.function_call_arguments.done",
+			ItemId:      item.ID,
+			OutputIndex: s.outputIndex(),
+			Arguments:   common.GetPointer[string](text),
+		}))
+	}
+	events = append(events, s.event(&dto.ResponsesStreamResponse{
+		Type:        dto.ResponsesOutputTypeItemDone,
+		OutputIndex: s.outputIndex(),
+		Item:        item,
+	}))
+	s.response.Output = append(s.response.Output, *item)
+	s.current = nil
+	s.currentText.Reset()
+	return events
+}
+
+// Finish 结束所有条目并返回携带完整输出与用量的 response.completed 事件
+func (s *ResponsesStreamConverter) Finish(usage *dto.Usage) []*dto.ResponsesStreamResponse {
+	events := s.closeCurrent()
+	s.response.Status = responsesStatus(s.finishReason)
+	s.response.Usage = UsageOpenAI2Responses(usage)
+	eventType := "response.completed"
+	if s.response.Status == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	return append(events, s.event(&dto.ResponsesStreamResponse{
+		Type:     eventType,
+		Response: s.response,
+	}))
+}
+
+// Response 返回当前已转换的完整响应
+func (s *ResponsesStreamConverter) Response() *dto.OpenAIResponsesResponse {
+	return s.response
+}
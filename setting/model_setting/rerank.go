@@ -0,0 +1,35 @@
+package model_setting
+
+import (
+	"one-api/setting/config"
+	"slices"
+)
+
+// RerankSettings 定义重排序的配置
+type RerankSettings struct {
+	// EmbeddingModels 由网关调用向量模型并按余弦相似度计算重排序的模型
+	EmbeddingModels []string `json:"embedding_models"`
+}
+
+// 默认配置
+var defaultRerankSettings = RerankSettings{
+	EmbeddingModels: []string{},
+}
+
+// 全局实例
+var rerankSettings = defaultRerankSettings
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("rerank", &rerankSettings)
+}
+
+// GetRerankSettings 获取重排序配置
+func GetRerankSettings() *RerankSettings {
+	return &rerankSettings
+}
+
+// IsEmbeddingRerankModel 判断模型是否通过向量相似度实现重排序
+func IsEmbeddingRerankModel(model string) bool {
+	return slices.Contains(rerankSettings.EmbeddingModels, model)
+}
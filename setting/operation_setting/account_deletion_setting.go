@@ -0,0 +1,23 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+type AccountDeletionSetting struct {
+	GracePeriodDays int `json:"grace_period_days"` // 申请注销后的冷静期，期间可撤销
+	IntervalMinutes int `json:"interval_minutes"`  // 执行到期注销申请的间隔
+}
+
+// 默认配置
+var accountDeletionSetting = AccountDeletionSetting{
+	GracePeriodDays: 15,
+	IntervalMinutes: 60,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("account_deletion_setting", &accountDeletionSetting)
+}
+
+func GetAccountDeletionSetting() *AccountDeletionSetting {
+	return &accountDeletionSetting
+}
@@ -0,0 +1,31 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// InviteRewardSetting 邀请奖励的归属条件与异常检测阈值。
+// 被邀请用户完成一次真实充值，或累计消耗达到 UsageThreshold 后，奖励才会发放
+type InviteRewardSetting struct {
+	UsageThreshold  int `json:"usage_threshold"`  // 归属所需的累计消耗额度，0 表示仅充值后归属
+	IpThreshold     int `json:"ip_threshold"`     // 同一邀请人下来自同一 IP 的被邀请用户达到该数量时标记
+	DeviceThreshold int `json:"device_threshold"` // 同一设备注册的被邀请用户达到该数量时标记
+	BurstThreshold  int `json:"burst_threshold"`  // 同一邀请人一小时内邀请用户达到该数量时标记
+	IntervalMinutes int `json:"interval_minutes"` // 检查奖励归属的间隔
+}
+
+// 默认配置
+var inviteRewardSetting = InviteRewardSetting{
+	UsageThreshold:  500000,
+	IpThreshold:     3,
+	DeviceThreshold: 2,
+	BurstThreshold:  10,
+	IntervalMinutes: 10,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("invite_reward_setting", &inviteRewardSetting)
+}
+
+func GetInviteRewardSetting() *InviteRewardSetting {
+	return &inviteRewardSetting
+}
@@ -0,0 +1,42 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	LogArchiveStorageLocal = "local"
+	LogArchiveStorageS3    = "s3"
+)
+
+type LogArchiveSetting struct {
+	Enabled           bool   `json:"enabled"`
+	RetentionDays     int    `json:"retention_days"`   // 超过该天数的日志会被归档
+	IntervalMinutes   int    `json:"interval_minutes"` // 自动归档的执行间隔
+	RowsPerFile       int    `json:"rows_per_file"`    // 每个归档文件包含的最大日志条数
+	Storage           string `json:"storage"`          // local 或 s3
+	LocalDir          string `json:"local_dir"`        // 本地归档目录
+	S3Endpoint        string `json:"s3_endpoint"`      // S3 兼容存储地址，如 http://127.0.0.1:9000
+	S3Region          string `json:"s3_region"`
+	S3Bucket          string `json:"s3_bucket"`
+	S3AccessKeyId     string `json:"s3_access_key_id"`
+	S3SecretAccessKey string `json:"s3_secret_access_key"`
+}
+
+// 默认配置
+var logArchiveSetting = LogArchiveSetting{
+	Enabled:         false,
+	RetentionDays:   90,
+	IntervalMinutes: 60,
+	RowsPerFile:     50000,
+	Storage:         LogArchiveStorageLocal,
+	LocalDir:        "./data/log_archive",
+	S3Region:        "us-east-1",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("log_archive_setting", &logArchiveSetting)
+}
+
+func GetLogArchiveSetting() *LogArchiveSetting {
+	return &logArchiveSetting
+}
@@ -5,3 +5,9 @@ var StripeWebhookSecret = ""
 var StripePriceId = ""
 var StripeUnitPrice = 8.0
 var StripeMinTopUp = 1
+
+// StripeSubscriptionPriceId 订阅使用的循环计费价格ID
+var StripeSubscriptionPriceId = ""
+
+// StripeSubscriptionQuota 每个计费周期充值的额度
+var StripeSubscriptionQuota = 0
@@ -8,3 +8,6 @@ var WorkerAllowHttpImageRequestEnabled = false
 func EnableWorker() bool {
 	return WorkerUrl != ""
 }
+
+// MetricsToken 访问 /metrics 所需的 Bearer Token，为空时不开放指标接口
+var MetricsToken = ""
@@ -62,6 +62,7 @@ const (
 	ErrorCodeBadResponseStatusCode  ErrorCode = "bad_response_status_code"
 	ErrorCodeBadResponse            ErrorCode = "bad_response"
 	ErrorCodeBadResponseBody        ErrorCode = "bad_response_body"
+	ErrorCodeContentFiltered        ErrorCode = "content_filter"
 
 	// sql error
 	ErrorCodeQueryDataError  ErrorCode = "query_data_error"